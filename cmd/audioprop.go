@@ -0,0 +1,215 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var audioPropPackets bool = false
+
+// audioPropParticipant collects the decoded fields of the audio packets of one participant.
+// Rates, channels and sequence numbers are the guessed meanings of AudioProp.Unknown4, Unknown8 and Unknown12.
+type audioPropParticipant struct {
+	ident        int32
+	packets      int
+	sampleRates  map[int32]int
+	channels     map[int32]int
+	opusChannels map[int]int
+	firstSeq     int32
+	// lastSeq is the highest sequence number so far
+	lastSeq int32
+	// missing counts skipped sequence numbers, reordered the ones that went backwards
+	missing   int64
+	reordered int
+}
+
+// addSeq counts the sequence numbers skipped since the highest one so far, or the packet as reordered if seq
+// went backwards. The sequence numbers may wrap around.
+func (p *audioPropParticipant) addSeq(seq int32) {
+	delta := int64(seq) - int64(p.lastSeq)
+	if delta > math.MaxInt32 {
+		delta -= 1 << 32
+	} else if delta < math.MinInt32 {
+		delta += 1 << 32
+	}
+	if delta <= 0 {
+		p.reordered++
+		return
+	}
+	p.missing += delta - 1
+	p.lastSeq = seq
+}
+
+// audioPropWord collects the values of the 32 bit word at one offset of the property, over all audio packets.
+type audioPropWord struct {
+	offset   int
+	present  int
+	values   map[uint32]int
+	min, max uint32
+	first    uint32
+	last     uint32
+	// increasing counts the packets in which the word was larger than in the previous one
+	increasing int
+}
+
+func (w *audioPropWord) add(v uint32) {
+	if w.present == 0 {
+		w.min, w.max, w.first = v, v, v
+	} else if v > w.last {
+		w.increasing++
+	}
+	if v < w.min {
+		w.min = v
+	}
+	if v > w.max {
+		w.max = v
+	}
+	w.values[v]++
+	w.last = v
+	w.present++
+}
+
+// formatCounts lists the values of m in order, each with the number of packets it occurred in.
+func formatCounts(m map[int32]int) string {
+	keys := []int{}
+	for k := range m {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+	ret := ""
+	for i, k := range keys {
+		if i > 0 {
+			ret += ", "
+		}
+		ret += fmt.Sprintf("%d (%d)", k, m[int32(k)])
+	}
+	return ret
+}
+
+func runAudioProp(filename string, w io.Writer) error {
+	sr := zoom.NewSampleReader(logger)
+	if err := sr.Open(filename); err != nil {
+		return err
+	}
+	defer sr.Close()
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if audioPropPackets {
+		fmt.Fprintf(tw, "Timing\tSize\tParticipant\tUnknown4 (rate?)\tUnknown8 (channels?)\tUnknown12 (sequence?)\tRest\n")
+	}
+	participants := map[int32]*audioPropParticipant{}
+	order := []int32{}
+	words := []*audioPropWord{}
+	sizes := map[int32]int{}
+	total := 0
+	for sr.Next() {
+		pkt := sr.Current()
+		aud := pkt.AudioProp()
+		if aud == nil {
+			continue
+		}
+		total++
+		sizes[pkt.PropertySize]++
+		if audioPropPackets {
+			fmt.Fprintf(tw, "%d\t%d\t0x%x\t%d\t%d\t%d\t%x\n", pkt.TimingA, pkt.DataSize, aud.NameIdent, aud.Unknown4, aud.Unknown8, aud.Unknown12, aud.Rest)
+		}
+
+		p, ok := participants[aud.NameIdent]
+		if !ok {
+			p = &audioPropParticipant{
+				ident:        aud.NameIdent,
+				sampleRates:  map[int32]int{},
+				channels:     map[int32]int{},
+				opusChannels: map[int]int{},
+				firstSeq:     aud.Unknown12,
+				lastSeq:      aud.Unknown12,
+			}
+			participants[aud.NameIdent] = p
+			order = append(order, aud.NameIdent)
+		} else {
+			p.addSeq(aud.Unknown12)
+		}
+		p.packets++
+		p.sampleRates[aud.Unknown4]++
+		p.channels[aud.Unknown8]++
+		if _, err := zoom.OpusPacketSamples(pkt.Data); err == nil {
+			p.opusChannels[zoom.OpusChannels(pkt.Data)]++
+		}
+
+		for i := 0; i+4 <= len(pkt.Property); i += 4 {
+			if i/4 >= len(words) {
+				words = append(words, &audioPropWord{offset: i, values: map[uint32]int{}})
+			}
+			words[i/4].add(binary.LittleEndian.Uint32(pkt.Property[i:]))
+		}
+	}
+	if err := sr.Err(); err != nil {
+		logger.Errorf("Failed to read all packets: %v", err)
+	}
+
+	if audioPropPackets {
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintf(tw, "%s: %d audio packets, property sizes: %s\n", filename, total, formatCounts(sizes))
+
+	fmt.Fprintf(tw, "\nParticipant\tPackets\tRates?\tChannels?\tOpus channels\tSequence?\tMissing\tReordered\n")
+	for _, ident := range order {
+		p := participants[ident]
+		opus := map[int32]int{}
+		for k, v := range p.opusChannels {
+			opus[int32(k)] = v
+		}
+		fmt.Fprintf(tw, "0x%x\t%d\t%s\t%s\t%s\t%d-%d\t%d\t%d\n", p.ident, p.packets, formatCounts(p.sampleRates), formatCounts(p.channels), formatCounts(opus), p.firstSeq, p.lastSeq, p.missing, p.reordered)
+	}
+
+	fmt.Fprintf(tw, "\nOffset\tPresent\tDistinct\tMin\tMax\tFirst\tLast\tIncreasing\n")
+	for _, word := range words {
+		fmt.Fprintf(tw, "0x%02x\t%d\t%d\t0x%x\t0x%x\t0x%x\t0x%x\t%d\n", word.offset, word.present, len(word.values), word.min, word.max, word.first, word.last, word.increasing)
+	}
+	return tw.Flush()
+}
+
+// audioPropCmd represents the audioprop command
+var audioPropCmd = &cobra.Command{
+	Use:   "audioprop <file>",
+	Short: "Tabulates the properties of the audio packets of a zoom file",
+	Long: `Decodes the property of every audio packet and summarizes it per participant. The words at offsets 4, 8 and 12
+are shown as the sample rate, channel count and sequence number they are guessed to be, the channel count signalled in
+the Opus packets themselves is shown for comparison.
+
+Every 32 bit word of the property is tabulated as well, with the number of distinct values and how often it increased
+from one packet to the next, to help figure out the meaning of the unknown bytes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAudioProp(args[0], os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(audioPropCmd)
+
+	audioPropCmd.Flags().BoolVarP(&audioPropPackets, "packets", "p", audioPropPackets, "Also print the decoded property of every packet")
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var avatarDir string = "."
+
+// avatarFilename names the n-th distinct avatar of a participant after its display name.
+// The ident keeps participants with the same name apart.
+func avatarFilename(p *zoom.Participant, n int, a *zoom.AvatarImage) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return r
+		}
+		return '_'
+	}, p.DisplayName())
+	if n > 0 {
+		return fmt.Sprintf("%s_%x_%d%s", name, p.Ident, n+1, a.Ext())
+	}
+	return fmt.Sprintf("%s_%x%s", name, p.Ident, a.Ext())
+}
+
+func writeAvatar(filename string, data []byte) error {
+	out, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := out.Write(data); err != nil {
+		return fmt.Errorf("Failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// runAvatars saves the distinct avatars of every participant of a file or recording.
+func runAvatars(path string) error {
+	logger.Infof("Extracting avatars from %s to %s", path, avatarDir)
+	rec, err := openRecordingPath(path)
+	if err != nil {
+		return err
+	}
+	defer rec.Close()
+
+	roster, err := rec.Roster()
+	if err != nil {
+		logger.Warnf("Failed to read participants: %v", err)
+	}
+	n := 0
+	for _, p := range roster.Participants() {
+		for i, a := range p.Avatars {
+			filename := filepath.Join(avatarDir, avatarFilename(p, i, a))
+			if err := writeAvatar(filename, a.Data); err != nil {
+				return err
+			}
+			logger.Infof("Written avatar of %s (0x%x) to %s", p.DisplayName(), p.Ident, filename)
+			n++
+		}
+	}
+	logger.Infof("Written %d avatars", n)
+	return nil
+}
+
+var avatarsCmd = &cobra.Command{
+	Use:   "avatars <file or recording>",
+	Short: "Saves the avatars of all participants",
+	Long: `Saves every distinct avatar of every participant to its own file, named after the participant,
+e.g. Alice_1000.png. Names are taken from the command file if the whole recording folder is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAvatars(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(avatarsCmd)
+
+	avatarsCmd.Flags().StringVarP(&avatarDir, "out", "o", avatarDir, "Directory to save the avatars in")
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var chatFormat string = "text"
+var chatOutput string = "-"
+
+// maximum time a chat message is shown in the subtitles
+var chatCueDuration = 5 * time.Second
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// openOutput opens filename for writing, "-" means stdout.
+func openOutput(filename string) (io.WriteCloser, error) {
+	if filename == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+}
+
+type chatMessage struct {
+	Time        time.Duration `json:"-"`
+	Offset      float64       `json:"offset"`
+	Participant uint32        `json:"participant"`
+	Name        string        `json:"name"`
+	Text        string        `json:"text"`
+}
+
+func formatVTTTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, ms%1000)
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// vttText escapes text for the payload of a WebVTT cue.
+// A blank line would end the cue, so empty lines are removed. Escaping '>' also breaks up any "-->".
+func vttText(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, vttEscaper.Replace(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// vttVoice escapes a name for the annotation of a voice span, which has to stay on one line.
+func vttVoice(name string) string {
+	return strings.Join(strings.Fields(vttEscaper.Replace(name)), " ")
+}
+
+func writeChat(w io.Writer, msgs []chatMessage, format string) error {
+	switch format {
+	case "text":
+		for _, m := range msgs {
+			if _, err := fmt.Fprintf(w, "[%s] %s: %s\n", formatVTTTime(m.Time), m.Name, m.Text); err != nil {
+				return err
+			}
+		}
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, m := range msgs {
+			if err := enc.Encode(m); err != nil {
+				return err
+			}
+		}
+	case "vtt":
+		if _, err := fmt.Fprint(w, "WEBVTT\n"); err != nil {
+			return err
+		}
+		for i, m := range msgs {
+			end := m.Time + chatCueDuration
+			if i+1 < len(msgs) && msgs[i+1].Time < end && msgs[i+1].Time > m.Time {
+				end = msgs[i+1].Time
+			}
+			if _, err := fmt.Fprintf(w, "\n%d\n%s --> %s\n<v %s>%s\n", i+1, formatVTTTime(m.Time), formatVTTTime(end), vttVoice(m.Name), vttText(m.Text)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Unknown chat format %s, expected one of text, jsonl, vtt", format)
+	}
+	return nil
+}
+
+func runChat(path string) error {
+	rec, err := zoom.OpenRecording(path, logger)
+	if err != nil {
+		return err
+	}
+	defer rec.Close()
+	rec.CmdTypes = cmdTypes
+
+	events, err := rec.Events()
+	if err != nil {
+		logger.Errorf("Failed to read all commands: %v", err)
+	}
+	roster := zoom.NewRoster(events)
+
+	msgs := []chatMessage{}
+	for _, e := range events {
+		chat, ok := e.(*zoom.ChatEvent)
+		if !ok {
+			continue
+		}
+		t := zoom.TimingToDuration(rec.Rel(chat.Timing()))
+		msgs = append(msgs, chatMessage{
+			Time:        t,
+			Offset:      t.Seconds(),
+			Participant: chat.Participant(),
+			Name:        roster.Name(chat.Participant()),
+			Text:        chat.Text,
+		})
+	}
+	logger.Infof("Found %d chat messages", len(msgs))
+
+	out, err := openOutput(chatOutput)
+	if err != nil {
+		return fmt.Errorf("Failed to open output file: %w", err)
+	}
+	defer out.Close()
+
+	return writeChat(out, msgs, chatFormat)
+}
+
+// chatCmd represents the chat command
+var chatCmd = &cobra.Command{
+	Use:   "chat <recording>",
+	Short: "Exports the meeting chat of a recording",
+	Long: `Exports the chat messages of a recording folder as plain text, JSON Lines or WebVTT.
+Timestamps are relative to the start of the recording, so the WebVTT output lines up with the extracted video.
+The command type value of chat messages has not been confirmed, so chat messages are only recognized once their
+type is given with --cmd-types, e.g. --cmd-types ChatMessage=3.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runChat(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+
+	chatCmd.Flags().StringVarP(&chatFormat, "format", "f", chatFormat, "Output format: text, jsonl, vtt")
+	chatCmd.Flags().StringVarP(&chatOutput, "out", "o", chatOutput, "Output filename, - for stdout")
+}
@@ -16,28 +16,25 @@ limitations under the License.
 package cmd
 
 import (
-	"path/filepath"
-
 	"github.com/galli-leo/gozoom/zoom"
 	"github.com/spf13/cobra"
 )
 
 func processCmdFile(path string) {
 	sugar := logger.Named("cmd_processor")
-	filename := filepath.Join(path, "double_click_to_convert_02.zoom")
-	f, err := zoom.OpenFile(filename, logger)
+	rec, err := zoom.OpenRecording(path, logger)
 	if err != nil {
-		sugar.Fatalw("Failed to open file", "error", err, "filename", filename)
+		sugar.Fatalw("Failed to open recording", "error", err, "path", path)
 	}
+	defer rec.Close()
+	rec.CmdTypes = cmdTypes
 
-	//f.ReadBeginning()
-	p := zoom.NewCmdPacket()
-	for f.HasData() {
-		err := f.ReadPacket(p)
-		if err != nil {
-			sugar.Fatalw("Failed to read packet", "error", err, "packet", p)
-		}
-		sugar.Infof("Read packet: %s", p.String())
+	events, err := rec.Events()
+	for _, e := range events {
+		sugar.Infof("Read event: %s", e)
+	}
+	if err != nil {
+		sugar.Fatalw("Failed to read packet", "error", err)
 	}
 }
 
@@ -0,0 +1,373 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var cursorOutput string = "cursor"
+var cursorFormat string = "json"
+
+// how long the last cursor position stays visible in the subtitles
+var cursorLastDuration = time.Second
+
+// cursorPosition is one entry of the cursor timeline.
+type cursorPosition struct {
+	Time   time.Duration `json:"-"`
+	Offset float64       `json:"offset"`
+	Timing int64         `json:"timing"`
+	X      int32         `json:"x"`
+	Y      int32         `json:"y"`
+	Type   int32         `json:"type"`
+	// Shape is the index of the current shape in the sprite sheet, -1 before the first shape was seen.
+	Shape int `json:"shape"`
+}
+
+// cursorShape is a distinct cursor image, its position in the sprite sheet is stored in the manifest.
+type cursorShape struct {
+	Index  int    `json:"index"`
+	Hash   string `json:"hash"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Timing of the first packet carrying the shape, and how often it was sent.
+	Timing int64 `json:"first_timing"`
+	Count  int   `json:"count"`
+
+	img *image.RGBA
+}
+
+type cursorTimeline struct {
+	Positions []*cursorPosition
+	Shapes    []*cursorShape
+	// resolution of the screen share, which the cursor positions refer to
+	Width  int32
+	Height int32
+	End    time.Duration
+}
+
+func hashCursorImage(img *image.RGBA) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%dx%d:", img.Rect.Dx(), img.Rect.Dy())
+	h.Write(img.Pix)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readCursorTimeline collects the cursor positions and the distinct cursor shapes of a recording.
+func readCursorTimeline(rec *zoom.Recording) (*cursorTimeline, error) {
+	res := &cursorTimeline{
+		Width:  1280,
+		Height: 720,
+		End:    zoom.TimingToDuration(rec.Duration()),
+	}
+	if screen := rec.Track(zoom.TrackScreenShare); screen != nil {
+		r := screen.Reader()
+		if r.Next() {
+			if vid := r.Current().VideoProp(); vid != nil {
+				res.Width = vid.Width
+				res.Height = vid.Height
+			}
+		} else if r.Error() != nil {
+			return nil, fmt.Errorf("Failed to read screen share: %w", r.Error())
+		}
+	}
+
+	track := rec.Track(zoom.TrackCursor)
+	if track == nil {
+		return res, nil
+	}
+	shapes := map[string]*cursorShape{}
+	shape := -1
+	r := track.Reader()
+	for r.Next() {
+		pkt := r.Current()
+		cur := pkt.CursorProp()
+		if cur == nil {
+			continue
+		}
+		img, err := pkt.CursorImage()
+		if err != nil {
+			logger.Warnf("Skipping cursor image at timing %d: %v", pkt.TimingA, err)
+		} else if img != nil {
+			hash := hashCursorImage(img)
+			s, ok := shapes[hash]
+			if !ok {
+				s = &cursorShape{
+					Index:  len(res.Shapes),
+					Hash:   hash,
+					Width:  img.Rect.Dx(),
+					Height: img.Rect.Dy(),
+					Timing: pkt.TimingA,
+					img:    img,
+				}
+				shapes[hash] = s
+				res.Shapes = append(res.Shapes, s)
+			}
+			s.Count++
+			shape = s.Index
+		}
+		t := zoom.TimingToDuration(rec.Rel(pkt.TimingA))
+		res.Positions = append(res.Positions, &cursorPosition{
+			Time:   t,
+			Offset: t.Seconds(),
+			Timing: pkt.TimingA,
+			X:      cur.X,
+			Y:      cur.Y,
+			Type:   cur.Type,
+			Shape:  shape,
+		})
+	}
+	if r.Error() != nil {
+		return res, fmt.Errorf("Failed to read cursor track: %w", r.Error())
+	}
+	return res, nil
+}
+
+func writeCursorPositions(w io.Writer, format string, positions []*cursorPosition) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(positions)
+	case "csv":
+		c := csv.NewWriter(w)
+		c.Write([]string{"offset", "timing", "x", "y", "type", "shape"})
+		for _, p := range positions {
+			c.Write([]string{
+				strconv.FormatFloat(p.Offset, 'f', 3, 64),
+				strconv.FormatInt(p.Timing, 10),
+				strconv.Itoa(int(p.X)),
+				strconv.Itoa(int(p.Y)),
+				strconv.Itoa(int(p.Type)),
+				strconv.Itoa(p.Shape),
+			})
+		}
+		c.Flush()
+		return c.Error()
+	}
+	return fmt.Errorf("Unknown format %q", format)
+}
+
+// writeCursorSprites places the shapes next to each other in a single image and records their position in the shapes.
+func writeCursorSprites(w io.Writer, shapes []*cursorShape) error {
+	width, height := 0, 0
+	for _, s := range shapes {
+		s.X = width
+		width += s.Width
+		if s.Height > height {
+			height = s.Height
+		}
+	}
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	for _, s := range shapes {
+		draw.Draw(sheet, image.Rect(s.X, s.Y, s.X+s.Width, s.Y+s.Height), s.img, image.Point{}, draw.Src)
+	}
+	return png.Encode(w, sheet)
+}
+
+func formatASSTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// assColor converts to the &HBBGGRR& notation of ASS.
+func assColor(r, g, b uint8) string {
+	return fmt.Sprintf("&H%02X%02X%02X&", b, g, r)
+}
+
+// cursorDrawing returns one ASS override tag and vector drawing per color of the cursor image.
+// ASS cannot show bitmaps, so every row is split into runs of pixels of the same (slightly quantized) color,
+// drawn as one pixel high rectangles.
+func cursorDrawing(img *image.RGBA) []string {
+	type colorKey [4]uint8
+	drawings := map[colorKey]*strings.Builder{}
+	keys := []colorKey{}
+	quantize := func(x, y int) colorKey {
+		i := img.PixOffset(x, y)
+		p := img.Pix[i : i+4]
+		if p[3] < 0x10 {
+			return colorKey{}
+		}
+		return colorKey{p[0] | 0x0f, p[1] | 0x0f, p[2] | 0x0f, p[3] | 0x1f}
+	}
+	b := img.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; {
+			key := quantize(x, y)
+			end := x + 1
+			for end < b.Max.X && quantize(end, y) == key {
+				end++
+			}
+			if key[3] != 0 {
+				d, ok := drawings[key]
+				if !ok {
+					d = &strings.Builder{}
+					drawings[key] = d
+					keys = append(keys, key)
+				}
+				fmt.Fprintf(d, "m %d %d l %d %d %d %d %d %d ", x, y, end, y, end, y+1, x, y+1)
+			}
+			x = end
+		}
+	}
+
+	// draw the most opaque colors last
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i][3] < keys[j][3]
+	})
+	ret := []string{}
+	for _, key := range keys {
+		tags := fmt.Sprintf(`\1c%s\1a&H%02X&`, assColor(key[0], key[1], key[2]), 0xff-key[3])
+		ret = append(ret, fmt.Sprintf(`{%s\p1}%s{\p0}`, tags, strings.TrimSpace(drawings[key].String())))
+	}
+	return ret
+}
+
+// writeCursorASS writes a subtitle track drawing the cursor on top of the screen share.
+// Consecutive positions with the same shape and coordinates are merged into a single event.
+func writeCursorASS(w io.Writer, res *cursorTimeline) error {
+	fmt.Fprintf(w, "[Script Info]\nScriptType: v4.00+\nPlayResX: %d\nPlayResY: %d\nWrapStyle: 2\nScaledBorderAndShadow: yes\n\n", res.Width, res.Height)
+	fmt.Fprint(w, "[V4+ Styles]\nFormat: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	fmt.Fprint(w, "Style: Cursor,Arial,20,&H00FFFFFF,&H00FFFFFF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,0,0,7,0,0,0,1\n\n")
+	if _, err := fmt.Fprint(w, "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n"); err != nil {
+		return err
+	}
+
+	drawings := make([][]string, len(res.Shapes))
+	for i, s := range res.Shapes {
+		drawings[i] = cursorDrawing(s.img)
+	}
+	for i := 0; i < len(res.Positions); {
+		p := res.Positions[i]
+		next := i + 1
+		for next < len(res.Positions) && res.Positions[next].X == p.X && res.Positions[next].Y == p.Y && res.Positions[next].Shape == p.Shape {
+			next++
+		}
+		end := p.Time + cursorLastDuration
+		if next < len(res.Positions) {
+			end = res.Positions[next].Time
+		} else if res.End > p.Time {
+			end = res.End
+		}
+		if p.Shape >= 0 && end > p.Time {
+			for _, d := range drawings[p.Shape] {
+				_, err := fmt.Fprintf(w, "Dialogue: 0,%s,%s,Cursor,,0,0,0,,{\\an7\\pos(%d,%d)}%s\n", formatASSTime(p.Time), formatASSTime(end), p.X, p.Y, d)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		i = next
+	}
+	return nil
+}
+
+// createCursorFile creates one of the output files and calls write with it.
+func createCursorFile(suffix string, write func(w io.Writer) error) error {
+	filename := cursorOutput + suffix
+	out, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if err := write(out); err != nil {
+		return fmt.Errorf("Failed to write %s: %w", filename, err)
+	}
+	logger.Infof("Written %s", filename)
+	return nil
+}
+
+func runCursor(path string) error {
+	if cursorFormat != "json" && cursorFormat != "csv" {
+		return fmt.Errorf("Unknown format %q", cursorFormat)
+	}
+	rec, err := openRecordingPath(path)
+	if err != nil {
+		return err
+	}
+	defer rec.Close()
+
+	res, err := readCursorTimeline(rec)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Found %d cursor positions and %d distinct shapes", len(res.Positions), len(res.Shapes))
+
+	err = createCursorFile("."+cursorFormat, func(w io.Writer) error {
+		return writeCursorPositions(w, cursorFormat, res.Positions)
+	})
+	if err != nil {
+		return err
+	}
+	if len(res.Shapes) > 0 {
+		if err := createCursorFile("_sprites.png", func(w io.Writer) error {
+			return writeCursorSprites(w, res.Shapes)
+		}); err != nil {
+			return err
+		}
+		if err := createCursorFile("_sprites.json", func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(res.Shapes)
+		}); err != nil {
+			return err
+		}
+	}
+	return createCursorFile(".ass", func(w io.Writer) error {
+		return writeCursorASS(w, res)
+	})
+}
+
+// cursorCmd represents the cursor command
+var cursorCmd = &cobra.Command{
+	Use:   "cursor <file or recording>",
+	Short: "Exports the cursor track of a zoom file or recording",
+	Long: `Exports the cursor of the screen share to several files, all starting with the --out prefix:
+
+  <out>.json or <out>.csv   the cursor position timeline, with offsets relative to the start of the recording
+  <out>_sprites.png         every distinct cursor shape, next to each other
+  <out>_sprites.json        the position of each shape in the sprite sheet
+  <out>.ass                 subtitles drawing the cursor over the screen share, e.g. the output of mux`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCursor(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cursorCmd)
+
+	cursorCmd.Flags().StringVarP(&cursorOutput, "out", "o", cursorOutput, "Prefix of the output filenames")
+	cursorCmd.Flags().StringVarP(&cursorFormat, "format", "f", cursorFormat, "Format of the position timeline: json, csv")
+}
@@ -16,10 +16,15 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 
+	"github.com/galli-leo/gozoom/ogg"
 	"github.com/galli-leo/gozoom/zoom"
 	"github.com/spf13/cobra"
 )
@@ -29,25 +34,53 @@ type ExtractType int
 const (
 	ExtractVideo ExtractType = iota
 	ExtractAudio
+	ExtractWebcam
 )
 
 //go:generate enumer -type=ExtractType -trimprefix=Extract
 
 var extractType string = ExtractVideo.String()
 var outputFile string = "out.h264"
+var extractFrom int64 = 0
+var extractRecover bool = false
+var extractFollow bool = false
+
+// interruptContext returns a context that is done once the process is interrupted.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		select {
+		case <-sigs:
+			logger.Infof("Interrupted, stopping")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+	return ctx, cancel
+}
 
 func runExtract(filename string, method ExtractType) {
 	logger.Infof("Extracting %s from %s to %s", method, filename, outputFile)
 	sr := zoom.NewSampleReader(logger)
+	sr.SetRecovery(extractRecover)
+	if extractFollow {
+		ctx, cancel := interruptContext()
+		defer cancel()
+		logger.Infof("Following %s until interrupted", filename)
+		sr.Follow(ctx)
+	}
 	if err := sr.Open(filename); err != nil {
 		logger.Fatalf("Failed to open file: %v", err)
 	}
-	sd := zoom.NewSampleDataReader(sr, func(pkt *zoom.SamplePacket) bool {
-		if method == ExtractAudio {
-			if pkt.MediaType() == zoom.Audio {
-				return true
-			}
+	if extractFrom > 0 {
+		if err := sr.SeekKeyframe(extractFrom, zoom.VideoScreenShare); err != nil {
+			logger.Fatalf("Failed to seek to %d: %v", extractFrom, err)
 		}
+	}
+	sd := zoom.NewSampleDataReader(sr, func(pkt *zoom.SamplePacket) bool {
 		if pkt.MediaType() == zoom.VideoScreenShare {
 			logger.Debugf("Timing: 0x%x, Name: 0x%x", pkt.TimingA, pkt.VideoProp().NameIdent)
 			return true
@@ -66,6 +99,224 @@ func runExtract(filename string, method ExtractType) {
 	if err != nil {
 		logger.Errorf("Failed to write to output: %v", err)
 	}
+	for _, r := range sr.Skipped() {
+		logger.Warnf("Skipped damaged data: %s", r)
+	}
+}
+
+// audioDetectPackets is the number of audio packets looked at to identify the codec.
+const audioDetectPackets = 50
+
+type audioPacket struct {
+	timing int64
+	data   []byte
+}
+
+// runExtractAudio writes the audio packets to an Ogg Opus file, keeping their position on the timeline of the recording.
+// Muted stretches without packets are filled with silence. ADTS audio is copied as is.
+func runExtractAudio(filename string) {
+	logger.Infof("Extracting audio from %s to %s", filename, outputFile)
+	sr := zoom.NewSampleReader(logger)
+	sr.SetRecovery(extractRecover)
+	if err := sr.Open(filename); err != nil {
+		logger.Fatalf("Failed to open file: %v", err)
+	}
+	defer sr.Close()
+	if extractFrom > 0 {
+		if err := sr.SeekTime(extractFrom); err != nil {
+			logger.Fatalf("Failed to seek to %d: %v", extractFrom, err)
+		}
+	}
+
+	// the timeline starts with the first packet of any type, so the audio lines up with the video
+	var start int64
+	started := false
+	buffered := []audioPacket{}
+	next := func() *audioPacket {
+		for sr.Next() {
+			pkt := sr.Current()
+			if !started {
+				start = pkt.TimingA
+				started = true
+			}
+			if pkt.MediaType() != zoom.Audio || len(pkt.Data) == 0 {
+				continue
+			}
+			return &audioPacket{timing: pkt.TimingA, data: pkt.Data}
+		}
+		return nil
+	}
+	for len(buffered) < audioDetectPackets {
+		p := next()
+		if p == nil {
+			break
+		}
+		if aud := sr.Current().AudioProp(); len(buffered) == 0 && aud != nil {
+			logger.Debugf("First audio packet at timing %d: %+v", p.timing, aud)
+		}
+		buffered = append(buffered, *p)
+	}
+	if len(buffered) == 0 {
+		logger.Fatalf("No audio packets found")
+	}
+	data := make([][]byte, len(buffered))
+	for i, p := range buffered {
+		data[i] = p.data
+	}
+	codec := zoom.DetectAudioCodec(data)
+	logger.Infof("Detected %s audio", codec)
+
+	outFile, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		logger.Fatalf("Failed to open output file: %v", err)
+	}
+	defer outFile.Close()
+
+	// packets returns the buffered packets first, then continues reading
+	packets := func() *audioPacket {
+		if len(buffered) > 0 {
+			p := &buffered[0]
+			buffered = buffered[1:]
+			return p
+		}
+		return next()
+	}
+	first := buffered[0].data
+	n := 0
+	switch codec {
+	case zoom.AudioOpus:
+		// the input sample rate is informational in Ogg Opus, the rate of the recording is not known, so it is the
+		// rate Opus always decodes at
+		o, err := ogg.NewOpusWriter(outFile, zoom.OpusChannels(first), zoom.OpusSampleRate)
+		if err != nil {
+			logger.Fatalf("Failed to write Ogg headers: %v", err)
+		}
+		for p := packets(); p != nil; p = packets() {
+			samples, err := zoom.OpusPacketSamples(p.data)
+			if err != nil {
+				logger.Warnf("Skipping invalid Opus packet at timing %d: %v", p.timing, err)
+				continue
+			}
+			position := (p.timing - start) * zoom.OpusSampleRate / zoom.TimingScale
+			if err := o.WritePacket(p.data, position, samples); err != nil {
+				logger.Fatalf("Failed to write to output: %v", err)
+			}
+			n++
+		}
+		if err := o.Close(); err != nil {
+			logger.Fatalf("Failed to write to output: %v", err)
+		}
+		logger.Infof("Written %d packets (%.1fs), including %.1fs of silence in gaps", n,
+			float64(o.Position())/zoom.OpusSampleRate, float64(o.Silence)/zoom.OpusSampleRate)
+	case zoom.AudioADTS:
+		logger.Warnf("ADTS streams carry no timestamps, gaps in the audio are not kept")
+		for p := packets(); p != nil; p = packets() {
+			if _, err := outFile.Write(p.data); err != nil {
+				logger.Fatalf("Failed to write to output: %v", err)
+			}
+			n++
+		}
+		logger.Infof("Written %d packets", n)
+	default:
+		logger.Fatalf("Failed to identify the audio codec, first packet: %x", first)
+	}
+
+	if err := sr.Err(); err != nil {
+		logger.Errorf("Failed to read all packets: %v", err)
+	}
+	for _, r := range sr.Skipped() {
+		logger.Warnf("Skipped damaged data: %s", r)
+	}
+}
+
+// participantFilename inserts the participant ident before the extension of filename.
+func participantFilename(filename string, ident uint32) string {
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s_%x%s", strings.TrimSuffix(filename, ext), ident, ext)
+}
+
+type webcamStream struct {
+	out    *os.File
+	width  int32
+	height int32
+	bytes  int64
+}
+
+// runExtractWebcam writes the webcam video of every participant to its own H.264 elementary stream.
+// With --from, the stream of each participant starts at their first keyframe at or after that timing.
+func runExtractWebcam(filename string) {
+	logger.Infof("Extracting webcams from %s", filename)
+	sr := zoom.NewSampleReader(logger)
+	sr.SetRecovery(extractRecover)
+	if err := sr.Open(filename); err != nil {
+		logger.Fatalf("Failed to open file: %v", err)
+	}
+	defer sr.Close()
+	if extractFrom > 0 {
+		if err := sr.SeekTime(extractFrom); err != nil {
+			logger.Fatalf("Failed to seek to %d: %v", extractFrom, err)
+		}
+	}
+
+	streams := map[uint32]*webcamStream{}
+	order := []uint32{}
+	// packets dropped before the first keyframe of a participant
+	dropped := map[uint32]int{}
+	for sr.Next() {
+		pkt := sr.Current()
+		if pkt.MediaType() != zoom.VideoWebCam || len(pkt.Data) == 0 {
+			continue
+		}
+		vid := pkt.VideoProp()
+		if vid == nil {
+			logger.Warnf("Webcam packet without property at timing %d", pkt.TimingA)
+			continue
+		}
+		ident := uint32(vid.NameIdent)
+		s, ok := streams[ident]
+		if !ok && extractFrom > 0 && !pkt.IsKeyframe() {
+			dropped[ident]++
+			continue
+		}
+		if !ok {
+			name := participantFilename(outputFile, ident)
+			out, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				logger.Fatalf("Failed to open output file: %v", err)
+			}
+			defer out.Close()
+			s = &webcamStream{out: out, width: vid.Width, height: vid.Height}
+			streams[ident] = s
+			order = append(order, ident)
+			logger.Infof("Found %dx%d webcam of participant 0x%x, writing to %s", vid.Width, vid.Height, ident, name)
+		} else if vid.Width != s.width || vid.Height != s.height {
+			logger.Infof("Webcam of participant 0x%x changed resolution from %dx%d to %dx%d at timing %d", ident, s.width, s.height, vid.Width, vid.Height, pkt.TimingA)
+			s.width = vid.Width
+			s.height = vid.Height
+		}
+		n, err := s.out.Write(pkt.Data)
+		s.bytes += int64(n)
+		if err != nil {
+			logger.Fatalf("Failed to write to output: %v", err)
+		}
+	}
+
+	for _, ident := range order {
+		logger.Infof("Written %d bytes of participant 0x%x", streams[ident].bytes, ident)
+	}
+	for ident, n := range dropped {
+		if _, ok := streams[ident]; !ok {
+			logger.Warnf("No keyframe of participant 0x%x after %d, dropped all %d packets", ident, extractFrom, n)
+		} else {
+			logger.Debugf("Dropped %d packets of participant 0x%x before their first keyframe", n, ident)
+		}
+	}
+	if err := sr.Err(); err != nil {
+		logger.Errorf("Failed to read all packets: %v", err)
+	}
+	for _, r := range sr.Skipped() {
+		logger.Warnf("Skipped damaged data: %s", r)
+	}
 }
 
 // extractCmd represents the extract command
@@ -83,7 +334,20 @@ to quickly create a Cobra application.`,
 		if err != nil {
 			return err
 		}
-		runExtract(args[0], t)
+		if extractFollow && t != ExtractVideo {
+			return fmt.Errorf("Following a recording is only supported for video")
+		}
+		switch t {
+		case ExtractWebcam:
+			runExtractWebcam(args[0])
+		case ExtractAudio:
+			if !cmd.Flags().Changed("out") {
+				outputFile = "out.ogg"
+			}
+			runExtractAudio(args[0])
+		default:
+			runExtract(args[0], t)
+		}
 
 		return nil
 	},
@@ -101,7 +365,10 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// extractCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	extractCmd.Flags().StringVarP(&extractType, "type", "t", extractType, "Type of data to extract: video, audio")
+	extractCmd.Flags().StringVarP(&extractType, "type", "t", extractType, "Type of data to extract: video, audio, webcam")
 	extractCmd.Flags().StringVarP(&outputFile, "out", "o", outputFile, "Output filename")
+	extractCmd.Flags().Int64VarP(&extractFrom, "from", "f", extractFrom, "Start extracting at this timing, video at the preceding screen share keyframe, webcams at the following keyframe of each participant and audio at the following packet")
+	extractCmd.Flags().BoolVarP(&extractRecover, "recover", "r", extractRecover, "Skip over damaged packets instead of stopping")
+	extractCmd.Flags().BoolVar(&extractFollow, "follow", extractFollow, "Keep extracting the screen share of a recording that is still being written, until interrupted")
 	extractCmd.MarkZshCompPositionalArgumentFile(1, "*.zoom")
 }
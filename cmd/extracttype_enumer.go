@@ -7,9 +7,9 @@ import (
 	"fmt"
 )
 
-const _ExtractTypeName = "VideoAudio"
+const _ExtractTypeName = "VideoAudioWebcam"
 
-var _ExtractTypeIndex = [...]uint8{0, 5, 10}
+var _ExtractTypeIndex = [...]uint8{0, 5, 10, 16}
 
 func (i ExtractType) String() string {
 	if i < 0 || i >= ExtractType(len(_ExtractTypeIndex)-1) {
@@ -18,11 +18,12 @@ func (i ExtractType) String() string {
 	return _ExtractTypeName[_ExtractTypeIndex[i]:_ExtractTypeIndex[i+1]]
 }
 
-var _ExtractTypeValues = []ExtractType{0, 1}
+var _ExtractTypeValues = []ExtractType{0, 1, 2}
 
 var _ExtractTypeNameToValueMap = map[string]ExtractType{
-	_ExtractTypeName[0:5]:  0,
-	_ExtractTypeName[5:10]: 1,
+	_ExtractTypeName[0:5]:   0,
+	_ExtractTypeName[5:10]:  1,
+	_ExtractTypeName[10:16]: 2,
 }
 
 // ExtractTypeString retrieves an enum value from the enum constants string name.
@@ -16,34 +16,32 @@ limitations under the License.
 package cmd
 
 import (
-	"io"
-	"os"
-
 	"github.com/galli-leo/gozoom/zoom"
 	"github.com/spf13/cobra"
 )
 
+// runFile logs the avatar packets of a file, the avatars command saves them.
 func runFile(filename string) {
 	logger.Infof("testing file %s", filename)
 	sr := zoom.NewSampleReader(logger)
 	if err := sr.Open(filename); err != nil {
 		logger.Fatalf("Failed to open file: %v", err)
 	}
+	defer sr.Close()
+	avatars := &zoom.AvatarCollector{}
 	for sr.Next() {
 		curr := sr.Current()
 		if curr.MediaType() == zoom.Avatar {
 			logger.Infof("Have avatar packet: %+v", curr.Property)
-			outf, err := os.OpenFile("avatar.png", os.O_CREATE|os.O_RDWR, 0777)
-			if err != nil {
-				logger.Fatalf("Failed to open file: %v", err)
-			}
-			defer outf.Close()
-			_, err = outf.Write(curr.Data)
-			if err != io.EOF && err != nil {
-				logger.Fatalf("Failed to write data: %v", err)
+			if a := avatars.Add(curr); a != nil {
+				logger.Infof("New avatar of 0x%x: %d bytes of %s", a.Ident, len(a.Data), a.Ext())
 			}
 		}
 	}
+	if err := sr.Err(); err != nil {
+		logger.Errorf("Failed to read all packets: %v", err)
+	}
+	logger.Infof("Found %d distinct avatars, save them with the avatars command", len(avatars.Avatars))
 }
 
 // fileCmd represents the file command
@@ -0,0 +1,93 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var mergeGap int64 = zoom.DefaultSessionGap
+var mergeOutput string = "merged"
+var mergeOrder string = "name"
+
+func runMerge(paths []string) error {
+	merged, err := openMerged(paths)
+	if err != nil {
+		return err
+	}
+	defer merged.Close()
+	if err := os.MkdirAll(mergeOutput, 0755); err != nil {
+		return err
+	}
+
+	samples := filepath.Join(mergeOutput, "double_click_to_convert_01.zoom")
+	w, err := zoom.CreateFile(samples, logger)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	stats, err := merged.MergeSamples(w)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Written %d packets to %s, dropped %d video packets before the first keyframe of a session", stats.Packets, samples, stats.Dropped)
+	if stats.ParameterChanges > 0 {
+		logger.Infof("Parameter sets changed %d times between sessions, every session starts with its own", stats.ParameterChanges)
+	}
+
+	commands := filepath.Join(mergeOutput, "double_click_to_convert_02.zoom")
+	cw, err := zoom.CreateFile(commands, logger)
+	if err != nil {
+		return err
+	}
+	defer cw.Close()
+	n, err := merged.MergeCommands(cw)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Written %d commands to %s", n, commands)
+	return nil
+}
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge <recording>...",
+	Short: "Merges the recordings of a paused or restarted meeting into one",
+	Long: `Pausing or restarting a meeting produces several recording folders, each with its own timing base.
+By default the recordings are ordered by their folder name, which Zoom derives from the date and time of the meeting,
+and their timings are shifted so that each one follows the previous one after --gap. With --order args they are kept
+in the order given, with --order timing they are ordered by the timing of their first packet, which only works if the
+timing did not restart between the recordings.
+
+The merged packets are written as a single recording to the output folder. To get an MP4 or MKV file instead,
+pass the same recordings to mux. Every video stream restarts at a keyframe carrying its parameter sets in each session.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMerge(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.Flags().Int64Var(&mergeGap, "gap", mergeGap, "Time between recordings, in TimingA units (ms)")
+	mergeCmd.Flags().StringVar(&mergeOrder, "order", mergeOrder, "Order of the recordings: name, args, timing")
+	mergeCmd.Flags().StringVarP(&mergeOutput, "out", "o", mergeOutput, "Folder to write the merged recording to")
+}
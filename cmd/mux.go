@@ -0,0 +1,407 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/galli-leo/gozoom/mkv"
+	"github.com/galli-leo/gozoom/mp4"
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var muxOutput string = "out.mp4"
+var muxFormat string = ""
+
+// muxStream is one output track: the screen share, the webcam of a single participant or the audio.
+type muxStream struct {
+	Kind  zoom.TrackKind
+	Ident uint32
+	Name  string
+	// SPS and PPS of the first keyframe, only set for video.
+	SPS []byte
+	PPS []byte
+	// Channels of the audio, only set for Opus audio.
+	Channels int
+
+	// first packets of the audio, to identify the codec
+	audio [][]byte
+}
+
+func (s *muxStream) IsVideo() bool {
+	return s.Kind == zoom.TrackScreenShare || s.Kind == zoom.TrackWebCam
+}
+
+type muxKey struct {
+	kind  zoom.TrackKind
+	ident uint32
+}
+
+// muxTracks are the tracks of a recording that end up in the output.
+var muxTracks = []zoom.TrackKind{zoom.TrackScreenShare, zoom.TrackWebCam, zoom.TrackAudio}
+
+func packetKey(kind zoom.TrackKind, pkt *zoom.SamplePacket) muxKey {
+	key := muxKey{kind: kind}
+	if kind == zoom.TrackWebCam {
+		if vid := pkt.VideoProp(); vid != nil {
+			key.ident = uint32(vid.NameIdent)
+		}
+	}
+	return key
+}
+
+// openRecordingPath opens a recording folder or a single sample file.
+func openRecordingPath(path string) (*zoom.Recording, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var rec *zoom.Recording
+	if info.IsDir() {
+		rec, err = zoom.OpenRecording(path, logger)
+	} else {
+		rec, err = zoom.OpenRecordingFiles([]string{path}, logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec.CmdTypes = cmdTypes
+	return rec, nil
+}
+
+// readMuxPackets calls fn for every packet of the muxed tracks, session after session,
+// merged in TimingA order within a session and rebased onto the merged timeline.
+func readMuxPackets(m *zoom.Merged, fn func(s *zoom.Session, kind zoom.TrackKind, pkt *zoom.SamplePacket) error) error {
+	for _, s := range m.Sessions {
+		if err := readSessionPackets(s, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSessionPackets(s *zoom.Session, fn func(s *zoom.Session, kind zoom.TrackKind, pkt *zoom.SamplePacket) error) error {
+	kinds := []zoom.TrackKind{}
+	readers := []*zoom.TrackReader{}
+	for _, kind := range muxTracks {
+		t := s.Track(kind)
+		if t == nil {
+			continue
+		}
+		r := t.Reader()
+		if r.Next() {
+			kinds = append(kinds, kind)
+			readers = append(readers, r)
+		} else if r.Error() != nil {
+			return fmt.Errorf("Failed to read %s track: %w", kind, r.Error())
+		}
+	}
+
+	for len(readers) > 0 {
+		next := 0
+		for i, r := range readers {
+			if r.Entry().TimingA < readers[next].Entry().TimingA {
+				next = i
+			}
+		}
+		r := readers[next]
+		pkt := r.Current()
+		s.Rebase(pkt)
+		if err := fn(s, kinds[next], pkt); err != nil {
+			return err
+		}
+		if !r.Next() {
+			if r.Error() != nil {
+				return fmt.Errorf("Failed to read %s track: %w", kinds[next], r.Error())
+			}
+			kinds = append(kinds[:next], kinds[next+1:]...)
+			readers = append(readers[:next], readers[next+1:]...)
+		}
+	}
+	return nil
+}
+
+// findMuxStreams splits the tracks of the recording into output streams and finds the parameter sets of the video streams.
+// Video streams without any SPS and PPS cannot be decoded and are left out.
+func findMuxStreams(merged *zoom.Merged) ([]*muxStream, error) {
+	roster, err := merged.Roster()
+	if err != nil {
+		logger.Warnf("Failed to read participants: %v", err)
+	}
+	streams := map[muxKey]*muxStream{}
+	order := []muxKey{}
+	err = readMuxPackets(merged, func(_ *zoom.Session, kind zoom.TrackKind, pkt *zoom.SamplePacket) error {
+		key := packetKey(kind, pkt)
+		s, ok := streams[key]
+		if !ok {
+			s = &muxStream{Kind: kind, Ident: key.ident}
+			switch kind {
+			case zoom.TrackScreenShare:
+				s.Name = "Screen share"
+			case zoom.TrackWebCam:
+				s.Name = roster.Name(key.ident)
+			case zoom.TrackAudio:
+				s.Name = "Audio"
+			}
+			streams[key] = s
+			order = append(order, key)
+		}
+		if s.IsVideo() && s.SPS == nil {
+			if sps, pps, _ := pkt.ParameterSets(); sps != nil && pps != nil {
+				s.SPS = sps
+				s.PPS = pps
+			}
+		}
+		if kind == zoom.TrackAudio && len(s.audio) < audioDetectPackets && len(pkt.Data) > 0 {
+			s.audio = append(s.audio, pkt.Data)
+		}
+		return nil
+	})
+
+	ret := []*muxStream{}
+	for _, key := range order {
+		s := streams[key]
+		if s.IsVideo() && s.SPS == nil {
+			logger.Warnf("No parameter sets found for %s, skipping it", s.Name)
+			continue
+		}
+		if s.Kind == zoom.TrackAudio {
+			codec := zoom.DetectAudioCodec(s.audio)
+			if codec != zoom.AudioOpus {
+				logger.Warnf("Only Opus audio can be muxed, skipping %s audio", codec)
+				continue
+			}
+			s.Channels = 1
+			for _, data := range s.audio {
+				if zoom.OpusChannels(data) == 2 {
+					s.Channels = 2
+				}
+			}
+			s.audio = nil
+		}
+		ret = append(ret, s)
+	}
+	return ret, err
+}
+
+// muxFormatFor returns the output format, guessing it from the output filename if it was not given.
+func muxFormatFor(format, filename string) string {
+	if format != "" {
+		return format
+	}
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+}
+
+// openMerged opens every path as a recording folder or a single sample file, and merges them into one timeline.
+// sortRecordings orders the recordings according to --order.
+func sortRecordings(recs []*zoom.Recording) error {
+	switch mergeOrder {
+	case "name":
+		zoom.SortRecordingsByPath(recs)
+	case "timing":
+		zoom.SortRecordingsByStart(recs)
+	case "args":
+	default:
+		return fmt.Errorf("Unknown order %q, expected name, args or timing", mergeOrder)
+	}
+	return nil
+}
+
+func openMerged(paths []string) (*zoom.Merged, error) {
+	recs := []*zoom.Recording{}
+	for _, path := range paths {
+		rec, err := openRecordingPath(path)
+		if err != nil {
+			for _, r := range recs {
+				r.Close()
+			}
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	if err := sortRecordings(recs); err != nil {
+		for _, r := range recs {
+			r.Close()
+		}
+		return nil, err
+	}
+	merged := zoom.MergeRecordings(recs, mergeGap)
+	merged.CmdTypes = cmdTypes
+	for i, s := range merged.Sessions {
+		logger.Infof("Session %d: %s, %.3fs, starting at %.3fs", i+1, s.Path, zoom.TimingToDuration(s.Duration()).Seconds(), zoom.TimingToDuration(merged.Rel(s.Start+s.Offset)).Seconds())
+	}
+	return merged, nil
+}
+
+func runMux(paths []string) error {
+	merged, err := openMerged(paths)
+	if err != nil {
+		return err
+	}
+	defer merged.Close()
+
+	streams, err := findMuxStreams(merged)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return fmt.Errorf("No streams found in %s", strings.Join(paths, ", "))
+	}
+
+	var write func(merged *zoom.Merged, streams []*muxStream, out io.Writer) error
+	format := muxFormatFor(muxFormat, muxOutput)
+	switch format {
+	case "mp4":
+		write = writeMP4
+	case "mkv":
+		write = writeMKV
+	default:
+		return fmt.Errorf("Unknown output format %q", format)
+	}
+
+	out, err := openOutput(muxOutput)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	logger.Infof("Writing %d streams of %s to %s as %s", len(streams), strings.Join(paths, ", "), muxOutput, format)
+	return write(merged, streams, out)
+}
+
+// muxPackets calls write for every packet of the given streams, starting every video stream at its first keyframe in each session.
+// Parameter sets that differ from the ones of the track stay in the samples, so decoders pick them up.
+func muxPackets(merged *zoom.Merged, streams []*muxStream, write func(s *muxStream, pkt *zoom.SamplePacket) error) error {
+	byKey := map[muxKey]*muxStream{}
+	for _, s := range streams {
+		byKey[muxKey{s.Kind, s.Ident}] = s
+	}
+	var session *zoom.Session
+	started := map[*muxStream]bool{}
+	return readMuxPackets(merged, func(sess *zoom.Session, kind zoom.TrackKind, pkt *zoom.SamplePacket) error {
+		if sess != session {
+			// decoding starts over after a pause
+			session = sess
+			started = map[*muxStream]bool{}
+		}
+		s, ok := byKey[packetKey(kind, pkt)]
+		if !ok || len(pkt.Data) == 0 {
+			return nil
+		}
+		if s.IsVideo() && !started[s] {
+			if !pkt.IsKeyframe() {
+				// nothing to decode before the first keyframe
+				return nil
+			}
+			if sps, pps, _ := pkt.ParameterSets(); sps != nil && pps != nil && (!bytes.Equal(sps, s.SPS) || !bytes.Equal(pps, s.PPS)) {
+				logger.Warnf("Parameter sets of %s changed in %s, keeping them in the stream", s.Name, sess.Path)
+			}
+		}
+		started[s] = true
+		return write(s, pkt)
+	})
+}
+
+func writeMP4(merged *zoom.Merged, streams []*muxStream, out io.Writer) error {
+	m := mp4.NewMuxer(out, zoom.TimingScale, logger)
+	tracks := map[*muxStream]*mp4.Track{}
+	for _, s := range streams {
+		var t *mp4.Track
+		var err error
+		if s.IsVideo() {
+			t, err = m.AddH264Track(s.Name, s.SPS, s.PPS)
+		} else {
+			t, err = m.AddOpusTrack(s.Name, zoom.OpusSampleRate, s.Channels)
+		}
+		if err != nil {
+			return err
+		}
+		tracks[s] = t
+	}
+
+	err := muxPackets(merged, streams, func(s *muxStream, pkt *zoom.SamplePacket) error {
+		// TimingB is not known to be a presentation time, so the composition offset stays zero
+		return m.WriteSample(tracks[s], mp4.Sample{
+			Time:     merged.Rel(pkt.TimingA),
+			Data:     pkt.Data,
+			Keyframe: pkt.IsKeyframe(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return m.Close()
+}
+
+func writeMKV(merged *zoom.Merged, streams []*muxStream, out io.Writer) error {
+	m := mkv.NewMuxer(out, zoom.TimingScale, logger)
+	tracks := map[*muxStream]*mkv.Track{}
+	for _, s := range streams {
+		var t *mkv.Track
+		var err error
+		if s.IsVideo() {
+			t, err = m.AddH264Track(s.Name, s.SPS, s.PPS)
+		} else {
+			t, err = m.AddOpusTrack(s.Name, zoom.OpusSampleRate, s.Channels)
+		}
+		if err != nil {
+			return err
+		}
+		tracks[s] = t
+	}
+
+	err := muxPackets(merged, streams, func(s *muxStream, pkt *zoom.SamplePacket) error {
+		return m.WriteSample(tracks[s], mkv.Sample{
+			Time:     merged.Rel(pkt.TimingA),
+			Data:     pkt.Data,
+			Keyframe: pkt.IsKeyframe(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return m.Close()
+}
+
+var muxCmd = &cobra.Command{
+	Use:   "mux <file or recording>...",
+	Short: "Writes the video and audio of a recording into a single container",
+	Long: `Writes the screen share, the webcam of every participant and the audio of a sample file or recording folder into one file.
+Every stream becomes its own track, timestamped from the sample headers, so the result plays without further processing.
+
+Several recordings of a paused or restarted meeting are merged into one timeline, like the merge command does.
+
+Supported formats: mp4 (fragmented) and mkv (Matroska). By default the format is taken from the extension of the output file.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMux(args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(muxCmd)
+
+	muxCmd.Flags().StringVarP(&muxOutput, "out", "o", muxOutput, "Output filename")
+	muxCmd.Flags().StringVarP(&muxFormat, "format", "f", muxFormat, "Output format: mp4, mkv")
+	muxCmd.Flags().StringVar(&mergeOrder, "order", mergeOrder, "Order of merged recordings: name, args, timing")
+	muxCmd.Flags().Int64Var(&mergeGap, "gap", mergeGap, "Time between merged recordings, in TimingA units (ms)")
+}
@@ -0,0 +1,369 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/galli-leo/gozoom/parser"
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/nareix/joy5/codec/h264"
+	"github.com/spf13/cobra"
+)
+
+var probeJSON bool = false
+
+type probeMedia struct {
+	Type     string  `json:"type"`
+	Packets  int     `json:"packets"`
+	Bytes    int64   `json:"bytes"`
+	First    int64   `json:"first_timing"`
+	Last     int64   `json:"last_timing"`
+	Duration float64 `json:"duration"`
+}
+
+type probeResolution struct {
+	Type        string `json:"type"`
+	Participant uint32 `json:"participant"`
+	Width       int32  `json:"width"`
+	Height      int32  `json:"height"`
+	Timing      int64  `json:"timing"`
+}
+
+type probeH264 struct {
+	Type       string `json:"type"`
+	Profile    string `json:"profile"`
+	ProfileIdc uint   `json:"profile_idc"`
+	LevelIdc   uint   `json:"level_idc"`
+	Width      uint   `json:"width"`
+	Height     uint   `json:"height"`
+	Entropy    string `json:"entropy"`
+}
+
+type probeFile struct {
+	Path          string            `json:"path"`
+	Kind          string            `json:"kind"`
+	VersionInfo   uint32            `json:"version_info,omitempty"`
+	VersionNumber int               `json:"version_number,omitempty"`
+	IsMC          bool              `json:"is_mc"`
+	Packets       int               `json:"packets"`
+	Media         []*probeMedia     `json:"media,omitempty"`
+	Resolutions   []probeResolution `json:"resolutions,omitempty"`
+	H264          []probeH264       `json:"h264,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+type probeAvatar struct {
+	Hash   string `json:"hash"`
+	Format string `json:"format"`
+	Bytes  int    `json:"bytes"`
+	Timing int64  `json:"timing"`
+}
+
+type probeParticipant struct {
+	Ident   uint32        `json:"ident"`
+	Name    string        `json:"name"`
+	Seen    []string      `json:"seen_in"`
+	Avatars []probeAvatar `json:"avatars,omitempty"`
+}
+
+type probeResult struct {
+	Path         string              `json:"path"`
+	Duration     float64             `json:"duration"`
+	Files        []*probeFile        `json:"files"`
+	Participants []*probeParticipant `json:"participants"`
+}
+
+// probeParticipants collects the NameIdents seen, together with where they were seen.
+type probeParticipants struct {
+	byIdent map[uint32]*probeParticipant
+	order   []*probeParticipant
+	avatars zoom.AvatarCollector
+}
+
+func (p *probeParticipants) see(ident uint32, where string) *probeParticipant {
+	if p.byIdent == nil {
+		p.byIdent = map[uint32]*probeParticipant{}
+	}
+	part, ok := p.byIdent[ident]
+	if !ok {
+		part = &probeParticipant{Ident: ident}
+		p.byIdent[ident] = part
+		p.order = append(p.order, part)
+	}
+	for _, s := range part.Seen {
+		if s == where {
+			return part
+		}
+	}
+	part.Seen = append(part.Seen, where)
+	return part
+}
+
+// summarizeH264 summarizes the parameter sets in nalus. The entropy coding mode is the one of the PPS referenced by
+// the first slice in nalus, or of the PPS with the lowest id if there is no slice.
+func summarizeH264(typ zoom.MediaType, nalus [][]byte) (probeH264, error) {
+	p, err := parser.ParseParameterSets(nalus, logger)
+	if err != nil {
+		return probeH264{}, err
+	}
+	ret := probeH264{
+		Type:       typ.String(),
+		Profile:    h264.ProfileMap[p.SPS.ProfileIdc],
+		ProfileIdc: p.SPS.ProfileIdc,
+		LevelIdc:   p.SPS.LevelIdc,
+		Width:      p.SPS.FrameWidth(),
+		Height:     p.SPS.FrameHeight(),
+		Entropy:    "unknown",
+	}
+
+	var pps *parser.PPSInfo
+	for _, nalu := range nalus {
+		if len(nalu) == 0 || (h264.NALUType(nalu) != h264.NALU_IDR && h264.NALUType(nalu) != h264.NALU_NONIDR) {
+			continue
+		}
+		id, err := parser.SlicePPSId(nalu)
+		if err != nil {
+			logger.Warnf("Failed to read pic_parameter_set_id of slice: %v", err)
+		} else if pps = p.PPSInfos[id]; pps == nil {
+			logger.Warnf("Slice references unknown PPS %d", id)
+		}
+		break
+	}
+	if pps == nil {
+		for id, info := range p.PPSInfos {
+			if pps == nil || id < pps.Id {
+				pps = info
+			}
+		}
+	}
+	if pps != nil {
+		ret.Entropy = "CAVLC"
+		if pps.EntropyCodingModeFlag != 0 {
+			ret.Entropy = "CABAC"
+		}
+	}
+	return ret, nil
+}
+
+func probeSampleFile(pf *probeFile, f *zoom.File, parts *probeParticipants) {
+	pf.Kind = "samples"
+	pf.VersionInfo = f.Header.VersionInfo
+	pf.VersionNumber = f.Header.VersionNumber()
+	pf.IsMC = f.Header.IsMC()
+
+	sr := zoom.NewSampleReader(logger)
+	if err := sr.OpenFile(f); err != nil {
+		pf.Error = err.Error()
+		return
+	}
+	media := map[zoom.MediaType]*probeMedia{}
+	lastRes := map[string]probeResolution{}
+	seenParams := map[string]bool{}
+
+	for sr.Next() {
+		pkt := sr.Current()
+		pf.Packets++
+		m, ok := media[pkt.MediaType()]
+		if !ok {
+			m = &probeMedia{Type: pkt.MediaType().String(), First: pkt.TimingA, Last: pkt.TimingA}
+			media[pkt.MediaType()] = m
+			pf.Media = append(pf.Media, m)
+		}
+		m.Packets++
+		m.Bytes += int64(pkt.DataSize)
+		if pkt.TimingA < m.First {
+			m.First = pkt.TimingA
+		}
+		if pkt.TimingA > m.Last {
+			m.Last = pkt.TimingA
+		}
+
+		if vid := pkt.VideoProp(); vid != nil {
+			ident := uint32(vid.NameIdent)
+			parts.see(ident, pkt.MediaType().String())
+			key := fmt.Sprintf("%d/%d", pkt.Type, ident)
+			res := probeResolution{pkt.MediaType().String(), ident, vid.Width, vid.Height, pkt.TimingA}
+			if prev, ok := lastRes[key]; !ok || prev.Width != res.Width || prev.Height != res.Height {
+				pf.Resolutions = append(pf.Resolutions, res)
+				lastRes[key] = res
+			}
+		}
+		if cur := pkt.CursorProp(); cur != nil {
+			parts.see(uint32(cur.NameIdent), pkt.MediaType().String())
+		}
+		if avatar := pkt.AvatarProp(); avatar != nil {
+			part := parts.see(uint32(avatar.NameIdent), pkt.MediaType().String())
+			if a := parts.avatars.Add(pkt); a != nil {
+				part.Avatars = append(part.Avatars, probeAvatar{a.Hash, strings.TrimPrefix(a.Ext(), "."), len(a.Data), a.Timing})
+			}
+		}
+
+		if pkt.IsVideo() {
+			nalus := pkt.NALUs()
+			params := [][]byte{}
+			for _, nalu := range nalus {
+				if len(nalu) > 0 && (h264.NALUType(nalu) == h264.NALU_SPS || h264.NALUType(nalu) == h264.NALU_PPS) {
+					params = append(params, nalu)
+				}
+			}
+			key := fmt.Sprintf("%d/%x", pkt.Type, params)
+			if len(params) > 0 && !seenParams[key] {
+				seenParams[key] = true
+				summary, err := summarizeH264(pkt.MediaType(), nalus)
+				if err != nil {
+					logger.Warnf("Failed to parse parameter sets: %v", err)
+				} else {
+					pf.H264 = append(pf.H264, summary)
+				}
+			}
+		}
+	}
+
+	if err := sr.Err(); err != nil {
+		pf.Error = err.Error()
+	}
+
+	for _, m := range pf.Media {
+		m.Duration = zoom.TimingToDuration(m.Last - m.First).Seconds()
+	}
+}
+
+func probeCmdFile(pf *probeFile, rec *zoom.Recording, parts *probeParticipants) *zoom.Roster {
+	pf.Kind = "commands"
+	events, err := rec.Events()
+	if err != nil {
+		pf.Error = err.Error()
+	}
+	pf.Packets = len(events)
+	roster := zoom.NewRoster(events)
+	for _, p := range roster.Participants() {
+		parts.see(p.Ident, "Commands")
+	}
+	return roster
+}
+
+func runProbe(path string) (*probeResult, error) {
+	rec, err := openRecordingPath(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Close()
+
+	res := &probeResult{Path: path, Duration: zoom.TimingToDuration(rec.Duration()).Seconds()}
+	parts := &probeParticipants{}
+	for _, f := range rec.SampleFiles {
+		pf := &probeFile{Path: rec.Filename(f)}
+		res.Files = append(res.Files, pf)
+		probeSampleFile(pf, f, parts)
+	}
+	var roster *zoom.Roster
+	if rec.CmdFile != nil {
+		pf := &probeFile{Path: rec.Filename(rec.CmdFile)}
+		res.Files = append(res.Files, pf)
+		roster = probeCmdFile(pf, rec, parts)
+	}
+	for _, filename := range rec.Unknown {
+		res.Files = append(res.Files, &probeFile{Path: filename, Kind: "unknown"})
+	}
+	sort.SliceStable(res.Files, func(i, j int) bool {
+		return res.Files[i].Path < res.Files[j].Path
+	})
+
+	res.Participants = parts.order
+	for _, p := range res.Participants {
+		if roster != nil {
+			p.Name = roster.Name(p.Ident)
+		} else {
+			p.Name = (&zoom.Participant{Ident: p.Ident}).DisplayName()
+		}
+	}
+
+	return res, nil
+}
+
+func printProbe(w io.Writer, res *probeResult) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s: duration %.3fs\n", res.Path, res.Duration)
+	for _, f := range res.Files {
+		fmt.Fprintf(tw, "\nFile %s (%s), %d packets\n", f.Path, f.Kind, f.Packets)
+		if f.Error != "" {
+			fmt.Fprintf(tw, "  Error: %s\n", f.Error)
+		}
+		if f.Kind == "samples" {
+			fmt.Fprintf(tw, "  Version info: 0x%x, version number: %d, MC: %t\n", f.VersionInfo, f.VersionNumber, f.IsMC)
+		}
+		if len(f.Media) > 0 {
+			fmt.Fprintf(tw, "  Media\tPackets\tBytes\tDuration\n")
+			for _, m := range f.Media {
+				fmt.Fprintf(tw, "  %s\t%d\t%d\t%.3fs\n", m.Type, m.Packets, m.Bytes, m.Duration)
+			}
+		}
+		for _, r := range f.Resolutions {
+			fmt.Fprintf(tw, "  %s of 0x%x: %dx%d at %d\n", r.Type, r.Participant, r.Width, r.Height, r.Timing)
+		}
+		for _, h := range f.H264 {
+			fmt.Fprintf(tw, "  %s H.264: %s (%d) level %d, %dx%d, %s\n", h.Type, h.Profile, h.ProfileIdc, h.LevelIdc, h.Width, h.Height, h.Entropy)
+		}
+	}
+	if len(res.Participants) > 0 {
+		fmt.Fprintf(tw, "\nParticipant\tName\tSeen in\tAvatars\n")
+		for _, p := range res.Participants {
+			avatars := []string{}
+			for _, a := range p.Avatars {
+				avatars = append(avatars, fmt.Sprintf("%s %.8s", a.Format, a.Hash))
+			}
+			fmt.Fprintf(tw, "0x%x\t%s\t%v\t%s\n", p.Ident, p.Name, p.Seen, strings.Join(avatars, ", "))
+		}
+	}
+	tw.Flush()
+}
+
+// probeCmd represents the probe command
+var probeCmd = &cobra.Command{
+	Use:   "probe <file or recording>",
+	Short: "Summarizes a .zoom file or recording folder",
+	Long: `Prints the header information, per media type packet statistics, participants,
+screen share resolutions and H.264 parameters of a .zoom file or of all files in a recording folder.
+The command type values of join, leave and chat events have not been confirmed, so participants are only named
+once their types are given with --cmd-types, e.g. --cmd-types ParticipantJoined=1.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res, err := runProbe(args[0])
+		if err != nil {
+			return err
+		}
+		if probeJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(res)
+		}
+		printProbe(os.Stdout, res)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(probeCmd)
+
+	probeCmd.Flags().BoolVar(&probeJSON, "json", probeJSON, "Output the summary as JSON")
+}
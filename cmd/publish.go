@@ -0,0 +1,168 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/nareix/joy5/av"
+	"github.com/nareix/joy5/format/flv"
+	"github.com/nareix/joy5/format/rtmp"
+	"github.com/spf13/cobra"
+)
+
+var publishWebcam string = ""
+var publishRealtime bool = true
+
+// publishFilter selects the screen share, or the webcam of the given participant.
+func publishFilter() (zoom.SampleDataFilter, error) {
+	if publishWebcam == "" {
+		return func(pkt *zoom.SamplePacket) bool {
+			return pkt.MediaType() == zoom.VideoScreenShare
+		}, nil
+	}
+	ident, err := strconv.ParseUint(publishWebcam, 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid participant %q: %w", publishWebcam, err)
+	}
+	return func(pkt *zoom.SamplePacket) bool {
+		vid := pkt.VideoProp()
+		return pkt.MediaType() == zoom.VideoWebCam && vid != nil && uint32(vid.NameIdent) == uint32(ident)
+	}, nil
+}
+
+// unbufferedConn sends every write straight to the connection, rtmp.Conn has no way to flush its own buffer.
+type unbufferedConn struct {
+	*bufio.Reader
+	net.Conn
+}
+
+func (c unbufferedConn) Read(b []byte) (int, error) {
+	return c.Reader.Read(b)
+}
+
+func (c unbufferedConn) Flush() error {
+	return nil
+}
+
+// dialRTMP connects to an RTMP server to publish packets, e.g. the ones read from a zoom.Demuxer.
+// Unlike rtmp.Client, writes are not buffered, so every packet is sent as soon as it is written.
+// The returned net.Conn has to be closed once publishing is done.
+func dialRTMP(rawURL string) (*rtmp.Conn, net.Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	nc, err := net.DialTimeout("tcp", rtmp.UrlGetHost(u), 15*time.Second)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to connect to %s: %w", u.Host, err)
+	}
+	c := rtmp.NewConn(unbufferedConn{bufio.NewReader(nc), nc})
+	c.URL = u
+	nc.SetDeadline(time.Now().Add(15 * time.Second))
+	if err := c.Prepare(rtmp.StageGotPublishOrPlayCommand, rtmp.PrepareWriting); err != nil {
+		nc.Close()
+		return nil, nil, fmt.Errorf("Failed to start publishing to %s: %w", rawURL, err)
+	}
+	nc.SetDeadline(time.Time{})
+	return c, nc, nil
+}
+
+func runPublish(filename, target string) error {
+	filter, err := publishFilter()
+	if err != nil {
+		return err
+	}
+	sr := zoom.NewSampleReader(logger)
+	if err := sr.Open(filename); err != nil {
+		return err
+	}
+	defer sr.Close()
+	d := zoom.NewDemuxer(sr, filter, logger)
+
+	var w av.PacketWriter
+	realtime := false
+	if strings.HasPrefix(target, "rtmp://") {
+		c, nc, err := dialRTMP(target)
+		if err != nil {
+			return err
+		}
+		defer nc.Close()
+		w = c
+		realtime = publishRealtime
+	} else {
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		m := flv.NewMuxer(out)
+		m.HasVideo = true
+		w = m
+	}
+
+	logger.Infof("Publishing %s to %s", filename, target)
+	start := time.Now()
+	n := 0
+	for {
+		pkt, err := d.ReadPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if realtime {
+			if wait := pkt.Time - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		if err := w.WritePacket(pkt); err != nil {
+			return fmt.Errorf("Failed to write packet: %w", err)
+		}
+		n++
+	}
+	logger.Infof("Published %d packets", n)
+	return nil
+}
+
+var publishCmd = &cobra.Command{
+	Use:   "publish <file> <rtmp url or flv file>",
+	Short: "Publishes the video of a zoom file over RTMP or writes it as FLV",
+	Long: `Publishes the screen share (or the webcam of one participant) of a sample file to an RTMP server,
+or writes it to an FLV file if the target is not an rtmp:// URL.
+Over RTMP the packets are sent in real time, according to their timestamps.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPublish(args[0], args[1])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(publishCmd)
+
+	publishCmd.Flags().StringVarP(&publishWebcam, "webcam", "w", publishWebcam, "Publish the webcam of the participant with this ident, e.g. 0x1000, instead of the screen share")
+	publishCmd.Flags().BoolVar(&publishRealtime, "realtime", publishRealtime, "Pace RTMP packets according to their timestamps")
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/galli-leo/gozoom/internal/testdata"
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/nareix/joy5/av"
+	"github.com/nareix/joy5/format/rtmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writePublishFile writes a sample file with two screen share frames, the first of them a keyframe, and an audio packet.
+func writePublishFile(t *testing.T) string {
+	idr := []byte{0, 0, 0, 1}
+	idr = append(idr, testdata.SPS...)
+	idr = append(idr, 0, 0, 0, 1)
+	idr = append(idr, testdata.PPS...)
+	idr = append(idr, 0, 0, 0, 1, 0x65, 0x88, 0x84)
+	videoProp := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 5, 0, 0, 0xd0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	newSample := func(typ zoom.MediaType, timing int64, prop []byte, data []byte) *zoom.SamplePacket {
+		p := zoom.NewSamplePacket()
+		p.Type = int32(typ)
+		p.TimingA = timing
+		p.TimingB = timing
+		p.PropertySize = int32(len(prop))
+		p.Property = prop
+		p.DataSize = int32(len(data))
+		p.Data = data
+		return p
+	}
+
+	filename := filepath.Join(t.TempDir(), "test.zoom")
+	w, err := zoom.CreateFile(filename, logger)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBeginning(zoom.NewHeader(0x4E21)))
+	for _, p := range []*zoom.SamplePacket{
+		newSample(zoom.VideoScreenShare, 1000, videoProp, idr),
+		newSample(zoom.Audio, 1010, []byte{1, 2, 3}, []byte{0x11}),
+		newSample(zoom.VideoScreenShare, 1040, videoProp, []byte{0, 0, 0, 1, 0x41, 0x9a, 0x02}),
+	} {
+		require.NoError(t, w.WritePacket(p))
+	}
+	require.NoError(t, w.Close())
+	return filename
+}
+
+func TestPublishRTMP(t *testing.T) {
+	logger = zap.NewNop().Sugar()
+	publishRealtime = false
+	defer func() { publishRealtime = true }()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	received := make(chan []av.Packet, 1)
+	s := rtmp.NewServer()
+	s.HandleConn = func(c *rtmp.Conn, nc net.Conn) {
+		defer nc.Close()
+		pkts := []av.Packet{}
+		for {
+			pkt, err := c.ReadPacket()
+			if err != nil {
+				break
+			}
+			pkts = append(pkts, pkt)
+		}
+		received <- pkts
+	}
+	go func() {
+		nc, err := lis.Accept()
+		if err == nil {
+			s.HandleNetConn(nc)
+		}
+	}()
+
+	require.NoError(t, runPublish(writePublishFile(t), "rtmp://"+lis.Addr().String()+"/live/test"))
+
+	select {
+	case pkts := <-received:
+		require.Len(t, pkts, 3)
+		assert.Equal(t, av.H264DecoderConfig, pkts[0].Type)
+		assert.True(t, pkts[1].IsKeyFrame)
+		assert.Equal(t, time.Duration(0), pkts[1].Time)
+		assert.False(t, pkts[2].IsKeyFrame)
+		assert.Equal(t, 40*time.Millisecond, pkts[2].Time)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the server")
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var redactWebcam string = zoom.RedactDrop.String()
+var redactAvatar string = zoom.RedactReplace.String()
+var redactAudio string = zoom.RedactKeep.String()
+var redactParticipants []string = nil
+var redactNames bool = true
+var redactChat bool = true
+var redactOutput string = "redacted"
+var redactReport string = ""
+var redactKeepCmdTypes []string = nil
+
+func parseRedactAction(media, value string) (zoom.RedactAction, error) {
+	a, err := zoom.RedactActionString(strings.Title(value))
+	if err != nil {
+		return a, fmt.Errorf("Invalid action %q for %s: %w", value, media, err)
+	}
+	return a, nil
+}
+
+func redactionFromFlags() (*zoom.Redaction, error) {
+	r := &zoom.Redaction{Names: redactNames, Chat: redactChat, CmdTypes: cmdTypes}
+	var err error
+	if r.Webcam, err = parseRedactAction("webcam", redactWebcam); err != nil {
+		return nil, err
+	}
+	if r.Avatar, err = parseRedactAction("avatar", redactAvatar); err != nil {
+		return nil, err
+	}
+	if r.Audio, err = parseRedactAction("audio", redactAudio); err != nil {
+		return nil, err
+	}
+	for _, p := range redactParticipants {
+		ident, err := strconv.ParseUint(p, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid participant %q: %w", p, err)
+		}
+		r.Participants = append(r.Participants, uint32(ident))
+	}
+	for _, t := range redactKeepCmdTypes {
+		typ, err := strconv.ParseInt(t, 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid command type %q: %w", t, err)
+		}
+		r.KeepCmdTypes = append(r.KeepCmdTypes, int32(typ))
+	}
+	return r, r.Validate()
+}
+
+func runRedact(path string) error {
+	r, err := redactionFromFlags()
+	if err != nil {
+		return err
+	}
+
+	report := zoom.NewRedactionReport()
+	err = recordingCopy{
+		samples: func(f *zoom.File, w *zoom.Writer, filename string) error {
+			if err := r.RedactSamples(f, w, report); err != nil {
+				return err
+			}
+			logger.Infof("Written %s", filename)
+			return nil
+		},
+		commands: func(cmds []*zoom.CmdPacket, filename string) []*zoom.CmdPacket {
+			r.RedactCommands(cmds, report)
+			logger.Infof("Written %s", filename)
+			return cmds
+		},
+	}.run(path, redactOutput)
+	if err != nil {
+		return err
+	}
+	unknown := []string{}
+	for typ := range report.UnknownCmdTypes {
+		unknown = append(unknown, typ)
+	}
+	sort.Strings(unknown)
+	for _, typ := range unknown {
+		logger.Warnf("Found %d commands of unknown type %s, pass --cmd-types to decode them", report.UnknownCmdTypes[typ], typ)
+	}
+	for _, w := range report.Warnings {
+		logger.Warn(w)
+	}
+
+	reportFile := redactReport
+	if reportFile == "" {
+		reportFile = filepath.Join(redactOutput, "redaction.json")
+	}
+	out, err := openOutput(reportFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("Failed to write report: %w", err)
+	}
+	logger.Infof("Written redaction report to %s", reportFile)
+	return nil
+}
+
+// redactCmd represents the redact command
+var redactCmd = &cobra.Command{
+	Use:   "redact <file or recording>",
+	Short: "Removes personal data from a zoom file or recording",
+	Long: `Writes a copy of a recording without personal data to the output folder, along with a report of what was removed.
+
+Webcam video, avatars and audio can each be kept, dropped or replaced (avatars with a placeholder image,
+Opus audio with silence of the same length), either for everyone or only for the --participant idents given.
+Display names are replaced with pseudonyms and chat messages with "[redacted]" unless disabled. The text of all other
+commands is replaced with "[redacted]" as well, unless their type is given with --keep-cmd-types. Join and chat commands
+are only recognized with --cmd-types, otherwise their text is redacted like that of any other command.
+
+Audio is attributed to participants by a property field that is not verified yet, check the result when
+redacting the audio of only some participants.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRedact(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(redactCmd)
+
+	redactCmd.Flags().StringVar(&redactWebcam, "webcam", redactWebcam, "What to do with webcam video: keep, drop")
+	redactCmd.Flags().StringVar(&redactAvatar, "avatar", redactAvatar, "What to do with avatars: keep, drop, replace")
+	redactCmd.Flags().StringVar(&redactAudio, "audio", redactAudio, "What to do with audio: keep, drop, replace")
+	redactCmd.Flags().StringSliceVarP(&redactParticipants, "participant", "p", redactParticipants, "Only redact the media of these participants, e.g. 0x1000")
+	redactCmd.Flags().BoolVar(&redactNames, "names", redactNames, "Replace display names with pseudonyms")
+	redactCmd.Flags().BoolVar(&redactChat, "chat", redactChat, "Replace the text of chat messages")
+	redactCmd.Flags().StringSliceVar(&redactKeepCmdTypes, "keep-cmd-types", redactKeepCmdTypes, "Command types known to carry no personal data, whose text is kept, e.g. 0x14")
+	redactCmd.Flags().StringVarP(&redactOutput, "out", "o", redactOutput, "Folder to write the redacted files to")
+	redactCmd.Flags().StringVar(&redactReport, "report", redactReport, "Filename of the report, defaults to redaction.json in the output folder, - for stdout")
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/galli-leo/gozoom/zoom"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -151,6 +152,11 @@ import (
 
 var logger *zap.SugaredLogger
 var level string = zapcore.DebugLevel.String()
+
+// cmdTypes decodes command packets, parsed from cmdTypesSpec.
+// No values of CmdHeader.Type are confirmed yet, so by default every command stays a raw event.
+var cmdTypes zoom.CmdTypeTable
+var cmdTypesSpec string
 var levels = func() []string {
 	ret := []string{}
 
@@ -181,7 +187,11 @@ to quickly create a Cobra application.`,
 			return err
 		}
 		log, err := config.Build()
+		if err != nil {
+			return err
+		}
 		logger = log.Sugar()
+		cmdTypes, err = zoom.ParseCmdTypeTable(cmdTypesSpec)
 		return err
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
@@ -210,4 +220,5 @@ func init() {
 	flags := rootCmd.PersistentFlags()
 	validLevels := strings.Join(levels, ", ")
 	flags.StringVarP(&level, "level", "l", level, fmt.Sprintf("Specifies the log level. If debug is selected, the logs will also include more information such as stack traces. Possible values: %s", validLevels))
+	flags.StringVar(&cmdTypesSpec, "cmd-types", "", "Command packet types to decode as events, e.g. ParticipantJoined=1,ChatMessage=3. Types not listed are shown as raw commands.")
 }
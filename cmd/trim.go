@@ -0,0 +1,180 @@
+/*
+Copyright © 2020 Leonardo Galli
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/galli-leo/gozoom/zoom"
+	"github.com/spf13/cobra"
+)
+
+var trimFrom int64 = 0
+var trimTo int64 = 0
+var trimOutput string = "trimmed"
+
+// recordingFilenames returns the .zoom files of a recording folder, or just path if it is a file.
+func recordingFilenames(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	filenames, err := filepath.Glob(filepath.Join(path, "*.zoom"))
+	sort.Strings(filenames)
+	return filenames, err
+}
+
+// recordingCopy writes a transformed copy of every file of a recording to another folder.
+type recordingCopy struct {
+	// samples copies the sample file f to w.
+	samples func(f *zoom.File, w *zoom.Writer, filename string) error
+	// commands returns the command packets to write in place of cmds.
+	commands func(cmds []*zoom.CmdPacket, filename string) []*zoom.CmdPacket
+}
+
+// run copies the .zoom files of path, a recording folder or a single file, to files with the same names in outDir.
+func (c recordingCopy) run(path, outDir string) error {
+	filenames, err := recordingFilenames(path)
+	if err != nil {
+		return err
+	}
+	if len(filenames) == 0 {
+		return fmt.Errorf("No .zoom files found in %s", path)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	for _, filename := range filenames {
+		out := filepath.Join(outDir, filepath.Base(filename))
+		absIn, _ := filepath.Abs(filename)
+		absOut, _ := filepath.Abs(out)
+		if absIn == absOut {
+			return fmt.Errorf("Refusing to overwrite %s", filename)
+		}
+
+		f, err := zoom.OpenFile(filename, logger)
+		if err != nil {
+			return err
+		}
+		kind, err := zoom.DetectFileKind(f)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("Failed to detect kind of %s: %w", filename, err)
+		case kind == zoom.SampleFile:
+			err = c.copySamples(f, out)
+		case kind == zoom.CmdFile:
+			err = c.copyCommands(f, out)
+		default:
+			logger.Warnf("Skipping %s of unknown kind", filename)
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c recordingCopy) copySamples(f *zoom.File, filename string) error {
+	w, err := zoom.CreateFile(filename, logger)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return c.samples(f, w, filename)
+}
+
+func (c recordingCopy) copyCommands(f *zoom.File, filename string) error {
+	cmds := []*zoom.CmdPacket{}
+	for f.HasData() {
+		p := zoom.NewCmdPacket()
+		if err := f.ReadPacket(p); err != nil {
+			return fmt.Errorf("Failed to read command packet: %w", err)
+		}
+		cmds = append(cmds, p)
+	}
+	cmds = c.commands(cmds, filename)
+
+	w, err := zoom.CreateFile(filename, logger)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	for _, p := range cmds {
+		if err := w.WritePacket(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runTrim(path string) error {
+	r := zoom.TimeRange{Start: trimFrom, End: trimTo}
+	if r.End != 0 && r.End <= r.Start {
+		return fmt.Errorf("Invalid range %s", r)
+	}
+
+	logger.Infof("Trimming %s to %s, writing to %s", path, r, trimOutput)
+	return recordingCopy{
+		samples: func(f *zoom.File, w *zoom.Writer, filename string) error {
+			stats, err := zoom.TrimSamples(f, w, r)
+			if err != nil {
+				return err
+			}
+			logger.Infof("Written %d packets to %s, %d screen share packets before the start to decode from the preceding keyframe", stats.Packets, filename, stats.Leading)
+			if stats.Dropped > 0 {
+				logger.Infof("Dropped %d webcam packets before the first keyframe of their participant", stats.Dropped)
+			}
+			return nil
+		},
+		commands: func(cmds []*zoom.CmdPacket, filename string) []*zoom.CmdPacket {
+			trimmed := zoom.TrimCommands(cmds, r, cmdTypes)
+			logger.Infof("Kept %d of %d commands in %s", len(trimmed), len(cmds), filename)
+			return trimmed
+		},
+	}.run(path, trimOutput)
+}
+
+// trimCmd represents the trim command
+var trimCmd = &cobra.Command{
+	Use:   "trim <file or recording>",
+	Short: "Writes a shorter copy of a zoom file or recording",
+	Long: `Copies the packets with a TimingA between --from (included) and --to (excluded) to new .zoom files
+with the same names in the output folder. Use probe to find the timings of a recording.
+
+The screen share starts at the keyframe preceding --from, with the parameter sets prepended if needed,
+webcams start at their first keyframe in the range. The command file keeps the events in the range,
+as well as the join events of everyone present at the start.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTrim(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trimCmd)
+
+	trimCmd.Flags().Int64VarP(&trimFrom, "from", "f", trimFrom, "First TimingA to keep")
+	trimCmd.Flags().Int64VarP(&trimTo, "to", "t", trimTo, "TimingA to stop at, 0 for the end of the recording")
+	trimCmd.Flags().StringVarP(&trimOutput, "out", "o", trimOutput, "Folder to write the trimmed files to")
+}
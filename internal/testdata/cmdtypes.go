@@ -0,0 +1,5 @@
+package testdata
+
+// CmdTypes is an example command type table in the format of the --cmd-types flag. The type values are made up, the
+// real values have not been confirmed against captured recordings.
+const CmdTypes = "ParticipantJoined=0x11,ParticipantLeft=0x12,ChatMessage=0x13,ShareStarted=0x14,ShareStopped=0x15,ActiveSpeaker=0x16"
@@ -0,0 +1,8 @@
+// Package testdata holds fixtures shared by the tests of several packages.
+package testdata
+
+// SPS is a 1280x720 High profile SPS with VUI.
+var SPS = []byte{0x67, 0x64, 0x00, 0x1f, 0xac, 0xd9, 0x40, 0x50, 0x05, 0xbb, 0x01, 0x10, 0x00, 0x00, 0x03, 0x00, 0x10, 0x00, 0x00, 0x03, 0x03, 0xc0, 0xf1, 0x83, 0x19, 0x60}
+
+// PPS is the CABAC PPS matching SPS.
+var PPS = []byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}
@@ -0,0 +1,148 @@
+package mkv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// EBML element ids used by the muxer.
+const (
+	idEBML               = 0x1A45DFA3
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42F7
+	idEBMLMaxIDLength    = 0x42F2
+	idEBMLMaxSizeLength  = 0x42F3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+
+	idVoid = 0xEC
+
+	idSegment      = 0x18538067
+	idSeekHead     = 0x114D9B74
+	idSeek         = 0x4DBB
+	idSeekID       = 0x53AB
+	idSeekPosition = 0x53AC
+
+	idInfo           = 0x1549A966
+	idTimestampScale = 0x2AD7B1
+	idMuxingApp      = 0x4D80
+	idWritingApp     = 0x5741
+	idDuration       = 0x4489
+
+	idTracks            = 0x1654AE6B
+	idTrackEntry        = 0xAE
+	idTrackNumber       = 0xD7
+	idTrackUID          = 0x73C5
+	idTrackType         = 0x83
+	idFlagLacing        = 0x9C
+	idName              = 0x536E
+	idLanguage          = 0x22B59C
+	idCodecID           = 0x86
+	idCodecPrivate      = 0x63A2
+	idCodecDelay        = 0x56AA
+	idSeekPreRoll       = 0x56BB
+	idVideo             = 0xE0
+	idPixelWidth        = 0xB0
+	idPixelHeight       = 0xBA
+	idAudio             = 0xE1
+	idSamplingFrequency = 0xB5
+	idChannels          = 0x9F
+
+	idCluster     = 0x1F43B675
+	idTimestamp   = 0xE7
+	idSimpleBlock = 0xA3
+
+	idCues               = 0x1C53BB6B
+	idCuePoint           = 0xBB
+	idCueTime            = 0xB3
+	idCueTrackPositions  = 0xB7
+	idCueTrack           = 0xF7
+	idCueClusterPosition = 0xF1
+)
+
+// unknownSize marks a master element whose size is not known when it is started.
+const unknownSize = 0x01FFFFFFFFFFFFFF
+
+// buffer builds EBML elements in memory.
+type buffer struct {
+	bytes.Buffer
+}
+
+// id writes an element id, which already contains its length marker.
+func (b *buffer) id(id uint32) {
+	switch {
+	case id > 0xFFFFFF:
+		b.WriteByte(byte(id >> 24))
+		fallthrough
+	case id > 0xFFFF:
+		b.WriteByte(byte(id >> 16))
+		fallthrough
+	case id > 0xFF:
+		b.WriteByte(byte(id >> 8))
+	}
+	b.WriteByte(byte(id))
+}
+
+// size writes v as a variable length integer of the shortest possible length.
+func (b *buffer) size(v uint64) {
+	n := 1
+	for n < 8 && v >= 1<<(7*uint(n))-1 {
+		n++
+	}
+	b.sizeN(v, n)
+}
+
+// sizeN writes v as a variable length integer of n bytes.
+func (b *buffer) sizeN(v uint64, n int) {
+	v |= 1 << (7 * uint(n))
+	for i := n - 1; i >= 0; i-- {
+		b.WriteByte(byte(v >> (8 * uint(i))))
+	}
+}
+
+func (b *buffer) bytesElement(id uint32, data []byte) {
+	b.id(id)
+	b.size(uint64(len(data)))
+	b.Write(data)
+}
+
+func (b *buffer) uintElement(id uint32, v uint64) {
+	n := 1
+	for n < 8 && v >= 1<<(8*uint(n)) {
+		n++
+	}
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	b.bytesElement(id, tmp[8-n:])
+}
+
+func (b *buffer) floatElement(id uint32, v float64) {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], math.Float64bits(v))
+	b.bytesElement(id, tmp[:])
+}
+
+func (b *buffer) stringElement(id uint32, v string) {
+	b.bytesElement(id, []byte(v))
+}
+
+// master writes an element containing the elements written to m by body.
+func (b *buffer) master(id uint32, body func(m *buffer)) {
+	m := &buffer{}
+	body(m)
+	b.bytesElement(id, m.Bytes())
+}
+
+// void writes a Void element taking up exactly n bytes, n has to be at least 2.
+func (b *buffer) void(n int) {
+	b.id(idVoid)
+	if n-2 < 0x7f {
+		b.sizeN(uint64(n-2), 1)
+		b.Write(make([]byte, n-2))
+		return
+	}
+	b.sizeN(uint64(n-9), 8)
+	b.Write(make([]byte, n-9))
+}
@@ -0,0 +1,387 @@
+// Package mkv writes Matroska files from H.264 and Opus samples.
+package mkv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/galli-leo/gozoom/mp4"
+	"github.com/galli-leo/gozoom/parser"
+	"go.uber.org/zap"
+)
+
+// Codec of a track.
+type Codec int
+
+const (
+	CodecH264 Codec = iota
+	CodecOpus
+)
+
+const (
+	// DefaultClusterDuration is the minimum length of a cluster, in seconds.
+	DefaultClusterDuration = 2
+
+	// space reserved at the start of the segment for the seek head
+	seekHeadSize = 96
+	// space reserved in the segment info for the duration
+	durationSize = 11
+	// in nanoseconds, as recommended for Opus
+	opusSeekPreRoll = 80000000
+)
+
+// Sample is a single frame of a track.
+type Sample struct {
+	// Time is the presentation time, in units of the muxer timescale.
+	Time int64
+	// Data is an Annex B access unit for H.264 tracks or a single packet for audio tracks.
+	Data     []byte
+	Keyframe bool
+}
+
+// Track is a track of a Muxer, created by one of the Add*Track methods.
+type Track struct {
+	Number uint64
+	Name   string
+	Codec  Codec
+
+	// H.264
+	sps, pps      []byte
+	spsInfo       *parser.SPSInfo
+	width, height uint
+	// Opus
+	channels   int
+	sampleRate int
+}
+
+type seekEntry struct {
+	id  uint32
+	pos int64
+}
+
+type cuePoint struct {
+	time     int64
+	track    uint64
+	position int64
+}
+
+// Muxer writes a Matroska file.
+// All tracks have to be added before the first sample is written.
+// If the underlying writer is an io.WriteSeeker, the duration, segment size and seek head are filled in by Close.
+type Muxer struct {
+	w         io.Writer
+	timescale int64
+	tracks    []*Track
+	hasVideo  bool
+	started   bool
+
+	// offset is the number of bytes written so far.
+	offset int64
+	// segmentSizePos and segmentStart are the offsets of the segment size and the segment data.
+	segmentSizePos int64
+	segmentStart   int64
+	seekHeadPos    int64
+	durationPos    int64
+	// positions of the top level elements relative to segmentStart
+	infoPos   int64
+	tracksPos int64
+	cuesPos   int64
+
+	cluster     *buffer
+	clusterTime int64
+	cues        []cuePoint
+	end         int64
+
+	// ClusterDuration is the minimum length of a cluster, in units of the muxer timescale.
+	ClusterDuration int64
+
+	log *zap.SugaredLogger
+}
+
+// NewMuxer returns a muxer writing to w. Sample times are given in ticks of timescale per second.
+func NewMuxer(w io.Writer, timescale int64, log *zap.SugaredLogger) *Muxer {
+	return &Muxer{
+		w:               w,
+		timescale:       timescale,
+		ClusterDuration: DefaultClusterDuration * timescale,
+		log:             log.Named("mkv"),
+	}
+}
+
+func (m *Muxer) addTrack(t *Track) (*Track, error) {
+	if m.started {
+		return nil, fmt.Errorf("Cannot add track %q after writing samples", t.Name)
+	}
+	t.Number = uint64(len(m.tracks) + 1)
+	m.tracks = append(m.tracks, t)
+	return t, nil
+}
+
+// AddH264Track adds a video track, with the codec private data built from the given SPS and PPS.
+func (m *Muxer) AddH264Track(name string, sps, pps []byte) (*Track, error) {
+	p, err := parser.ParseParameterSets([][]byte{sps, pps}, m.log)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse parameter sets of track %q: %w", name, err)
+	}
+	m.hasVideo = true
+	return m.addTrack(&Track{
+		Name:    name,
+		Codec:   CodecH264,
+		sps:     sps,
+		pps:     pps,
+		spsInfo: p.SPS,
+		width:   p.SPS.FrameWidth(),
+		height:  p.SPS.FrameHeight(),
+	})
+}
+
+// AddOpusTrack adds an audio track containing Opus packets.
+func (m *Muxer) AddOpusTrack(name string, sampleRate, channels int) (*Track, error) {
+	return m.addTrack(&Track{
+		Name:       name,
+		Codec:      CodecOpus,
+		channels:   channels,
+		sampleRate: sampleRate,
+	})
+}
+
+// Tracks returns the tracks of the muxer.
+func (m *Muxer) Tracks() []*Track {
+	return m.tracks
+}
+
+func (m *Muxer) write(b []byte) error {
+	n, err := m.w.Write(b)
+	m.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("Failed to write %d bytes: %w", len(b), err)
+	}
+	return nil
+}
+
+// WriteSample adds a sample to the track.
+// A new cluster is started at video keyframes once the current one is long enough,
+// so samples of all tracks should be written in roughly increasing time order.
+func (m *Muxer) WriteSample(t *Track, s Sample) error {
+	if !m.started {
+		if err := m.writeHeader(); err != nil {
+			return err
+		}
+		m.started = true
+	}
+
+	keyframe := s.Keyframe || t.Codec != CodecH264
+	// only video keyframes start clusters, unless there is no video at all
+	clusterStart := keyframe && (t.Codec == CodecH264 || !m.hasVideo)
+	rel := s.Time - m.clusterTime
+	if m.cluster == nil || rel > math.MaxInt16 || rel < math.MinInt16 || (clusterStart && rel >= m.ClusterDuration) {
+		if err := m.flushCluster(); err != nil {
+			return err
+		}
+		m.cluster = &buffer{}
+		m.clusterTime = s.Time
+		m.cluster.uintElement(idTimestamp, uint64(s.Time))
+		if clusterStart {
+			m.cues = append(m.cues, cuePoint{s.Time, t.Number, m.offset - m.segmentStart})
+		}
+		rel = 0
+	}
+
+	data := s.Data
+	if t.Codec == CodecH264 {
+		data = mp4.ToAVCC(data, t.sps, t.pps)
+	}
+	block := &buffer{}
+	block.size(t.Number)
+	var tmp [2]byte
+	binary.BigEndian.PutUint16(tmp[:], uint16(int16(rel)))
+	block.Write(tmp[:])
+	if keyframe {
+		block.WriteByte(0x80)
+	} else {
+		block.WriteByte(0)
+	}
+	block.Write(data)
+	m.cluster.bytesElement(idSimpleBlock, block.Bytes())
+
+	if s.Time > m.end {
+		m.end = s.Time
+	}
+	return nil
+}
+
+func (m *Muxer) flushCluster() error {
+	if m.cluster == nil {
+		return nil
+	}
+	b := &buffer{}
+	b.bytesElement(idCluster, m.cluster.Bytes())
+	m.cluster = nil
+	return m.write(b.Bytes())
+}
+
+func (m *Muxer) writeHeader() error {
+	if len(m.tracks) == 0 {
+		return fmt.Errorf("No tracks added")
+	}
+	b := &buffer{}
+	b.master(idEBML, func(e *buffer) {
+		e.uintElement(idEBMLVersion, 1)
+		e.uintElement(idEBMLReadVersion, 1)
+		e.uintElement(idEBMLMaxIDLength, 4)
+		e.uintElement(idEBMLMaxSizeLength, 8)
+		e.stringElement(idDocType, "matroska")
+		e.uintElement(idDocTypeVersion, 4)
+		e.uintElement(idDocTypeReadVersion, 2)
+	})
+	b.id(idSegment)
+	m.segmentSizePos = int64(b.Len())
+	b.sizeN(unknownSize, 8)
+	m.segmentStart = int64(b.Len())
+
+	m.seekHeadPos = int64(b.Len())
+	b.void(seekHeadSize)
+
+	m.infoPos = int64(b.Len()) - m.segmentStart
+	b.master(idInfo, func(i *buffer) {
+		i.uintElement(idTimestampScale, uint64(1000000000/m.timescale))
+		i.stringElement(idMuxingApp, "gozoom")
+		i.stringElement(idWritingApp, "gozoom")
+		// filled in by Close, if possible
+		i.void(durationSize)
+	})
+	m.durationPos = int64(b.Len()) - durationSize
+
+	m.tracksPos = int64(b.Len()) - m.segmentStart
+	b.master(idTracks, func(ts *buffer) {
+		for _, t := range m.tracks {
+			ts.master(idTrackEntry, t.writeEntry)
+		}
+	})
+	return m.write(b.Bytes())
+}
+
+func (t *Track) writeEntry(b *buffer) {
+	b.uintElement(idTrackNumber, t.Number)
+	b.uintElement(idTrackUID, t.Number)
+	b.uintElement(idFlagLacing, 0)
+	b.stringElement(idName, t.Name)
+	b.stringElement(idLanguage, "und")
+	switch t.Codec {
+	case CodecH264:
+		b.uintElement(idTrackType, 1)
+		b.stringElement(idCodecID, "V_MPEG4/ISO/AVC")
+		b.bytesElement(idCodecPrivate, mp4.AVCDecoderConfig(t.spsInfo, t.sps, t.pps))
+		b.master(idVideo, func(v *buffer) {
+			v.uintElement(idPixelWidth, uint64(t.width))
+			v.uintElement(idPixelHeight, uint64(t.height))
+		})
+	case CodecOpus:
+		b.uintElement(idTrackType, 2)
+		b.stringElement(idCodecID, "A_OPUS")
+		b.bytesElement(idCodecPrivate, t.opusHead())
+		b.uintElement(idCodecDelay, 0)
+		b.uintElement(idSeekPreRoll, opusSeekPreRoll)
+		b.master(idAudio, func(a *buffer) {
+			a.floatElement(idSamplingFrequency, float64(t.sampleRate))
+			a.uintElement(idChannels, uint64(t.channels))
+		})
+	}
+}
+
+// opusHead returns the identification header of the Opus stream, as described in RFC 7845.
+func (t *Track) opusHead() []byte {
+	b := &buffer{}
+	b.WriteString("OpusHead")
+	b.WriteByte(1)
+	b.WriteByte(byte(t.channels))
+	var tmp [4]byte
+	binary.LittleEndian.PutUint16(tmp[:], 0) // pre skip
+	b.Write(tmp[:2])
+	binary.LittleEndian.PutUint32(tmp[:], uint32(t.sampleRate))
+	b.Write(tmp[:])
+	b.Write([]byte{0, 0}) // output gain
+	b.WriteByte(0)        // channel mapping family
+	return b.Bytes()
+}
+
+// Close writes the remaining samples and the cues.
+// It does not close the underlying writer.
+func (m *Muxer) Close() error {
+	if !m.started {
+		if err := m.writeHeader(); err != nil {
+			return err
+		}
+		m.started = true
+	}
+	if err := m.flushCluster(); err != nil {
+		return err
+	}
+
+	if len(m.cues) > 0 {
+		m.cuesPos = m.offset - m.segmentStart
+		b := &buffer{}
+		b.master(idCues, func(c *buffer) {
+			for _, cue := range m.cues {
+				c.master(idCuePoint, func(p *buffer) {
+					p.uintElement(idCueTime, uint64(cue.time))
+					p.master(idCueTrackPositions, func(tp *buffer) {
+						tp.uintElement(idCueTrack, cue.track)
+						tp.uintElement(idCueClusterPosition, uint64(cue.position))
+					})
+				})
+			}
+		})
+		if err := m.write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	ws, ok := m.w.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+	return m.finalize(ws)
+}
+
+// finalize fills in the parts of the header that are only known at the end.
+func (m *Muxer) finalize(ws io.WriteSeeker) error {
+	size := &buffer{}
+	size.sizeN(uint64(m.offset-m.segmentStart), 8)
+
+	duration := &buffer{}
+	duration.floatElement(idDuration, float64(m.end))
+
+	seekHead := &buffer{}
+	seekHead.master(idSeekHead, func(s *buffer) {
+		seeks := []seekEntry{{idInfo, m.infoPos}, {idTracks, m.tracksPos}}
+		if m.cuesPos > 0 {
+			seeks = append(seeks, seekEntry{idCues, m.cuesPos})
+		}
+		for _, seek := range seeks {
+			s.master(idSeek, func(e *buffer) {
+				id := &buffer{}
+				id.id(seek.id)
+				e.bytesElement(idSeekID, id.Bytes())
+				e.uintElement(idSeekPosition, uint64(seek.pos))
+			})
+		}
+	})
+	seekHead.void(seekHeadSize - seekHead.Len())
+
+	for _, patch := range []struct {
+		pos  int64
+		data []byte
+	}{{m.segmentSizePos, size.Bytes()}, {m.durationPos, duration.Bytes()}, {m.seekHeadPos, seekHead.Bytes()}} {
+		if _, err := ws.Seek(patch.pos, io.SeekStart); err != nil {
+			return fmt.Errorf("Failed to seek to 0x%x: %w", patch.pos, err)
+		}
+		if _, err := ws.Write(patch.data); err != nil {
+			return fmt.Errorf("Failed to write header at 0x%x: %w", patch.pos, err)
+		}
+	}
+	_, err := ws.Seek(m.offset, io.SeekStart)
+	return err
+}
@@ -0,0 +1,124 @@
+package mkv
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/galli-leo/gozoom/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testElement struct {
+	id       uint32
+	data     []byte
+	children []testElement
+}
+
+var testMasters = map[uint32]bool{
+	idEBML: true, idSegment: true, idSeekHead: true, idSeek: true, idInfo: true, idTracks: true, idTrackEntry: true,
+	idVideo: true, idAudio: true, idCluster: true, idCues: true, idCuePoint: true, idCueTrackPositions: true,
+}
+
+func readVint(b []byte, keepMarker bool) (uint64, int) {
+	n := 1
+	for b[0]&(0x80>>uint(n-1)) == 0 {
+		n++
+	}
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= 0xff >> uint(n)
+	}
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n
+}
+
+func parseElements(t *testing.T, b []byte) []testElement {
+	ret := []testElement{}
+	for len(b) > 0 {
+		id, n := readVint(b, true)
+		size, m := readVint(b[n:], false)
+		b = b[n+m:]
+		if size == unknownSize&^(1<<56) {
+			size = uint64(len(b))
+		}
+		require.True(t, size <= uint64(len(b)), "element 0x%x too large", id)
+		e := testElement{id: uint32(id), data: b[:size]}
+		if testMasters[e.id] {
+			e.children = parseElements(t, e.data)
+		}
+		ret = append(ret, e)
+		b = b[size:]
+	}
+	return ret
+}
+
+func find(elements []testElement, ids ...uint32) []testElement {
+	ret := []testElement{}
+	for _, e := range elements {
+		if e.id == ids[0] {
+			if len(ids) == 1 {
+				ret = append(ret, e)
+			} else {
+				ret = append(ret, find(e.children, ids[1:]...)...)
+			}
+		}
+	}
+	return ret
+}
+
+func TestMuxer(t *testing.T) {
+	f, err := ioutil.TempFile("", "gozoom-*.mkv")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	m := NewMuxer(f, 1000, zap.NewNop().Sugar())
+	m.ClusterDuration = 100
+	video, err := m.AddH264Track("Screen share", testdata.SPS, testdata.PPS)
+	require.NoError(t, err)
+	audio, err := m.AddOpusTrack("Audio", 48000, 1)
+	require.NoError(t, err)
+
+	idr := append([]byte{0, 0, 0, 1}, testdata.SPS...)
+	idr = append(idr, 0, 0, 0, 1, 0x65, 0x88)
+	for i := int64(0); i < 6; i++ {
+		data := []byte{0, 0, 0, 1, 0x41, 0x9a}
+		if i%3 == 0 {
+			data = idr
+		}
+		require.NoError(t, m.WriteSample(video, Sample{Time: 10 + i*40, Data: data, Keyframe: i%3 == 0}))
+		require.NoError(t, m.WriteSample(audio, Sample{Time: 10 + i*40, Data: []byte{0xfc, byte(i)}}))
+	}
+	require.NoError(t, m.Close())
+
+	b, err := ioutil.ReadFile(f.Name())
+	require.NoError(t, err)
+	elements := parseElements(t, b)
+
+	names := find(elements, idSegment, idTracks, idTrackEntry, idName)
+	require.Len(t, names, 2)
+	assert.Equal(t, "Screen share", string(names[0].data))
+	assert.Equal(t, "Audio", string(names[1].data))
+	assert.Equal(t, "A_OPUS", string(find(elements, idSegment, idTracks, idTrackEntry, idCodecID)[1].data))
+
+	// a new cluster starts at the second keyframe
+	clusters := find(elements, idSegment, idCluster)
+	require.Len(t, clusters, 2)
+	assert.Equal(t, []byte{10}, find(clusters[0].children, idTimestamp)[0].data)
+	assert.Equal(t, []byte{130}, find(clusters[1].children, idTimestamp)[0].data)
+	blocks := find(clusters[0].children, idSimpleBlock)
+	require.Len(t, blocks, 6)
+	// track 1, relative time 0, keyframe, the SPS is only in the codec private data
+	assert.Equal(t, []byte{0x81, 0, 0, 0x80, 0, 0, 0, 2, 0x65, 0x88}, blocks[0].data)
+	assert.Equal(t, []byte{0x82, 0, 80, 0x80, 0xfc, 2}, blocks[5].data)
+
+	// filled in after the fact, as the file is seekable
+	assert.Len(t, find(elements, idSegment, idInfo, idDuration), 1)
+	assert.Len(t, find(elements, idSegment, idSeekHead, idSeek), 3)
+	assert.Len(t, find(elements, idSegment, idCues, idCuePoint), 2)
+}
@@ -0,0 +1,62 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// buffer builds ISO base media file format boxes in memory.
+type buffer struct {
+	bytes.Buffer
+}
+
+func (b *buffer) u8(v uint8) {
+	b.WriteByte(v)
+}
+
+func (b *buffer) u16(v uint16) {
+	var tmp [2]byte
+	binary.BigEndian.PutUint16(tmp[:], v)
+	b.Write(tmp[:])
+}
+
+func (b *buffer) u32(v uint32) {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	b.Write(tmp[:])
+}
+
+func (b *buffer) u64(v uint64) {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	b.Write(tmp[:])
+}
+
+func (b *buffer) zeros(n int) {
+	b.Write(make([]byte, n))
+}
+
+// box writes a box of the given type, with the contents written by body.
+// The size is patched in after body returns.
+func (b *buffer) box(typ string, body func()) {
+	start := b.Len()
+	b.u32(0)
+	b.WriteString(typ)
+	body()
+	binary.BigEndian.PutUint32(b.Bytes()[start:], uint32(b.Len()-start))
+}
+
+// fullBox writes a box with the version and flags header.
+func (b *buffer) fullBox(typ string, version uint8, flags uint32, body func()) {
+	b.box(typ, func() {
+		b.u32(uint32(version)<<24 | flags&0xffffff)
+		body()
+	})
+}
+
+// matrix writes the identity transformation matrix used by mvhd and tkhd.
+func (b *buffer) matrix() {
+	for _, v := range []uint32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000} {
+		b.u32(v)
+	}
+}
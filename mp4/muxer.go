@@ -0,0 +1,500 @@
+// Package mp4 writes fragmented MP4 files from H.264 and Opus samples.
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/galli-leo/gozoom/parser"
+	"github.com/nareix/joy5/codec/h264"
+	"go.uber.org/zap"
+)
+
+// Codec of a track.
+type Codec int
+
+const (
+	CodecH264 Codec = iota
+	CodecOpus
+)
+
+const (
+	videoTimescale = 90000
+	opusTimescale  = 48000
+
+	// DefaultFragmentDuration is the minimum length of a fragment, in seconds.
+	DefaultFragmentDuration = 2
+
+	sampleFlagsSync    = 0x02000000
+	sampleFlagsNonSync = 0x01010000
+)
+
+// Sample is a single frame of a track.
+type Sample struct {
+	// Time is the decode time, in units of the muxer timescale.
+	Time int64
+	// CompositionOffset is added to Time to get the presentation time.
+	CompositionOffset int64
+	// Data is an Annex B access unit for H.264 tracks or a single packet for audio tracks.
+	Data     []byte
+	Keyframe bool
+}
+
+type pendingSample struct {
+	time     int64
+	cts      int64
+	data     []byte
+	keyframe bool
+}
+
+// Track is a track of a Muxer, created by one of the Add*Track methods.
+type Track struct {
+	ID    uint32
+	Name  string
+	Codec Codec
+
+	timescale uint32
+
+	// H.264
+	sps, pps      []byte
+	spsInfo       *parser.SPSInfo
+	width, height uint
+	// Opus
+	channels   int
+	sampleRate int
+
+	pending      []pendingSample
+	lastTime     int64
+	lastDuration int64
+	started      bool
+}
+
+// Muxer writes a fragmented MP4 file.
+// All tracks have to be added before the first sample is written.
+type Muxer struct {
+	w         io.Writer
+	timescale int64
+	tracks    []*Track
+	started   bool
+	sequence  uint32
+	// FragmentDuration is the minimum length of a fragment, in units of the muxer timescale.
+	FragmentDuration int64
+
+	log *zap.SugaredLogger
+}
+
+// NewMuxer returns a muxer writing to w. Sample times are given in ticks of timescale per second.
+func NewMuxer(w io.Writer, timescale int64, log *zap.SugaredLogger) *Muxer {
+	return &Muxer{
+		w:                w,
+		timescale:        timescale,
+		FragmentDuration: DefaultFragmentDuration * timescale,
+		log:              log.Named("mp4"),
+	}
+}
+
+func (m *Muxer) addTrack(t *Track) (*Track, error) {
+	if m.started {
+		return nil, fmt.Errorf("Cannot add track %q after writing samples", t.Name)
+	}
+	t.ID = uint32(len(m.tracks) + 1)
+	m.tracks = append(m.tracks, t)
+	return t, nil
+}
+
+// AddH264Track adds a video track, with the avcC configuration built from the given SPS and PPS.
+func (m *Muxer) AddH264Track(name string, sps, pps []byte) (*Track, error) {
+	p, err := parser.ParseParameterSets([][]byte{sps, pps}, m.log)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse parameter sets of track %q: %w", name, err)
+	}
+	return m.addTrack(&Track{
+		Name:      name,
+		Codec:     CodecH264,
+		timescale: videoTimescale,
+		sps:       sps,
+		pps:       pps,
+		spsInfo:   p.SPS,
+		width:     p.SPS.FrameWidth(),
+		height:    p.SPS.FrameHeight(),
+	})
+}
+
+// AddOpusTrack adds an audio track containing Opus packets.
+func (m *Muxer) AddOpusTrack(name string, sampleRate, channels int) (*Track, error) {
+	return m.addTrack(&Track{
+		Name:       name,
+		Codec:      CodecOpus,
+		timescale:  opusTimescale,
+		channels:   channels,
+		sampleRate: sampleRate,
+	})
+}
+
+// Tracks returns the tracks of the muxer.
+func (m *Muxer) Tracks() []*Track {
+	return m.tracks
+}
+
+func (m *Muxer) write(b []byte) error {
+	if _, err := m.w.Write(b); err != nil {
+		return fmt.Errorf("Failed to write %d bytes: %w", len(b), err)
+	}
+	return nil
+}
+
+// toTrack converts a time from the muxer timescale to the timescale of t.
+func (m *Muxer) toTrack(t *Track, time int64) int64 {
+	return time * int64(t.timescale) / m.timescale
+}
+
+// WriteSample adds a sample to the track.
+// Samples of a track have to be written in decode order, times going backwards are clamped to the previous time.
+// A sample lasts until the next one of the same track starts, so pauses become longer samples.
+func (m *Muxer) WriteSample(t *Track, s Sample) error {
+	if !m.started {
+		if err := m.writeHeader(); err != nil {
+			return err
+		}
+		m.started = true
+	}
+
+	time := m.toTrack(t, s.Time)
+	if t.started && time < t.lastTime {
+		m.log.Debugw("Sample time goes backwards, clamping", "track", t.Name, "time", time, "last", t.lastTime)
+		time = t.lastTime
+	}
+
+	keyframe := s.Keyframe || t.Codec != CodecH264
+	if len(t.pending) > 0 && keyframe && time-t.pending[0].time >= m.toTrack(t, m.FragmentDuration) {
+		if err := m.flush(t, time); err != nil {
+			return err
+		}
+	}
+
+	data := s.Data
+	if t.Codec == CodecH264 {
+		data = t.toAVCC(data)
+	}
+	cts := m.toTrack(t, s.CompositionOffset)
+	if cts < 0 {
+		cts = 0
+	}
+	t.pending = append(t.pending, pendingSample{time, cts, data, keyframe})
+	t.lastTime = time
+	t.started = true
+	return nil
+}
+
+func (t *Track) toAVCC(data []byte) []byte {
+	return ToAVCC(data, t.sps, t.pps)
+}
+
+// ToAVCC converts an Annex B access unit to length prefixed NAL units.
+// Parameter sets equal to sps and pps, which are stored in the sample description, are dropped, changed ones are kept in band.
+func ToAVCC(data []byte, sps, pps []byte) []byte {
+	nalus, _ := h264.SplitNALUs(data)
+	out := &bytes.Buffer{}
+	for _, nalu := range nalus {
+		if len(nalu) == 0 {
+			continue
+		}
+		switch h264.NALUType(nalu) {
+		case h264.NALU_SPS:
+			if bytes.Equal(nalu, sps) {
+				continue
+			}
+		case h264.NALU_PPS:
+			if bytes.Equal(nalu, pps) {
+				continue
+			}
+		}
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(nalu)))
+		out.Write(size[:])
+		out.Write(nalu)
+	}
+	return out.Bytes()
+}
+
+// Close writes all remaining samples. It does not close the underlying writer.
+func (m *Muxer) Close() error {
+	if !m.started {
+		if err := m.writeHeader(); err != nil {
+			return err
+		}
+		m.started = true
+	}
+	for _, t := range m.tracks {
+		if len(t.pending) == 0 {
+			continue
+		}
+		duration := t.lastDuration
+		if duration == 0 {
+			// single sample track, show it for a frame at 30 fps
+			duration = int64(t.timescale) / 30
+		}
+		if err := m.flush(t, t.lastTime+duration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes all pending samples of the track as one fragment. next is the start of the following sample.
+func (m *Muxer) flush(t *Track, next int64) error {
+	m.sequence++
+	samples := t.pending
+	t.pending = nil
+
+	durations := make([]uint32, len(samples))
+	size := 0
+	for i, s := range samples {
+		end := next
+		if i+1 < len(samples) {
+			end = samples[i+1].time
+		}
+		durations[i] = uint32(end - s.time)
+		size += len(s.data)
+	}
+	t.lastDuration = int64(durations[len(durations)-1])
+
+	moof := &buffer{}
+	dataOffsetPos := 0
+	moof.box("moof", func() {
+		moof.fullBox("mfhd", 0, 0, func() {
+			moof.u32(m.sequence)
+		})
+		moof.box("traf", func() {
+			// default-base-is-moof
+			moof.fullBox("tfhd", 0, 0x020000, func() {
+				moof.u32(t.ID)
+			})
+			moof.fullBox("tfdt", 1, 0, func() {
+				moof.u64(uint64(samples[0].time))
+			})
+			// data offset, sample duration, size, flags and composition time offset present
+			moof.fullBox("trun", 0, 0x000f01, func() {
+				moof.u32(uint32(len(samples)))
+				dataOffsetPos = moof.Len()
+				moof.u32(0)
+				for i, s := range samples {
+					moof.u32(durations[i])
+					moof.u32(uint32(len(s.data)))
+					if s.keyframe {
+						moof.u32(sampleFlagsSync)
+					} else {
+						moof.u32(sampleFlagsNonSync)
+					}
+					moof.u32(uint32(s.cts))
+				}
+			})
+		})
+	})
+	// the sample data starts right after the mdat header
+	binary.BigEndian.PutUint32(moof.Bytes()[dataOffsetPos:], uint32(moof.Len()+8))
+
+	mdat := &buffer{}
+	mdat.u32(uint32(size + 8))
+	mdat.WriteString("mdat")
+	for _, s := range samples {
+		mdat.Write(s.data)
+	}
+
+	if err := m.write(moof.Bytes()); err != nil {
+		return err
+	}
+	return m.write(mdat.Bytes())
+}
+
+func (m *Muxer) writeHeader() error {
+	if len(m.tracks) == 0 {
+		return fmt.Errorf("No tracks added")
+	}
+	b := &buffer{}
+	b.box("ftyp", func() {
+		b.WriteString("iso5")
+		b.u32(0x200)
+		for _, brand := range []string{"iso5", "iso6", "mp41", "avc1"} {
+			b.WriteString(brand)
+		}
+	})
+	b.box("moov", func() {
+		b.fullBox("mvhd", 0, 0, func() {
+			b.u32(0) // creation time
+			b.u32(0) // modification time
+			b.u32(uint32(m.timescale))
+			b.u32(0) // duration, unknown for fragmented files
+			b.u32(0x10000)
+			b.u16(0x100)
+			b.zeros(10)
+			b.matrix()
+			b.zeros(24)
+			b.u32(uint32(len(m.tracks) + 1))
+		})
+		for _, t := range m.tracks {
+			m.writeTrak(b, t)
+		}
+		b.box("mvex", func() {
+			for _, t := range m.tracks {
+				b.fullBox("trex", 0, 0, func() {
+					b.u32(t.ID)
+					b.u32(1) // sample description index
+					b.u32(0) // duration
+					b.u32(0) // size
+					b.u32(0) // flags
+				})
+			}
+		})
+	})
+	return m.write(b.Bytes())
+}
+
+func (m *Muxer) writeTrak(b *buffer, t *Track) {
+	video := t.Codec == CodecH264
+	b.box("trak", func() {
+		// enabled, in movie, in preview
+		b.fullBox("tkhd", 0, 7, func() {
+			b.u32(0)
+			b.u32(0)
+			b.u32(t.ID)
+			b.u32(0)
+			b.u32(0) // duration
+			b.zeros(8)
+			b.u16(0) // layer
+			b.u16(0) // alternate group
+			if video {
+				b.u16(0)
+			} else {
+				b.u16(0x100)
+			}
+			b.u16(0)
+			b.matrix()
+			b.u32(uint32(t.width) << 16)
+			b.u32(uint32(t.height) << 16)
+		})
+		b.box("mdia", func() {
+			b.fullBox("mdhd", 0, 0, func() {
+				b.u32(0)
+				b.u32(0)
+				b.u32(t.timescale)
+				b.u32(0)
+				b.u16(0x55c4) // und
+				b.u16(0)
+			})
+			b.fullBox("hdlr", 0, 0, func() {
+				b.u32(0)
+				if video {
+					b.WriteString("vide")
+				} else {
+					b.WriteString("soun")
+				}
+				b.zeros(12)
+				b.WriteString(t.Name)
+				b.u8(0)
+			})
+			b.box("minf", func() {
+				if video {
+					b.fullBox("vmhd", 0, 1, func() {
+						b.zeros(8)
+					})
+				} else {
+					b.fullBox("smhd", 0, 0, func() {
+						b.zeros(4)
+					})
+				}
+				b.box("dinf", func() {
+					b.fullBox("dref", 0, 0, func() {
+						b.u32(1)
+						// data is in the same file
+						b.fullBox("url ", 0, 1, func() {})
+					})
+				})
+				b.box("stbl", func() {
+					b.fullBox("stsd", 0, 0, func() {
+						b.u32(1)
+						if video {
+							t.writeAVC3(b)
+						} else {
+							t.writeOpus(b)
+						}
+					})
+					// the samples are all in fragments
+					b.fullBox("stts", 0, 0, func() { b.u32(0) })
+					b.fullBox("stsc", 0, 0, func() { b.u32(0) })
+					b.fullBox("stsz", 0, 0, func() { b.u32(0); b.u32(0) })
+					b.fullBox("stco", 0, 0, func() { b.u32(0) })
+				})
+			})
+		})
+	})
+}
+
+// writeAVC3 writes the sample entry of an H.264 track. It is avc3 rather than avc1, as parameter sets that change
+// mid-stream are kept in band, which avc1 does not allow.
+func (t *Track) writeAVC3(b *buffer) {
+	b.box("avc3", func() {
+		b.zeros(6)
+		b.u16(1) // data reference index
+		b.zeros(16)
+		b.u16(uint16(t.width))
+		b.u16(uint16(t.height))
+		b.u32(0x480000) // 72 dpi
+		b.u32(0x480000)
+		b.u32(0)
+		b.u16(1) // frame count
+		b.zeros(32)
+		b.u16(0x18) // depth
+		b.u16(0xffff)
+		b.box("avcC", func() {
+			b.Write(AVCDecoderConfig(t.spsInfo, t.sps, t.pps))
+		})
+	})
+}
+
+func (t *Track) writeOpus(b *buffer) {
+	b.box("Opus", func() {
+		b.zeros(6)
+		b.u16(1) // data reference index
+		b.zeros(8)
+		b.u16(uint16(t.channels))
+		b.u16(16) // sample size
+		b.zeros(4)
+		b.u32(uint32(opusTimescale) << 16)
+		b.box("dOps", func() {
+			b.u8(0) // version
+			b.u8(uint8(t.channels))
+			b.u16(0) // pre skip
+			b.u32(uint32(t.sampleRate))
+			b.u16(0) // output gain
+			b.u8(0)  // channel mapping family
+		})
+	})
+}
+
+// AVCDecoderConfig builds an AVCDecoderConfigurationRecord (the contents of the avcC box) from a single SPS and PPS.
+// Profile, level and for high profiles the chroma format and bit depths are taken from the parsed SPS.
+func AVCDecoderConfig(s *parser.SPSInfo, sps, pps []byte) []byte {
+	b := &buffer{}
+	b.u8(1) // configuration version
+	b.u8(uint8(s.ProfileIdc))
+	b.u8(uint8(s.ConstraintSetFlag << 2))
+	b.u8(uint8(s.LevelIdc))
+	b.u8(0xfc | 3) // 4 byte NAL unit lengths
+	b.u8(0xe0 | 1)
+	b.u16(uint16(len(sps)))
+	b.Write(sps)
+	b.u8(1)
+	b.u16(uint16(len(pps)))
+	b.Write(pps)
+	switch s.ProfileIdc {
+	case 100, 110, 122, 144:
+		b.u8(0xfc | uint8(s.ChromaFormatIdc))
+		b.u8(0xf8 | uint8(s.BitDepthLumaMinus8))
+		b.u8(0xf8 | uint8(s.BitDepthChromaMinus8))
+		b.u8(0) // no SPS extensions
+	}
+	return b.Bytes()
+}
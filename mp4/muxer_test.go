@@ -0,0 +1,100 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/galli-leo/gozoom/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func annexB(nalus ...[]byte) []byte {
+	out := []byte{}
+	for _, n := range nalus {
+		out = append(out, 0, 0, 0, 1)
+		out = append(out, n...)
+	}
+	return out
+}
+
+type testBox struct {
+	typ  string
+	data []byte
+}
+
+// findBoxes returns all boxes with the given path, e.g. moov/trak.
+func findBoxes(b []byte, path ...string) []testBox {
+	ret := []testBox{}
+	for len(b) >= 8 {
+		size := binary.BigEndian.Uint32(b)
+		typ := string(b[4:8])
+		if typ == path[0] {
+			if len(path) == 1 {
+				ret = append(ret, testBox{typ, b[8:size]})
+			} else {
+				ret = append(ret, findBoxes(b[8:size], path[1:]...)...)
+			}
+		}
+		b = b[size:]
+	}
+	return ret
+}
+
+func TestMuxer(t *testing.T) {
+	out := &bytes.Buffer{}
+	m := NewMuxer(out, 1000, zap.NewNop().Sugar())
+	m.FragmentDuration = 100
+
+	video, err := m.AddH264Track("Screen share", testdata.SPS, testdata.PPS)
+	require.NoError(t, err)
+	audio, err := m.AddOpusTrack("Audio", 48000, 1)
+	require.NoError(t, err)
+
+	idr := annexB(testdata.SPS, testdata.PPS, []byte{0x65, 0x88, 0x84})
+	p := annexB([]byte{0x41, 0x9a})
+	for i := int64(0); i < 6; i++ {
+		data := p
+		if i%3 == 0 {
+			data = idr
+		}
+		require.NoError(t, m.WriteSample(video, Sample{Time: 10 + i*40, Data: data, Keyframe: i%3 == 0}))
+		require.NoError(t, m.WriteSample(audio, Sample{Time: 10 + i*20, Data: []byte{0xfc, byte(i)}}))
+	}
+	require.NoError(t, m.Close())
+	_, err = m.AddOpusTrack("Late", 48000, 1)
+	assert.Error(t, err)
+
+	b := out.Bytes()
+	assert.Len(t, findBoxes(b, "moov", "trak"), 2)
+	avcC := findBoxes(b, "moov", "trak", "mdia", "minf", "stbl", "stsd")
+	require.Len(t, avcC, 2)
+	assert.Equal(t, "avc3", string(avcC[0].data[12:16]))
+	assert.True(t, bytes.Contains(avcC[0].data, append([]byte("avcC\x01\x64\x00\x1f\xff\xe1\x00"), byte(len(testdata.SPS)))))
+	assert.True(t, bytes.Contains(avcC[1].data, []byte("dOps")))
+
+	// video is cut at the second keyframe, audio after 100ms
+	trafs := findBoxes(b, "moof", "traf")
+	require.Len(t, trafs, 4)
+	counts := map[uint32][]uint32{}
+	for _, traf := range trafs {
+		id := binary.BigEndian.Uint32(findBoxes(traf.data, "tfhd")[0].data[4:])
+		trun := findBoxes(traf.data, "trun")[0].data
+		counts[id] = append(counts[id], binary.BigEndian.Uint32(trun[4:]))
+	}
+	assert.Equal(t, []uint32{3, 3}, counts[video.ID])
+	assert.Equal(t, []uint32{5, 1}, counts[audio.ID])
+
+	// parameter sets are in the sample description, the first video sample only keeps the slice
+	mdat := findBoxes(b, "mdat")[0].data
+	assert.Equal(t, []byte{0, 0, 0, 3, 0x65, 0x88, 0x84}, mdat[:7])
+}
+
+func TestToAVCC(t *testing.T) {
+	pps := []byte{0x68, 0xee, 0x3c, 0x80}
+	avcc := ToAVCC(annexB(testdata.SPS, pps, []byte{0x65, 0x88}), testdata.SPS, testdata.PPS)
+	// the changed PPS stays in band
+	assert.Equal(t, append(append([]byte{0, 0, 0, 4}, pps...), 0, 0, 0, 2, 0x65, 0x88), avcc)
+}
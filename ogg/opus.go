@@ -0,0 +1,120 @@
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// opusMinSilenceSamples is the shortest silence, a single 2.5ms frame.
+	opusMinSilenceSamples = 120
+	// opusMaxSilenceSamples is the longest silence in a single packet, 120ms.
+	opusMaxSilenceSamples = 5760
+)
+
+// OpusSilence returns an Opus packet of silent CELT frames lasting the given number of samples at 48 kHz.
+func OpusSilence(samples int) ([]byte, error) {
+	// full band CELT configurations, from 20ms down to 2.5ms frames
+	for config := 31; config >= 28; config-- {
+		frame := 120 << (config - 28)
+		if samples%frame != 0 || samples/frame > 48 {
+			continue
+		}
+		count := samples / frame
+		toc := byte(config << 3)
+		if count == 1 {
+			return []byte{toc, 0xff, 0xfe}, nil
+		}
+		pkt := []byte{toc | 3, byte(count)}
+		for i := 0; i < count; i++ {
+			pkt = append(pkt, 0xff, 0xfe)
+		}
+		return pkt, nil
+	}
+	return nil, fmt.Errorf("No silence for %d samples", samples)
+}
+
+// OpusWriter writes an Ogg Opus file. Packets carry their own position on the timeline,
+// gaps between them are filled with silence so that players keep them in sync with other streams.
+type OpusWriter struct {
+	w        *Writer
+	position int64
+	// Silence is the number of samples of silence inserted so far.
+	Silence int64
+}
+
+// NewOpusWriter writes the identification and comment headers of an Ogg Opus stream to w.
+func NewOpusWriter(w io.Writer, channels int, inputSampleRate uint32) (*OpusWriter, error) {
+	o := &OpusWriter{
+		w: NewWriter(w, 0x7a6f6f6d),
+	}
+
+	head := &bytes.Buffer{}
+	head.WriteString("OpusHead")
+	head.WriteByte(1) // version
+	head.WriteByte(byte(channels))
+	binary.Write(head, binary.LittleEndian, uint16(0)) // pre-skip
+	binary.Write(head, binary.LittleEndian, inputSampleRate)
+	binary.Write(head, binary.LittleEndian, int16(0)) // output gain
+	head.WriteByte(0)                                 // channel mapping family
+	if err := o.writeHeader(head.Bytes()); err != nil {
+		return nil, err
+	}
+
+	vendor := "gozoom"
+	tags := &bytes.Buffer{}
+	tags.WriteString("OpusTags")
+	binary.Write(tags, binary.LittleEndian, uint32(len(vendor)))
+	tags.WriteString(vendor)
+	binary.Write(tags, binary.LittleEndian, uint32(0)) // no user comments
+	if err := o.writeHeader(tags.Bytes()); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *OpusWriter) writeHeader(pkt []byte) error {
+	if err := o.w.WritePacket(pkt, 0); err != nil {
+		return err
+	}
+	return o.w.Flush()
+}
+
+// WritePacket writes an Opus packet starting at start and decoding to samples samples, both at 48 kHz.
+// Gaps are filled with silence in steps of 2.5ms, the remainder of a gap is carried over to the next one,
+// so the packets never drift from their start by more than 2.5ms.
+// Packets starting before the end of the previous one are appended directly after it.
+func (o *OpusWriter) WritePacket(pkt []byte, start int64, samples int) error {
+	for gap := start - o.position; gap >= opusMinSilenceSamples; gap = start - o.position {
+		n := gap - gap%opusMinSilenceSamples
+		if n > opusMaxSilenceSamples {
+			n = opusMaxSilenceSamples
+		}
+		silence, err := OpusSilence(int(n))
+		if err != nil {
+			return err
+		}
+		if err := o.write(silence, int(n)); err != nil {
+			return err
+		}
+		o.Silence += n
+	}
+	return o.write(pkt, samples)
+}
+
+func (o *OpusWriter) write(pkt []byte, samples int) error {
+	o.position += int64(samples)
+	return o.w.WritePacket(pkt, o.position)
+}
+
+// Position returns the end of the last packet written, at 48 kHz.
+func (o *OpusWriter) Position() int64 {
+	return o.position
+}
+
+// Close ends the stream. It does not close the underlying writer.
+func (o *OpusWriter) Close() error {
+	return o.w.Close()
+}
@@ -0,0 +1,129 @@
+// Package ogg writes Ogg streams, in particular Ogg Opus files as described in RFC 7845.
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	headerBOS = 0x02
+	headerEOS = 0x04
+
+	maxSegments = 255
+	// pages are flushed once they contain this much data
+	pageDataSize = 4096
+)
+
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func crc(b []byte) uint32 {
+	var c uint32
+	for _, v := range b {
+		c = c<<8 ^ crcTable[byte(c>>24)^v]
+	}
+	return c
+}
+
+// Writer packs packets of a single logical stream into Ogg pages.
+type Writer struct {
+	w        io.Writer
+	serial   uint32
+	sequence uint32
+	bos      bool
+
+	segments []byte
+	data     bytes.Buffer
+	granule  int64
+}
+
+func NewWriter(w io.Writer, serial uint32) *Writer {
+	return &Writer{
+		w:      w,
+		serial: serial,
+		bos:    true,
+	}
+}
+
+// WritePacket adds a packet to the current page. granule is the granule position after the packet.
+func (w *Writer) WritePacket(pkt []byte, granule int64) error {
+	lacing := len(pkt)/255 + 1
+	if lacing > maxSegments {
+		return fmt.Errorf("Packet of %d bytes does not fit in a page", len(pkt))
+	}
+	if len(w.segments)+lacing > maxSegments {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < lacing-1; i++ {
+		w.segments = append(w.segments, 255)
+	}
+	w.segments = append(w.segments, byte(len(pkt)%255))
+	w.data.Write(pkt)
+	w.granule = granule
+
+	if w.data.Len() >= pageDataSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the current page, if it contains any packets.
+// Header packets have to be flushed on their own page.
+func (w *Writer) Flush() error {
+	if len(w.segments) == 0 {
+		return nil
+	}
+	return w.writePage(0)
+}
+
+// Close writes the last page, marked as the end of the stream. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.writePage(headerEOS)
+}
+
+func (w *Writer) writePage(flags byte) error {
+	if w.bos {
+		flags |= headerBOS
+		w.bos = false
+	}
+	page := &bytes.Buffer{}
+	page.WriteString("OggS")
+	page.WriteByte(0) // version
+	page.WriteByte(flags)
+	binary.Write(page, binary.LittleEndian, w.granule)
+	binary.Write(page, binary.LittleEndian, w.serial)
+	binary.Write(page, binary.LittleEndian, w.sequence)
+	binary.Write(page, binary.LittleEndian, uint32(0)) // checksum
+	page.WriteByte(byte(len(w.segments)))
+	page.Write(w.segments)
+	page.Write(w.data.Bytes())
+
+	b := page.Bytes()
+	binary.LittleEndian.PutUint32(b[22:], crc(b))
+
+	w.sequence++
+	w.segments = w.segments[:0]
+	w.data.Reset()
+	if _, err := w.w.Write(b); err != nil {
+		return fmt.Errorf("Failed to write page: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPage struct {
+	flags   byte
+	granule int64
+	packets [][]byte
+}
+
+// readPages splits b into pages, checking their checksums, and reassembles the packets of each page.
+func readPages(t *testing.T, b []byte) []testPage {
+	pages := []testPage{}
+	for len(b) > 0 {
+		require.True(t, len(b) >= 27)
+		require.Equal(t, "OggS", string(b[:4]))
+		n := int(b[26])
+		size := 27 + n
+		for _, l := range b[27 : 27+n] {
+			size += int(l)
+		}
+		page := append([]byte{}, b[:size]...)
+		sum := binary.LittleEndian.Uint32(page[22:])
+		binary.LittleEndian.PutUint32(page[22:], 0)
+		assert.Equal(t, crc(page), sum)
+
+		p := testPage{flags: b[5], granule: int64(binary.LittleEndian.Uint64(b[6:]))}
+		data := b[27+n : size]
+		pkt := []byte{}
+		for _, l := range b[27 : 27+n] {
+			pkt = append(pkt, data[:l]...)
+			data = data[l:]
+			if l < 255 {
+				p.packets = append(p.packets, pkt)
+				pkt = []byte{}
+			}
+		}
+		pages = append(pages, p)
+		b = b[size:]
+	}
+	return pages
+}
+
+func TestCRC(t *testing.T) {
+	// check value of the CRC-32 variant used by Ogg (no reflection, no final xor)
+	assert.Equal(t, uint32(0x89a1897f), crc([]byte("123456789")))
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, 1)
+	large := bytes.Repeat([]byte{0xab}, 600)
+	require.NoError(t, w.WritePacket([]byte{1, 2, 3}, 10))
+	require.NoError(t, w.WritePacket(large, 20))
+	require.NoError(t, w.Flush())
+	require.NoError(t, w.WritePacket([]byte{4}, 30))
+	require.NoError(t, w.Close())
+
+	pages := readPages(t, buf.Bytes())
+	require.Len(t, pages, 2)
+	assert.Equal(t, byte(headerBOS), pages[0].flags)
+	assert.Equal(t, int64(20), pages[0].granule)
+	assert.Equal(t, [][]byte{{1, 2, 3}, large}, pages[0].packets)
+	assert.Equal(t, byte(headerEOS), pages[1].flags)
+	assert.Equal(t, int64(30), pages[1].granule)
+	assert.Equal(t, [][]byte{{4}}, pages[1].packets)
+}
+
+func TestOpusWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o, err := NewOpusWriter(buf, 2, 48000)
+	require.NoError(t, err)
+	frame := []byte{0xfc, 0xff, 0xfe}
+	require.NoError(t, o.WritePacket(frame, 0, 960))
+	// a gap of 61.25ms is filled with 60ms of silence, the rest is carried over to the next gap
+	require.NoError(t, o.WritePacket(frame, 960+2940, 960))
+	require.NoError(t, o.WritePacket(frame, 960+2940+960+60, 960))
+	// overlapping packets are appended
+	require.NoError(t, o.WritePacket(frame, 960+2940+960+60, 960))
+	require.NoError(t, o.Close())
+	assert.Equal(t, int64(3000), o.Silence)
+	assert.Equal(t, int64(4*960+3000), o.Position())
+
+	pages := readPages(t, buf.Bytes())
+	require.Len(t, pages, 3)
+	require.Len(t, pages[0].packets, 1)
+	head := pages[0].packets[0]
+	assert.Equal(t, "OpusHead", string(head[:8]))
+	assert.Equal(t, byte(2), head[9])
+	assert.Equal(t, "OpusTags", string(pages[1].packets[0][:8]))
+	silence, err := OpusSilence(2880)
+	require.NoError(t, err)
+	shortSilence, err := OpusSilence(120)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{frame, silence, frame, shortSilence, frame, frame}, pages[2].packets)
+	assert.Equal(t, int64(4*960+3000), pages[2].granule)
+	assert.Equal(t, byte(headerEOS), pages[2].flags)
+}
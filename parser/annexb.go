@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 
@@ -22,45 +23,89 @@ type AnnexBReader struct {
 	log  *zap.SugaredLogger
 	curr *NALUInfo
 	err  error
+	// bytes read from the underlying reader, but not yet returned
+	ahead []byte
 }
 
+// readRaw reads the next byte of the byte stream, regardless of NAL unit boundaries.
+func (r *AnnexBReader) readRaw() (byte, error) {
+	if len(r.ahead) > 0 {
+		b := r.ahead[0]
+		r.ahead = r.ahead[1:]
+		return b, nil
+	}
+	return r.BitReader.ReadByte()
+}
+
+// ReadByte reads the next byte of the current NAL unit.
+// It returns io.EOF at the end of the NAL unit, i.e. before the next three byte sequence 0x000000 or 0x000001,
+// or before the trailing zero bytes at the end of the stream.
+func (r *AnnexBReader) ReadByte() (byte, error) {
+	end := false
+	for len(r.ahead) < 3 {
+		b, err := r.BitReader.ReadByte()
+		if err != nil {
+			end = true
+			break
+		}
+		r.ahead = append(r.ahead, b)
+	}
+	if len(r.ahead) == 3 && r.ahead[0] == 0 && r.ahead[1] == 0 && r.ahead[2] <= 1 {
+		return 0, io.EOF
+	}
+	if end && bytes.Count(r.ahead, []byte{0}) == len(r.ahead) {
+		return 0, io.EOF
+	}
+	return r.readRaw()
+}
+
+func (r *AnnexBReader) Read(b []byte) (n int, err error) {
+	for n = 0; n < len(b); n++ {
+		if b[n], err = r.ReadByte(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Next advances to the next NAL unit and parses its header.
+// Any data left over from the previous NAL unit is skipped.
 func (r *AnnexBReader) Next() bool {
 	numZero := 0
+	skipped := 0
 	for {
-		b, err := r.ReadByte()
+		b, err := r.readRaw()
 		if err != nil {
-			if err == io.EOF {
-				return false
+			if err != io.EOF {
+				r.err = multierror.Append(r.err, err)
 			}
-			r.err = multierror.Append(r.err, err)
 			return false
 		}
-		if numZero < 2 {
-			if b != 0 {
-				r.addErr("Expected zero byte, got 0x%x", b)
-				return false
+		if b == 0 {
+			numZero++
+			continue
+		}
+		if b == 1 && numZero >= 2 {
+			if skipped > 0 {
+				r.log.Debugf("Skipped %d bytes before start code", skipped)
 			}
-		} else {
-			if b != 1 && b != 0 {
-				r.addErr("Expected zero/one byte, got 0x%x", b)
+			// parse nalu info
+			header, err := r.readRaw()
+			if err != nil {
+				r.addErr("missing NAL unit header: %v", err)
 				return false
 			}
-			if b == 1 {
-				// parse nalu info
-				forbiddenZero := r.ReadBits(1)
-				if forbiddenZero != 0 {
-					r.addErr("forbidden_zero_bit is not zero")
-				}
-				r.curr = &NALUInfo{}
-				r.curr.NalRefIdc = uint(r.ReadBits(2))
-				r.curr.Type = NALUType(r.ReadBits(5))
-				return true
+			if header&0x80 != 0 {
+				r.addErr("forbidden_zero_bit is not zero")
 			}
+			r.curr = &NALUInfo{}
+			r.curr.NalRefIdc = uint(header>>5) & 3
+			r.curr.Type = NALUType(header & 0x1f)
+			return true
 		}
-		numZero++
-
+		skipped += numZero + 1
+		numZero = 0
 	}
-	return false
 }
 
 func (r *AnnexBReader) Current() *NALUInfo {
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestAnnexBReader(stream []byte) *AnnexBReader {
+	return NewAnnexBReader(NewBitioReader(bytes.NewReader(stream)), zap.NewNop().Sugar())
+}
+
+func TestAnnexBReaderNext(t *testing.T) {
+	stream := []byte{
+		// leading garbage, then a four byte start code
+		0xff, 0x12, 0, 0, 0, 1, 0x67, 0xaa, 0xbb,
+		// a three byte start code, the payload contains 0x000003
+		0, 0, 1, 0x68, 0x00, 0x00, 0x03, 0x01,
+		// trailing_zero_8bits before the next start code
+		0, 0, 0, 0, 1, 0x65, 0x88,
+		// trailing zeros at the end of the stream
+		0, 0,
+	}
+	r := newTestAnnexBReader(stream)
+
+	type nalu struct {
+		typ     NALUType
+		refIdc  uint
+		payload []byte
+	}
+	expected := []nalu{
+		{NALU_SPS, 3, []byte{0xaa, 0xbb}},
+		{NALU_PPS, 3, []byte{0x00, 0x00, 0x03, 0x01}},
+		{NALU_IDR, 3, []byte{0x88}},
+	}
+	for _, e := range expected {
+		require.True(t, r.Next())
+		assert.Equal(t, e.typ, r.Current().Type)
+		assert.Equal(t, e.refIdc, r.Current().NalRefIdc)
+		payload, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, e.payload, payload, "%s", e.typ)
+		// the end of the NAL unit stays the end until Next is called
+		_, err = r.ReadByte()
+		assert.Equal(t, io.EOF, err)
+	}
+	assert.False(t, r.Next())
+	assert.NoError(t, r.Error())
+}
+
+func TestAnnexBReaderNextSkipsRest(t *testing.T) {
+	r := newTestAnnexBReader([]byte{0, 0, 1, 0x67, 1, 2, 3, 0, 0, 1, 0x41, 4})
+	require.True(t, r.Next())
+	b, err := r.ReadByte()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, b)
+
+	require.True(t, r.Next())
+	assert.Equal(t, NALU_NONIDR, r.Current().Type)
+	assert.EqualValues(t, 2, r.Current().NalRefIdc)
+	b, err = r.ReadByte()
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, b)
+	assert.False(t, r.Next())
+}
+
+func TestAnnexBReaderForbiddenZeroBit(t *testing.T) {
+	r := newTestAnnexBReader([]byte{0, 0, 1, 0xe7, 1})
+	require.True(t, r.Next())
+	assert.Error(t, r.Error())
+
+	r = newTestAnnexBReader([]byte{0, 0, 1})
+	assert.False(t, r.Next())
+	assert.Error(t, r.Error())
+}
+
+func TestEmuPreventionReader(t *testing.T) {
+	annexb := newTestAnnexBReader([]byte{
+		0, 0, 1, 0x68, 0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x03, 0x80,
+		0, 0, 1, 0x68, 0xc0, 0x00,
+	})
+	r := NewEmuPreventionReader(annexb, zap.NewNop().Sugar())
+
+	require.True(t, annexb.Next())
+	rbsp, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x03, 0x80}, rbsp)
+
+	r.Reset()
+	require.True(t, annexb.Next())
+	// the rbsp_stop_one_bit is the second bit, the trailing zero byte is cabac_zero_word padding
+	assert.True(t, r.MoreRBSPData())
+	assert.EqualValues(t, 1, r.ReadBits(1))
+	assert.False(t, r.MoreRBSPData())
+	r.ReadBits(1)
+	assert.False(t, r.MoreRBSPData())
+}
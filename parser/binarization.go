@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"math"
 	"math/bits"
 )
@@ -123,17 +122,138 @@ func (b *TableBin) GetValue(bins uint, binIdx uint) (ok bool, val uint) {
 	return
 }
 
-var GolombBin = NewUIBin("Golomb")
-
-func NewUIBin(name string) *UnimplBin {
-	return &UnimplBin{name}
+func NewBinStrBin(tbl map[string]uint) *BinStrBin {
+	return &BinStrBin{tbl}
 }
 
-type UnimplBin struct {
-	name string
+// BinStrBin looks up bin strings, written in decoding order, in a table.
+// Unlike TableBin it tells apart bin strings that only differ in their number of leading zeros.
+type BinStrBin struct {
+	tbl map[string]uint
 }
 
-func (b *UnimplBin) GetValue(bins uint, binIdx uint) (ok bool, val uint) {
-	panic(fmt.Sprintf("Binarization %s is not yet implemented!", b.name))
+func (b *BinStrBin) GetValue(bins uint, binIdx uint) (ok bool, val uint) {
+	str := make([]byte, binIdx+1)
+	for i := range str {
+		str[i] = '0' + byte((bins>>uint(i))&1)
+	}
+	val, ok = b.tbl[string(str)]
 	return
 }
+
+/* Table 9-37, the prefix "1" of intra macroblock types yields NumPMBTypes, the value of the first of them */
+var MbTypePPrefixBin = NewBinStrBin(map[string]uint{
+	"000": P_L0_16x16.MBType(),
+	"011": P_L0_L0_16x8.MBType(),
+	"010": P_L0_L0_8x16.MBType(),
+	"001": P_8x8.MBType(),
+	"1":   NumPMBTypes,
+})
+
+/* Table 9-37, the prefix "111101" of intra macroblock types yields NumBMBTypes, the value of the first of them */
+var MbTypeBPrefixBin = NewBinStrBin(map[string]uint{
+	"0":       B_Direct_16x16.MBType(),
+	"100":     B_L0_16x16.MBType(),
+	"101":     B_L1_16x16.MBType(),
+	"110000":  B_Bi_16x16.MBType(),
+	"110001":  B_L0_L0_16x8.MBType(),
+	"110010":  B_L0_L0_8x16.MBType(),
+	"110011":  B_L1_L1_16x8.MBType(),
+	"110100":  B_L1_L1_8x16.MBType(),
+	"110101":  B_L0_L1_16x8.MBType(),
+	"110110":  B_L0_L1_8x16.MBType(),
+	"110111":  B_L1_L0_16x8.MBType(),
+	"111110":  B_L1_L0_8x16.MBType(),
+	"1110000": B_L0_Bi_16x8.MBType(),
+	"1110001": B_L0_Bi_8x16.MBType(),
+	"1110010": B_L1_Bi_16x8.MBType(),
+	"1110011": B_L1_Bi_8x16.MBType(),
+	"1110100": B_Bi_L0_16x8.MBType(),
+	"1110101": B_Bi_L0_8x16.MBType(),
+	"1110110": B_Bi_L1_16x8.MBType(),
+	"1110111": B_Bi_L1_8x16.MBType(),
+	"1111000": B_Bi_Bi_16x8.MBType(),
+	"1111001": B_Bi_Bi_8x16.MBType(),
+	"111111":  B_8x8.MBType(),
+	"111101":  NumBMBTypes,
+})
+
+/* Table 9-38 */
+var SubMbTypePBin = NewBinStrBin(map[string]uint{
+	"1":   uint(P_L0_8x8),
+	"00":  uint(P_L0_8x4),
+	"011": uint(P_L0_4x8),
+	"010": uint(P_L0_4x4),
+})
+
+/* Table 9-38 */
+var SubMbTypeBBin = NewBinStrBin(map[string]uint{
+	"0":      uint(B_Direct_8x8),
+	"100":    uint(B_L0_8x8),
+	"101":    uint(B_L1_8x8),
+	"11000":  uint(B_Bi_8x8),
+	"11001":  uint(B_L0_8x4),
+	"11010":  uint(B_L0_4x8),
+	"11011":  uint(B_L1_8x4),
+	"111000": uint(B_L1_4x8),
+	"111001": uint(B_Bi_8x4),
+	"111010": uint(B_Bi_4x8),
+	"111011": uint(B_L0_4x4),
+	"11110":  uint(B_L1_4x4),
+	"11111":  uint(B_Bi_4x4),
+})
+
+/* Clause 9.3.2.3 */
+func NewUEGkBin(k, uCoff uint) *UEGkBin {
+	return &UEGkBin{
+		k:     k,
+		uCoff: uCoff,
+	}
+}
+
+// UEGkBin is the concatenated unary / k-th order Exp-Golomb binarization.
+// The prefix is truncated unary with cMax uCoff, the suffix (bins from uCoff on) an Exp-Golomb code of order k.
+// Signed values are binarized as their absolute value, followed by a separate sign bin.
+type UEGkBin struct {
+	k     uint
+	uCoff uint
+}
+
+func (b *UEGkBin) GetValue(bins uint, binIdx uint) (ok bool, val uint) {
+	numBins := binIdx + 1
+	i := uint(0)
+	for ; i < b.uCoff; i++ {
+		if i >= numBins {
+			return false, 0
+		}
+		if (bins>>i)&1 == 0 {
+			return i+1 == numBins, i
+		}
+	}
+
+	val = b.uCoff
+	k := b.k
+	for {
+		if i >= numBins {
+			return false, 0
+		}
+		bit := (bins >> i) & 1
+		i++
+		if bit == 0 {
+			break
+		}
+		val += 1 << k
+		k++
+	}
+	if i+k != numBins {
+		return false, 0
+	}
+	for j := uint(0); j < k; j++ {
+		val += ((bins >> (i + j)) & 1) << (k - 1 - j)
+	}
+
+	return true, val
+}
+
+var UEG0Bin = NewUEGkBin(0, 14)
+var UEG3Bin = NewUEGkBin(3, 9)
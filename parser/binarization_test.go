@@ -102,3 +102,85 @@ func TestUbin(t *testing.T) {
 		},
 	})
 }
+
+func TestUEG0Bin(t *testing.T) {
+	prefix := uint(1)<<14 - 1
+	runBinTest(t, UEG0Bin, []binCase{
+		{
+			0, 0, 0,
+		},
+		{
+			0b0111, 3, 3,
+		},
+		{
+			prefix, 13, notOk,
+		},
+		{
+			prefix, 14, 14,
+		},
+		{
+			// suffix 1, 1, 0, 10
+			prefix | 1<<14 | 1<<15 | 1<<17, 17, notOk,
+		},
+		{
+			prefix | 1<<14 | 1<<15 | 1<<17, 18, 19,
+		},
+	})
+}
+
+func TestMbTypePPrefixBin(t *testing.T) {
+	runBinTest(t, MbTypePPrefixBin, []binCase{
+		{
+			0b1, 0, NumPMBTypes,
+		},
+		{
+			0b100, 2, P_8x8.MBType(),
+		},
+		{
+			0b110, 2, P_L0_L0_16x8.MBType(),
+		},
+		{
+			0b00, 1, notOk,
+		},
+	})
+}
+
+func TestMbTypeBPrefixBin(t *testing.T) {
+	runBinTest(t, MbTypeBPrefixBin, []binCase{
+		{
+			0b0, 0, B_Direct_16x16.MBType(),
+		},
+		{
+			0b101, 2, B_L1_16x16.MBType(),
+		},
+		{
+			0b011111, 5, B_L1_L0_8x16.MBType(),
+		},
+		{
+			0b0000111, 6, B_L0_Bi_16x8.MBType(),
+		},
+		{
+			0b101111, 5, NumBMBTypes,
+		},
+		{
+			0b1011, 3, notOk,
+		},
+	})
+}
+
+func TestSubMbTypeBBin(t *testing.T) {
+	runBinTest(t, SubMbTypeBBin, []binCase{
+		{
+			0b0, 0, uint(B_Direct_8x8),
+		},
+		{
+			0b00011, 4, uint(B_Bi_8x8),
+		},
+		{
+			0b110111, 5, uint(B_L0_4x4),
+		},
+		{
+			0b11111, 4, uint(B_Bi_4x4),
+		},
+	})
+}
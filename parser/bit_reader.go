@@ -38,7 +38,21 @@ func (b *BitioReader) Error() error {
 
 func (b *BitioReader) Read(p []byte) (n int, err error) {
 	n, err = b.Reader.Read(p)
-	b.pos += uint(n)
+	b.pos += 8 * uint(n)
+	return
+}
+
+func (b *BitioReader) ReadByte() (byte, error) {
+	val, err := b.Reader.ReadByte()
+	if err == nil {
+		b.pos += 8
+	}
+	return val, err
+}
+
+func (b *BitioReader) Align() (skipped uint8) {
+	skipped = b.Reader.Align()
+	b.pos += uint(skipped)
 	return
 }
 
@@ -57,6 +71,7 @@ func (b *BitioReader) ReadBits(n uint8) (u uint) {
 	return
 }
 
+// Position returns the number of bits read.
 func (b *BitioReader) Position() uint {
 	return b.pos
 }
@@ -34,6 +34,9 @@ type CabacParser struct {
 	codIRange  uint16
 
 	bins uint
+
+	// the partition of the ref_idx or mvd being parsed
+	part partRef
 }
 
 func Clip3(x, y, z int64) int64 {
@@ -53,9 +56,14 @@ func preCtxState(m, n, sliceQP int64) int64 {
 
 func (p *CabacParser) Initialize() {
 	sliceQP := 26 + p.h.PPS.PicInitQPMinus26 + p.h.SliceQPDelta
+	// the first column is used by I slices, the others by cabac_init_idc 0-2
+	model := 0
+	if !p.h.Intra() {
+		model = int(p.h.CabacInitIdc) + 1
+	}
 	for i := 0; i < WELS_CONTEXT_COUNT; i++ {
-		m := g_kiCabacGlobalContextIdx[i][0][0]
-		n := g_kiCabacGlobalContextIdx[i][0][1]
+		m := g_kiCabacGlobalContextIdx[i][model][0]
+		n := g_kiCabacGlobalContextIdx[i][model][1]
 		iPreCtxState := preCtxState(int64(m), int64(n), int64(sliceQP))
 		var elem CabacElement
 		if iPreCtxState <= 63 {
@@ -83,13 +91,59 @@ type CabacSE interface {
 	Binarization() Binarization
 	MaxBinIdx() uint
 	GetCtxIdx(binIdx uint) uint
-	Bypass() bool
+	Bypass(binIdx uint) bool
 }
 
 func (p *CabacParser) ParseMBType() uint {
+	switch p.h.Type() {
+	case SliceP, SliceSP:
+		prefix := p.ParseElement(MBTypePPrefixSE)
+		if prefix != NumPMBTypes {
+			return prefix
+		}
+		return NumPMBTypes + p.ParseElement(MBTypePSuffixSE)
+	case SliceB:
+		prefix := p.ParseElement(MBTypeBPrefixSE)
+		if prefix != NumBMBTypes {
+			return prefix
+		}
+		return NumBMBTypes + p.ParseElement(MBTypeBSuffixSE)
+	}
 	return p.ParseElement(MBTypeSE)
 }
 
+func (p *CabacParser) ParseMbSkipFlag() uint {
+	if p.h.Type() == SliceB {
+		return p.ParseElement(MBSkipFlagBSE)
+	}
+	return p.ParseElement(MBSkipFlagPSE)
+}
+
+func (p *CabacParser) ParseSubMbType() uint {
+	if p.h.Type() == SliceB {
+		return p.ParseElement(SubMBTypeBSE)
+	}
+	return p.ParseElement(SubMBTypePSE)
+}
+
+func (p *CabacParser) ParseRefIdx(list, mbPartIdx uint) int {
+	p.part = partRef{list: list, mbPartIdx: mbPartIdx}
+	return int(p.ParseElement(RefIdxSE))
+}
+
+func (p *CabacParser) ParseMvd(list, mbPartIdx, subMbPartIdx, compIdx uint) int {
+	p.part = partRef{list, mbPartIdx, subMbPartIdx, compIdx}
+	elem := MvdHorSE
+	if compIdx == 1 {
+		elem = MvdVerSE
+	}
+	val := int(p.ParseElement(elem))
+	if val != 0 && p.ParseElement(MvdSignFlagSE) != 0 {
+		val = -val
+	}
+	return val
+}
+
 func (p *CabacParser) ParseTransformSize8x8Flag() uint {
 	return p.ParseElement(TransformSizeFlagSE)
 }
@@ -137,16 +191,33 @@ func (p *CabacParser) ParseCodedBlockFlag(block *ResidualBlock) uint {
 	return p.ParseElement(CodedBlockFlagSE)
 }
 
-func (p *CabacParser) ParseSignificantCoeffFlag(block *ResidualBlock) uint {
+func (p *CabacParser) ParseSignificantCoeffFlag(block *ResidualBlock, levelListIdx uint) uint {
 	SignificantCoeffFlagSE.block = block
+	SignificantCoeffFlagSE.levelListIdx = levelListIdx
 	return p.ParseElement(SignificantCoeffFlagSE)
 }
 
-func (p *CabacParser) ParseLastSignificantCoeffFlag(block *ResidualBlock) uint {
+func (p *CabacParser) ParseLastSignificantCoeffFlag(block *ResidualBlock, levelListIdx uint) uint {
 	LastSignificantCoeffFlagSE.block = block
+	LastSignificantCoeffFlagSE.levelListIdx = levelListIdx
 	return p.ParseElement(LastSignificantCoeffFlagSE)
 }
 
+func (p *CabacParser) ParseCoeffAbsLevelMinus1(block *ResidualBlock) uint {
+	CoeffAbsLevelMinus1SE.block = block
+	val := p.ParseElement(CoeffAbsLevelMinus1SE)
+	if val == 0 {
+		block.numDecodAbsLevelEq1++
+	} else {
+		block.numDecodAbsLevelGt1++
+	}
+	return val
+}
+
+func (p *CabacParser) ParseCoeffSignFlag() uint {
+	return p.ParseElement(CoeffSignFlagSE)
+}
+
 func (p *CabacParser) ParseElement(elem CabacSE) uint {
 	p.bins = uint(0)
 	elem.SetParser(p)
@@ -155,10 +226,12 @@ func (p *CabacParser) ParseElement(elem CabacSE) uint {
 		if binIdx > elem.MaxBinIdx() {
 			clampedBinIdx = elem.MaxBinIdx()
 		}
-		ctxIdx := elem.GetCtxIdx(clampedBinIdx)
 		bypass := uint(0)
-		if elem.Bypass() {
+		ctxIdx := uint(0)
+		if elem.Bypass(binIdx) {
 			bypass = 1
+		} else {
+			ctxIdx = elem.GetCtxIdx(clampedBinIdx)
 		}
 
 		binVal := p.DecodeBin(ctxIdx, bypass)
@@ -253,9 +326,7 @@ func (p *CabacParser) GetCtxIdxInc(binIdx uint, ctxIdxOffset uint) uint {
 
 	if ctxIdxOffset == 40 || ctxIdxOffset == 47 {
 		if binIdx == 0 {
-			// TODO: Clause 9.3.3.1.1.7
-			panic("Not yet implemented!")
-
+			return p.CtxIdxIncMvd()
 		}
 		if binIdx >= 4 {
 			return 6
@@ -265,9 +336,7 @@ func (p *CabacParser) GetCtxIdxInc(binIdx uint, ctxIdxOffset uint) uint {
 
 	if ctxIdxOffset == 54 {
 		if binIdx == 0 {
-			// TODO: Clause 9.3.3.1.1.6
-			panic("Not yet implemented!")
-
+			return p.CtxIdxIncRefIdx()
 		}
 		if binIdx == 1 {
 			return 4
@@ -391,3 +460,45 @@ func (p *CabacParser) DecodeTerminate() (binVal uint) {
 
 	return
 }
+
+/* Clause 7.3.5.3.3 */
+func (p *CabacParser) ParseResidualBlock(coeffLevel *ResidualBlock, startIdx, endIdx, maxNumCoeff uint) {
+	coded_block_flag := uint(1)
+	if maxNumCoeff != 64 || p.h264.SPS.ChromaArrayType() == 3 {
+		coded_block_flag = p.ParseCodedBlockFlag(coeffLevel)
+	}
+	*coeffLevel.codedBlockFlag = coded_block_flag
+	for i := uint(0); i < maxNumCoeff; i++ {
+		coeffLevel.level[i] = 0
+	}
+	if coded_block_flag == 0 {
+		return
+	}
+
+	significant_coeff_flag := make([]uint, maxNumCoeff)
+	numCoeff := endIdx + 1
+	for i := startIdx; i < numCoeff-1; i++ {
+		significant_coeff_flag[i] = p.ParseSignificantCoeffFlag(coeffLevel, i)
+		p.s.CurrMb.significantCoeffFlag = append(p.s.CurrMb.significantCoeffFlag, significant_coeff_flag[i])
+		if significant_coeff_flag[i] != 0 {
+			last_significant_coeff_flag := p.ParseLastSignificantCoeffFlag(coeffLevel, i)
+			p.s.CurrMb.lastSignificantCoeffFlag = append(p.s.CurrMb.lastSignificantCoeffFlag, last_significant_coeff_flag)
+			if last_significant_coeff_flag != 0 {
+				numCoeff = i + 1
+			}
+		}
+	}
+	// the last coefficient is significant, if no earlier one was marked as the last
+	significant_coeff_flag[numCoeff-1] = 1
+
+	for i := int(numCoeff) - 1; i >= int(startIdx); i-- {
+		if significant_coeff_flag[i] == 0 {
+			continue
+		}
+		coeff_abs_level_minus1 := p.ParseCoeffAbsLevelMinus1(coeffLevel)
+		coeff_sign_flag := p.ParseCoeffSignFlag()
+		p.s.CurrMb.coeffAbsLevelMinus1 = append(p.s.CurrMb.coeffAbsLevelMinus1, coeff_abs_level_minus1)
+		p.s.CurrMb.coeffSignFlag = append(p.s.CurrMb.coeffSignFlag, coeff_sign_flag)
+		coeffLevel.level[i] = int(coeff_abs_level_minus1+1) * (1 - 2*int(coeff_sign_flag))
+	}
+}
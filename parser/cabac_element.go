@@ -19,7 +19,11 @@ func (b *BaseCabacSE) GetCtxIdx(binIdx uint) uint {
 	return b.ctxIdxOffset
 }
 
-func (b *BaseCabacSE) Bypass() bool {
+// Bypass returns whether the bin is decoded in bypass mode, which is the case for the suffix of UEGk binarizations.
+func (b *BaseCabacSE) Bypass(binIdx uint) bool {
+	if ueg, ok := b.bin.(*UEGkBin); ok {
+		return binIdx >= ueg.uCoff
+	}
 	return false
 }
 
@@ -50,6 +54,23 @@ func NewFlagSE(ctxIdxOffset uint) *RegularCabacSE {
 	return NewRegSE(0, ctxIdxOffset, FlagBin)
 }
 
+func NewBypassSE(bin Binarization) *BypassCabacSE {
+	return &BypassCabacSE{
+		BaseCabacSE{
+			bin: bin,
+		},
+	}
+}
+
+// BypassCabacSE is a syntax element with all bins decoded in bypass mode.
+type BypassCabacSE struct {
+	BaseCabacSE
+}
+
+func (b *BypassCabacSE) Bypass(binIdx uint) bool {
+	return true
+}
+
 var MBTypeSE = NewRegSE(6, 3, MbTypeIBin)
 var TransformSizeFlagSE = NewFlagSE(399)
 var CodedBlockPatternLumaSE = NewRegSE(3, 73, NewFLBin(15))
@@ -60,8 +81,22 @@ var PrevIntraPredModeFlagSE = NewFlagSE(68)
 var RemIntraPredModeSE = NewRegSE(0, 69, NewFLBin(7))
 var MBFieldDecodingFlagSE = NewFlagSE(70)
 var EndOfSliceSE = NewFlagSE(TERMINATE_CTX)
+var CoeffSignFlagSE = NewBypassSE(FlagBin)
 
-func NewBlockSE(maxBinIdx uint, blockCatOffset [14]uint, ctxIdxOffset, ctxIdxInc func(*BlockCabacSE) uint, bin Binarization) *BlockCabacSE {
+var MBSkipFlagPSE = NewFlagSE(11)
+var MBTypePPrefixSE = NewRegSE(2, 14, MbTypePPrefixBin)
+var MBTypePSuffixSE = NewRegSE(5, 17, MbTypeIBin)
+var SubMBTypePSE = NewRegSE(2, 21, SubMbTypePBin)
+var MBSkipFlagBSE = NewFlagSE(24)
+var MBTypeBPrefixSE = NewRegSE(3, 27, MbTypeBPrefixBin)
+var MBTypeBSuffixSE = NewRegSE(5, 32, MbTypeIBin)
+var SubMBTypeBSE = NewRegSE(3, 36, SubMbTypeBBin)
+var RefIdxSE = NewRegSE(2, 54, NewUBin())
+var MvdHorSE = NewRegSE(4, 40, UEG3Bin)
+var MvdVerSE = NewRegSE(4, 47, UEG3Bin)
+var MvdSignFlagSE = NewBypassSE(FlagBin)
+
+func NewBlockSE(maxBinIdx uint, blockCatOffset [14]uint, ctxIdxOffset func(*BlockCabacSE) uint, ctxIdxInc func(*BlockCabacSE, uint) uint, bin Binarization) *BlockCabacSE {
 	return &BlockCabacSE{
 		BaseCabacSE{
 			maxBinIdx:    maxBinIdx,
@@ -81,15 +116,15 @@ type BlockCabacSE struct {
 	block          *ResidualBlock
 	levelListIdx   uint
 	blockCatOffset [14]uint
-	ctxIdxInc      func(*BlockCabacSE) uint
+	ctxIdxInc      func(*BlockCabacSE, uint) uint
 	ctxIdxOffset   func(*BlockCabacSE) uint
 }
 
 func (b *BlockCabacSE) GetCtxIdx(binIdx uint) uint {
-	return b.ctxIdxOffset(b) + b.ctxIdxInc(b) + b.blockCatOffset[b.block.cat.Num()]
+	return b.ctxIdxOffset(b) + b.ctxIdxInc(b, binIdx) + b.blockCatOffset[b.block.cat.Num()]
 }
 
-func NewBFlagSE(blockCatOffset [14]uint, ctxIdxOffset, ctxIdxInc func(*BlockCabacSE) uint) *BlockCabacSE {
+func NewBFlagSE(blockCatOffset [14]uint, ctxIdxOffset func(*BlockCabacSE) uint, ctxIdxInc func(*BlockCabacSE, uint) uint) *BlockCabacSE {
 	return NewBlockSE(0, blockCatOffset, ctxIdxOffset, ctxIdxInc, FlagBin)
 }
 
@@ -110,6 +145,7 @@ func CBFOffset(b *BlockCabacSE) uint {
 	return 1024
 }
 
+/* Clause 9.3.3.1.1.9 */
 func CBFGetTransBlock(b *BlockCabacSE, nt NType) (mbAddrN MBAddr, transBlockN *ResidualBlock) {
 	cat := b.block.cat
 	blkIdx := b.block.blkIdx
@@ -119,30 +155,44 @@ func CBFGetTransBlock(b *BlockCabacSE, nt NType) (mbAddrN MBAddr, transBlockN *R
 		return
 	}
 	mb := b.p.s.MacroBlocks[mbAddrN]
+	if mb.IsPCM() {
+		return
+	}
+	if cat.Chroma() {
+		residual := &mb.chromaResidual[b.block.iCbCr]
+		if cat.FullMB() {
+			if mb.CodedBlockPatternChroma() != AllCoeffs0 {
+				transBlockN = residual.GetBlock(BlockDC, 0)
+			}
+		} else if mb.CodedBlockPatternChroma() == AllDCCoeffs0 {
+			transBlockN = residual.GetBlock(BlockAC, uint(blkIdxN))
+		}
+		return
+	}
 	residual := mb.GetResidual(cat)
 	if cat.FullMB() {
-		if cat.Chroma() {
-			panic("Not implemented yet")
+		if mb.MbPartPredMode(0) == Intra_16x16 {
+			transBlockN = residual.GetBlock(BlockDC, 0)
 		}
-		if mb.IMBType().MbPartPredMode(0) != Intra_16x16 {
-			return
-		}
-		transBlockN = residual.GetBlock(cat, 0)
 		return
 	}
 	if cat.Level4() {
 		if ((mb.CodedBlockPatternLuma() >> (blkIdxN >> 2)) & 1) != 0 {
 			if mb.transformSize8x8Flag == 0 {
-				transBlockN = residual.GetBlock(cat.Type()|BlockLevel, uint(blkIdxN))
+				size := BlockLevel
+				if mb.MbPartPredMode(0) == Intra_16x16 {
+					size = BlockAC
+				}
+				transBlockN = residual.GetBlock(size, uint(blkIdxN))
 			} else {
-				transBlockN = residual.GetBlock(cat.Type()|BlockLevel8, uint(blkIdxN)>>2)
+				transBlockN = residual.GetBlock(BlockLevel8, uint(blkIdxN)>>2)
 			}
 		}
 		return
 	}
 	if cat.Level8() {
 		if ((mb.CodedBlockPatternLuma()>>blkIdxN)&1) != 0 && mb.transformSize8x8Flag == 1 {
-			transBlockN = residual.GetBlock(cat, uint(blkIdxN))
+			transBlockN = residual.GetBlock(BlockLevel8, uint(blkIdxN))
 		}
 	}
 
@@ -151,16 +201,22 @@ func CBFGetTransBlock(b *BlockCabacSE, nt NType) (mbAddrN MBAddr, transBlockN *R
 
 func CBFCondFlag(b *BlockCabacSE, nt NType) uint {
 	mbAddrN, transBlockN := CBFGetTransBlock(b, nt)
-	if mbAddrN == MBUnavailable /* Check for Intra! */ {
+	if mbAddrN == MBUnavailable {
+		if b.p.s.CurrMb.IsIntra() {
+			return 1
+		}
+		return 0
+	}
+	if b.p.s.MacroBlocks[mbAddrN].IsPCM() {
 		return 1
 	}
 	if transBlockN == nil {
 		return 0
 	}
-	panic("Not yet implemented")
+	return transBlockN.CodedBlockFlag()
 }
 
-func CBFInc(b *BlockCabacSE) uint {
+func CBFInc(b *BlockCabacSE, binIdx uint) uint {
 	condFlagA := CBFCondFlag(b, NA)
 	condFlagB := CBFCondFlag(b, NB)
 
@@ -205,36 +261,82 @@ func LSCFOffset(b *BlockCabacSE) uint {
 	return 616
 }
 
-func SCFInc(b *BlockCabacSE) uint {
-	cat := b.block.cat
-	if cat.Size() != BlockLevel8 && cat != BlockChromaDC {
-		return b.levelListIdx
+func chromaDCInc(b *BlockCabacSE) uint {
+	val := b.levelListIdx / b.p.s.h264.SPS.NumC8x8()
+	if val > 2 {
+		return 2
 	}
+	return val
+}
+
+/* Clause 9.3.3.1.3 */
+func SCFInc(b *BlockCabacSE, binIdx uint) uint {
+	cat := b.block.cat
 	if cat == BlockChromaDC {
-		val := b.levelListIdx / b.p.s.h264.SPS.NumC8x8()
-		if val > 2 {
-			return 2
-		}
+		return chromaDCInc(b)
+	}
+	if cat.Size() != BlockLevel8 {
+		return b.levelListIdx
 	}
 
 	return kuiSignificantCoeffFlagOffset8x8[0][b.levelListIdx]
 }
 
-func LSCFInc(b *BlockCabacSE) uint {
+func LSCFInc(b *BlockCabacSE, binIdx uint) uint {
 	cat := b.block.cat
-	if cat.Size() != BlockLevel8 && cat != BlockChromaDC {
-		return b.levelListIdx
-	}
 	if cat == BlockChromaDC {
-		val := b.levelListIdx / b.p.s.h264.SPS.NumC8x8()
-		if val > 2 {
-			return 2
-		}
+		return chromaDCInc(b)
+	}
+	if cat.Size() != BlockLevel8 {
+		return b.levelListIdx
 	}
 
 	return kuiLastSignificantCoeffFlagOffset8x8[b.levelListIdx]
 }
 
+func CALOffset(b *BlockCabacSE) uint {
+	cat := b.block.cat.Num()
+	switch cat {
+	case 5:
+		return 426
+	case 9:
+		return 708
+	case 13:
+		return 766
+	}
+	if cat < 5 {
+		return 227
+	}
+	if cat < 9 {
+		return 952
+	}
+	return 982
+}
+
+/* Clause 9.3.3.1.3 */
+func CALInc(b *BlockCabacSE, binIdx uint) uint {
+	eq1 := b.block.numDecodAbsLevelEq1
+	gt1 := b.block.numDecodAbsLevelGt1
+	if binIdx == 0 {
+		if gt1 != 0 {
+			return 0
+		}
+		if eq1 >= 3 {
+			return 4
+		}
+		return 1 + eq1
+	}
+	maxInc := uint(4)
+	if b.block.cat == BlockChromaDC {
+		maxInc = 3
+	}
+	if gt1 > maxInc {
+		return 5 + maxInc
+	}
+	return 5 + gt1
+}
+
 var CodedBlockFlagSE = NewBFlagSE(kuiCtxIdxBlockCatOffset[0], CBFOffset, CBFInc)
 var SignificantCoeffFlagSE = NewBFlagSE(kuiCtxIdxBlockCatOffset[1], SCFOffset, SCFInc)
 var LastSignificantCoeffFlagSE = NewBFlagSE(kuiCtxIdxBlockCatOffset[2], LSCFOffset, LSCFInc)
+var CoeffAbsLevelMinus1SE = NewBlockSE(1, kuiCtxIdxBlockCatOffset[3], CALOffset, CALInc, UEG0Bin)
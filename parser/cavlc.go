@@ -0,0 +1,319 @@
+package parser
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+func NewCavlcParser(p *H264Parser) *CavlcParser {
+	return &CavlcParser{
+		GolombBitReader: p.GolombBitReader,
+		log:             p.log.Named("CavlcParser"),
+		h264:            p,
+		s:               p.slice,
+		h:               p.slice.h,
+	}
+}
+
+// CavlcParser parses the syntax elements of the slice data if entropy_coding_mode_flag is 0.
+// They are Exp-Golomb or fixed length coded, except for the residual blocks, which use CAVLC (Clause 9.2).
+type CavlcParser struct {
+	*GolombBitReader
+	log  *zap.SugaredLogger
+	h264 *H264Parser
+	h    *SliceHeader
+	s    *SliceParser
+}
+
+// maxVLCLength is the length of the longest code in the tables of Clause 9.2.
+const maxVLCLength = 16
+
+// coeffToken packs the TrailingOnes and TotalCoeff of a coeff_token into one value of kuiCoeffTokenTable.
+func coeffToken(trailingOnes, totalCoeff uint) uint {
+	return totalCoeff<<2 | trailingOnes
+}
+
+// readVLC reads bits until they form one of the codes of tbl, which are given as strings of bits, and returns its value.
+func (p *CavlcParser) readVLC(tbl map[string]uint, name string) uint {
+	code := make([]byte, 0, maxVLCLength)
+	for len(code) < maxVLCLength {
+		code = append(code, '0'+byte(p.ReadBits(1)))
+		if val, ok := tbl[string(code)]; ok {
+			return val
+		}
+	}
+	p.addError(fmt.Errorf("Invalid %s code %s at %d", name, code, p.Position()))
+	return 0
+}
+
+func (p *CavlcParser) ParseMbSkipRun() uint {
+	return p.ReadUE()
+}
+
+func (p *CavlcParser) ParseMBType() uint {
+	return p.ReadUE()
+}
+
+func (p *CavlcParser) ParseSubMbType() uint {
+	return p.ReadUE()
+}
+
+/* Clause 9.1, te(v) with the range num_ref_idx_lX_active_minus1 */
+func (p *CavlcParser) ParseRefIdx(list, mbPartIdx uint) int {
+	if p.s.numRefIdxActiveMinus1(list) == 1 {
+		return int(1 - p.ReadBits(1))
+	}
+	return int(p.ReadUE())
+}
+
+func (p *CavlcParser) ParseMvd(list, mbPartIdx, subMbPartIdx, compIdx uint) int {
+	return p.ReadSE()
+}
+
+func (p *CavlcParser) ParseTransformSize8x8Flag() uint {
+	return p.ReadBits(1)
+}
+
+/* Clause 9.1.2 */
+func (p *CavlcParser) ParseCodedBlockPattern(ChromaArrayType uint) uint {
+	codeNum := p.ReadUE()
+	column := 1
+	if p.s.CurrMb.IsIntra() {
+		column = 0
+	}
+	tbl := kuiCodedBlockPatternMe[column][:]
+	if ChromaArrayType == 0 || ChromaArrayType == 3 {
+		tbl = kuiCodedBlockPatternMeNoChroma[column][:]
+	}
+	if codeNum >= uint(len(tbl)) {
+		p.addError(fmt.Errorf("Invalid coded_block_pattern codeNum %d", codeNum))
+		return 0
+	}
+	return tbl[codeNum]
+}
+
+func (p *CavlcParser) ParseMbQpDelta() int {
+	return p.ReadSE()
+}
+
+func (p *CavlcParser) ParseIntraChromaPredMode() uint {
+	return p.ReadUE()
+}
+
+func (p *CavlcParser) ParsePrevIntraPredModeFlag() uint {
+	return p.ReadBits(1)
+}
+
+func (p *CavlcParser) ParseRemIntraPredMode() uint {
+	return p.ReadBits(3)
+}
+
+func (p *CavlcParser) ParseMbFieldDecodingFlag() uint {
+	return p.ReadBits(1)
+}
+
+/* Clause 7.3.5.3.2 */
+func (p *CavlcParser) ParseResidualBlock(coeffLevel *ResidualBlock, startIdx, endIdx, maxNumCoeff uint) {
+	for i := uint(0); i < maxNumCoeff; i++ {
+		coeffLevel.level[i] = 0
+	}
+	*coeffLevel.codedBlockFlag = 0
+
+	trailingOnes, totalCoeff := p.ParseCoeffToken(coeffLevel)
+	if totalCoeff == 0 {
+		return
+	}
+	if totalCoeff > endIdx-startIdx+1 {
+		p.addError(fmt.Errorf("TotalCoeff %d exceeds the %d coefficients of the block", totalCoeff, endIdx-startIdx+1))
+		return
+	}
+	*coeffLevel.codedBlockFlag = 1
+
+	levelVal := make([]int, totalCoeff)
+	suffixLength := uint(0)
+	if totalCoeff > 10 && trailingOnes < 3 {
+		suffixLength = 1
+	}
+	for i := uint(0); i < totalCoeff; i++ {
+		if i < trailingOnes {
+			trailing_ones_sign_flag := p.ReadBits(1)
+			levelVal[i] = 1 - 2*int(trailing_ones_sign_flag)
+			continue
+		}
+		level_prefix := p.ParseLevelPrefix()
+		levelCode := level_prefix
+		if levelCode > 15 {
+			levelCode = 15
+		}
+		levelCode <<= suffixLength
+		if suffixLength > 0 || level_prefix >= 14 {
+			// Clause 7.4.5.3.2, levelSuffixSize
+			levelSuffixSize := suffixLength
+			if level_prefix == 14 && suffixLength == 0 {
+				levelSuffixSize = 4
+			} else if level_prefix >= 15 {
+				levelSuffixSize = level_prefix - 3
+			}
+			level_suffix := p.ReadBits(uint8(levelSuffixSize))
+			levelCode += level_suffix
+		}
+		if level_prefix >= 15 && suffixLength == 0 {
+			levelCode += 15
+		}
+		if level_prefix >= 16 {
+			levelCode += (1 << (level_prefix - 3)) - 4096
+		}
+		if i == trailingOnes && trailingOnes < 3 {
+			levelCode += 2
+		}
+		if levelCode%2 == 0 {
+			levelVal[i] = int(levelCode+2) >> 1
+		} else {
+			levelVal[i] = (-int(levelCode) - 1) >> 1
+		}
+		if suffixLength == 0 {
+			suffixLength = 1
+		}
+		if (levelVal[i] > 3<<(suffixLength-1) || -levelVal[i] > 3<<(suffixLength-1)) && suffixLength < 6 {
+			suffixLength++
+		}
+	}
+
+	zerosLeft := uint(0)
+	if totalCoeff < endIdx-startIdx+1 {
+		zerosLeft = p.ParseTotalZeros(totalCoeff, maxNumCoeff)
+		if totalCoeff+zerosLeft > endIdx-startIdx+1 {
+			p.addError(fmt.Errorf("total_zeros %d exceeds the %d coefficients of the block", zerosLeft, endIdx-startIdx+1))
+			return
+		}
+	}
+	runVal := make([]uint, totalCoeff)
+	for i := uint(0); i < totalCoeff-1; i++ {
+		if zerosLeft > 0 {
+			runVal[i] = p.ParseRunBefore(zerosLeft)
+		}
+		if runVal[i] > zerosLeft {
+			p.addError(fmt.Errorf("run_before %d exceeds zerosLeft %d", runVal[i], zerosLeft))
+			return
+		}
+		zerosLeft -= runVal[i]
+	}
+	runVal[totalCoeff-1] = zerosLeft
+
+	coeffNum := -1
+	for i := int(totalCoeff) - 1; i >= 0; i-- {
+		coeffNum += int(runVal[i]) + 1
+		coeffLevel.level[startIdx+uint(coeffNum)] = levelVal[i]
+	}
+}
+
+/* Clause 9.2.1 */
+func (p *CavlcParser) ParseCoeffToken(block *ResidualBlock) (trailingOnes, totalCoeff uint) {
+	nC := p.NC(block)
+	column := 0
+	switch {
+	case nC == -1:
+		column = 4
+	case nC == -2:
+		column = 5
+	case nC >= 8:
+		column = 3
+	case nC >= 4:
+		column = 2
+	case nC >= 2:
+		column = 1
+	}
+	val := p.readVLC(kuiCoeffTokenTable[column], "coeff_token")
+	return val & 3, val >> 2
+}
+
+/* Clause 9.2.1 */
+// NC returns nC, which selects the coeff_token table of the block from the number of coefficients of its neighbours.
+func (p *CavlcParser) NC(block *ResidualBlock) int {
+	if block.cat == BlockChromaDC {
+		if p.h264.SPS.ChromaArrayType() == 1 {
+			return -1
+		}
+		return -2
+	}
+	nA, availableA := p.neighTotalCoeff(block, NA)
+	nB, availableB := p.neighTotalCoeff(block, NB)
+	switch {
+	case availableA && availableB:
+		return int(nA+nB+1) >> 1
+	case availableA:
+		return int(nA)
+	case availableB:
+		return int(nB)
+	}
+	return 0
+}
+
+// neighTotalCoeff returns nN, the TotalCoeff of the neighbouring 4x4 block of the block, and whether it is available.
+// Data partitioning is not supported, so constrained_intra_pred_flag has no influence.
+func (p *CavlcParser) neighTotalCoeff(block *ResidualBlock, nt NType) (uint, bool) {
+	cat := block.cat
+	blkIdx := block.blkIdx
+	if cat.FullMB() {
+		// DC blocks take the neighbours of the first 4x4 block
+		blkIdx = 0
+	}
+	// the Cb and Cr blocks of ChromaArrayType 3 are located like luma blocks
+	location := BlockLumaLevel
+	if cat.Chroma() {
+		location = BlockChromaAC
+	}
+	mbAddrN, blkIdxN := p.s.NeighBlocksN(nt, blkIdx, location)
+	if mbAddrN == MBUnavailable || blkIdxN == LumaBlkUnavailable {
+		return 0, false
+	}
+	mb := p.s.MacroBlocks[mbAddrN]
+	if mb.IsSkip() {
+		return 0, true
+	}
+	if mb.IsPCM() {
+		return 16, true
+	}
+	if cat.Chroma() {
+		return mb.chromaResidual[block.iCbCr].GetBlock(BlockAC, uint(blkIdxN)).TotalCoeff(), true
+	}
+	size := BlockLevel
+	if mb.MbPartPredMode(0) == Intra_16x16 {
+		size = BlockAC
+	}
+	return mb.GetResidual(cat).GetBlock(size, uint(blkIdxN)).TotalCoeff(), true
+}
+
+/* Clause 9.2.2.1 */
+func (p *CavlcParser) ParseLevelPrefix() uint {
+	leadingZeroBits := uint(0)
+	for p.ReadBits(1) == 0 {
+		leadingZeroBits++
+		if leadingZeroBits > 32 {
+			p.addError(fmt.Errorf("Invalid level_prefix at %d", p.Position()))
+			break
+		}
+	}
+	return leadingZeroBits
+}
+
+/* Clause 9.2.3 */
+func (p *CavlcParser) ParseTotalZeros(totalCoeff, maxNumCoeff uint) uint {
+	tzVlcIndex := totalCoeff
+	switch maxNumCoeff {
+	case 4:
+		return p.readVLC(kuiTotalZerosChromaDC420[tzVlcIndex-1], "total_zeros")
+	case 8:
+		return p.readVLC(kuiTotalZerosChromaDC422[tzVlcIndex-1], "total_zeros")
+	}
+	return p.readVLC(kuiTotalZeros4x4[tzVlcIndex-1], "total_zeros")
+}
+
+/* Clause 9.2.3 */
+func (p *CavlcParser) ParseRunBefore(zerosLeft uint) uint {
+	if zerosLeft > 7 {
+		zerosLeft = 7
+	}
+	return p.readVLC(kuiRunBefore[zerosLeft-1], "run_before")
+}
@@ -0,0 +1,287 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// code writes a code given as a string of bits.
+func (w *bitWriter) code(bits string) {
+	for _, b := range bits {
+		w.bit(uint(b - '0'))
+	}
+}
+
+// vlcCode returns the code of val in one of the VLC tables of clause 9.2.
+func vlcCode(t *testing.T, tbl map[string]uint, val uint) string {
+	for code, v := range tbl {
+		if v == val {
+			return code
+		}
+	}
+	t.Fatalf("No code for value %d", val)
+	return ""
+}
+
+// cbpCodeNum returns the codeNum of the mapped Exp-Golomb coded_block_pattern.
+func cbpCodeNum(t *testing.T, cbp uint, intra bool) uint {
+	column := 1
+	if intra {
+		column = 0
+	}
+	for codeNum, v := range kuiCodedBlockPatternMe[column] {
+		if v == cbp {
+			return uint(codeNum)
+		}
+	}
+	t.Fatalf("No codeNum for coded_block_pattern %d", cbp)
+	return 0
+}
+
+// cavlcResidualBlock encodes residual_block_cavlc for levels, starting at index 0, with the coeff_token table column of nC.
+func cavlcResidualBlock(t *testing.T, w *bitWriter, levels []int, nC int) {
+	nonZero := []int{}
+	for i := len(levels) - 1; i >= 0; i-- {
+		if levels[i] != 0 {
+			nonZero = append(nonZero, i)
+		}
+	}
+	totalCoeff := uint(len(nonZero))
+	trailingOnes := uint(0)
+	for _, i := range nonZero {
+		if trailingOnes == 3 || (levels[i] != 1 && levels[i] != -1) {
+			break
+		}
+		trailingOnes++
+	}
+
+	column := 0
+	switch {
+	case nC == -1:
+		column = 4
+	case nC == -2:
+		column = 5
+	case nC >= 8:
+		column = 3
+	case nC >= 4:
+		column = 2
+	case nC >= 2:
+		column = 1
+	}
+	w.code(vlcCode(t, kuiCoeffTokenTable[column], coeffToken(trailingOnes, totalCoeff)))
+	if totalCoeff == 0 {
+		return
+	}
+
+	suffixLength := uint(0)
+	if totalCoeff > 10 && trailingOnes < 3 {
+		suffixLength = 1
+	}
+	for n, i := range nonZero {
+		level := levels[i]
+		if uint(n) < trailingOnes {
+			if level < 0 {
+				w.bit(1)
+			} else {
+				w.bit(0)
+			}
+			continue
+		}
+		levelCode := uint(2*level - 2)
+		if level < 0 {
+			levelCode = uint(-2*level - 1)
+		}
+		if uint(n) == trailingOnes && trailingOnes < 3 {
+			levelCode -= 2
+		}
+		switch {
+		case suffixLength == 0 && levelCode < 14:
+			w.bits(1, uint(levelCode)+1)
+		case suffixLength == 0 && levelCode < 30:
+			w.bits(1, 15)
+			w.bits(levelCode-14, 4)
+		case suffixLength == 0:
+			w.bits(1, 16)
+			w.bits(levelCode-30, 12)
+		case levelCode < 15<<suffixLength:
+			w.bits(1, uint(levelCode>>suffixLength)+1)
+			w.bits(levelCode&(1<<suffixLength-1), suffixLength)
+		default:
+			w.bits(1, 16)
+			w.bits(levelCode-15<<suffixLength, 12)
+		}
+		if suffixLength == 0 {
+			suffixLength = 1
+		}
+		if (level > 3<<(suffixLength-1) || -level > 3<<(suffixLength-1)) && suffixLength < 6 {
+			suffixLength++
+		}
+	}
+
+	zerosLeft := uint(nonZero[0]+1) - totalCoeff
+	if totalCoeff < uint(len(levels)) {
+		switch len(levels) {
+		case 4:
+			w.code(vlcCode(t, kuiTotalZerosChromaDC420[totalCoeff-1], zerosLeft))
+		case 8:
+			w.code(vlcCode(t, kuiTotalZerosChromaDC422[totalCoeff-1], zerosLeft))
+		default:
+			w.code(vlcCode(t, kuiTotalZeros4x4[totalCoeff-1], zerosLeft))
+		}
+	}
+	for n := 0; n < len(nonZero)-1 && zerosLeft > 0; n++ {
+		run := uint(nonZero[n] - nonZero[n+1] - 1)
+		tbl := zerosLeft
+		if tbl > 7 {
+			tbl = 7
+		}
+		w.code(vlcCode(t, kuiRunBefore[tbl-1], run))
+		zerosLeft -= run
+	}
+}
+
+func TestCavlcTablesPrefixFree(t *testing.T) {
+	tables := map[string][]map[string]uint{
+		"coeff_token":           kuiCoeffTokenTable[:],
+		"total_zeros":           kuiTotalZeros4x4[:],
+		"total_zeros chroma DC": append(kuiTotalZerosChromaDC420[:], kuiTotalZerosChromaDC422[:]...),
+		"run_before":            kuiRunBefore[:],
+	}
+	for name, tbls := range tables {
+		for i, tbl := range tbls {
+			for a := range tbl {
+				for b := range tbl {
+					if a != b && len(a) < len(b) {
+						assert.NotEqual(t, a, b[:len(a)], "%s table %d: %s is a prefix of %s", name, i, a, b)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCavlcResidualBlock(t *testing.T) {
+	// Example of Richardson, H.264 and MPEG-4 Video Compression, 6.4.13.2
+	w := &bitWriter{}
+	cavlcResidualBlock(t, w, []int{0, 3, 0, 1, -1, -1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}, 0)
+	expected := &bitWriter{}
+	expected.code("000010001110010111101101")
+	assert.Equal(t, expected, w)
+}
+
+// cavlcSliceHeader writes the header of an I slice of an IDR picture or of a P slice with two active reference pictures.
+func cavlcSliceHeader(w *bitWriter, sliceType SliceType, qpDelta int) {
+	// first_mb_in_slice, slice_type, pic_parameter_set_id, frame_num
+	w.ue(0)
+	w.ue(uint(sliceType) + 5)
+	w.ue(0)
+	if sliceType == SliceI {
+		w.bits(0, 4)
+		// idr_pic_id, pic_order_cnt_lsb
+		w.ue(0)
+		w.bits(0, 4)
+		// dec_ref_pic_marking: no_output_of_prior_pics_flag, long_term_reference_flag
+		w.bit(0)
+		w.bit(0)
+	} else {
+		w.bits(1, 4)
+		w.bits(2, 4)
+		// num_ref_idx_active_override_flag, num_ref_idx_l0_active_minus1, ref_pic_list_modification_flag_l0
+		w.bit(1)
+		w.ue(1)
+		w.bit(0)
+		// adaptive_ref_pic_marking_mode_flag
+		w.bit(0)
+	}
+	w.se(qpDelta)
+	// disable_deblocking_filter_idc
+	w.ue(1)
+}
+
+func TestCavlcISlice(t *testing.T) {
+	params := testStreamParams{widthInMbs: 1, heightInMbs: 1}
+	blk0 := []int{0, 3, 0, 1, -1, -1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+	blk3 := []int{-20, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	chromaDC := [2][]int{{1, 0, 0, -3}, {0, 0, 0, 0}}
+
+	w := &bitWriter{}
+	cavlcSliceHeader(w, SliceI, 0)
+	// mb_type I_NxN, prev_intra4x4_pred_mode_flag of all blocks, intra_chroma_pred_mode 1
+	w.ue(0)
+	for blkIdx := 0; blkIdx < 16; blkIdx++ {
+		w.bit(1)
+	}
+	w.ue(1)
+	// coded_block_pattern: luma 0b0001, chroma DC
+	w.ue(cbpCodeNum(t, 1+16, true))
+	// mb_qp_delta
+	w.se(3)
+	// the Richardson example, the blocks next to it use nC 5 and the last one nC 0
+	w.code("000010001110010111101101")
+	cavlcResidualBlock(t, w, make([]int, 16), 5)
+	cavlcResidualBlock(t, w, make([]int, 16), 5)
+	cavlcResidualBlock(t, w, blk3, 0)
+	for iCbCr := 0; iCbCr < 2; iCbCr++ {
+		cavlcResidualBlock(t, w, chromaDC[iCbCr], -1)
+	}
+	w.trailing()
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(3, NALU_IDR, w.bytes()))
+	require.Len(t, p.slice.MacroBlocks, 1)
+	mb := p.slice.MacroBlocks[0]
+	assert.EqualValues(t, I_NxN, mb.mbType)
+	assert.EqualValues(t, 1, mb.intraChromaPredMode)
+	assert.EqualValues(t, 17, mb.codedBlockPattern)
+	assert.Equal(t, 29, mb.qpVal)
+
+	assert.Equal(t, blk0, mb.lumaResidual.GetBlock(BlockLevel, 0).Level())
+	assert.EqualValues(t, 5, mb.lumaResidual.GetBlock(BlockLevel, 0).TotalCoeff())
+	assert.EqualValues(t, 0, mb.lumaResidual.GetBlock(BlockLevel, 1).CodedBlockFlag())
+	assert.Equal(t, blk3, mb.lumaResidual.GetBlock(BlockLevel, 3).Level())
+	for iCbCr := uint(0); iCbCr < 2; iCbCr++ {
+		assert.Equal(t, chromaDC[iCbCr], mb.ChromaResidual(iCbCr).GetBlock(BlockDC, 0).Level()[:4])
+	}
+}
+
+func TestCavlcPSlice(t *testing.T) {
+	params := testStreamParams{widthInMbs: 4, heightInMbs: 1}
+	levels := []int{7, -1, 0, 0, 2, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0}
+
+	w := &bitWriter{}
+	cavlcSliceHeader(w, SliceP, 0)
+	// mb_skip_run 2
+	w.ue(2)
+	// MB 2: P_L0_16x16, ref_idx_l0 1 is te(v) coded with a single inverted bit, mvd_l0 (-4, 9)
+	w.ue(0)
+	w.bit(0)
+	w.se(-4)
+	w.se(9)
+	// coded_block_pattern: luma 0b0100, mb_qp_delta -1
+	w.ue(cbpCodeNum(t, 4, false))
+	w.se(-1)
+	// the block to the left is in the skipped macroblock, the blocks 9 and 10 next to block 8 use nC 2
+	cavlcResidualBlock(t, w, levels, 0)
+	cavlcResidualBlock(t, w, make([]int, 16), 2)
+	cavlcResidualBlock(t, w, make([]int, 16), 2)
+	cavlcResidualBlock(t, w, make([]int, 16), 0)
+	// mb_skip_run 1, the slice ends with the skipped macroblock
+	w.ue(1)
+	w.trailing()
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(2, NALU_NONIDR, w.bytes()))
+	require.Len(t, p.slice.MacroBlocks, 4)
+	for _, addr := range []MBAddr{0, 1, 3} {
+		mb := p.slice.MacroBlocks[addr]
+		assert.True(t, mb.IsSkip(), "macroblock %d", addr)
+		assert.Equal(t, 0, mb.RefIdx(0, 0))
+	}
+	mb := p.slice.MacroBlocks[2]
+	assert.EqualValues(t, P_L0_16x16, mb.mbType)
+	assert.Equal(t, 1, mb.RefIdx(0, 15))
+	assert.Equal(t, [2]int{-4, 9}, mb.Mvd(0, 15))
+	assert.Equal(t, 25, mb.qpVal)
+	assert.Equal(t, levels, mb.lumaResidual.GetBlock(BlockLevel, 8).Level())
+	assert.Equal(t, 25, p.slice.MacroBlocks[3].qpVal)
+}
@@ -106,7 +106,7 @@ func (cat BlockCat) Level4() bool {
 }
 
 func (cat BlockCat) Level8() bool {
-	return cat&(BlockLevel8) != 8
+	return cat&(BlockLevel8) != 0
 }
 
 const (
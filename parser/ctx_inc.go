@@ -10,7 +10,7 @@ func (p *CabacParser) CtxIdxIncPrior(ctxIdxOffset uint, binIdx uint) uint {
 	}
 	b := p.getBin(priorIdx)
 	ret := kuiCtxIdxIncPrior[ctxIdxOffset][b]
-	if ctxIdxOffset == 3 {
+	if ctxIdxOffset == 3 && binIdx == 5 {
 		ret++
 	}
 	return ret
@@ -42,14 +42,11 @@ func (p *CabacParser) CtxIdxIncCondFlagBlk(mbAddr MBAddr, blkIdx LumaBlkIdx, con
 /* Clause 9.3.3.1.1.1 */
 func (p *CabacParser) CtxIdxIncMbSkipFlag() uint {
 	mbAddrA, mbAddrB := p.s.NeighMacroBlock()
-	condTermFlagA := uint(1)
-	condTermFlagB := condTermFlagA
-	if mbAddrA == MBUnavailable {
-		condTermFlagA = 0
-	}
-	if mbAddrB == MBUnavailable {
-		condTermFlagB = 0
+	cond := func(mb *MacroBlock) bool {
+		return !mb.IsSkip()
 	}
+	condTermFlagA := p.CtxIdxIncCondFlag(mbAddrA, cond)
+	condTermFlagB := p.CtxIdxIncCondFlag(mbAddrB, cond)
 	return condTermFlagA + condTermFlagB
 }
 
@@ -61,9 +58,9 @@ func (p *CabacParser) CtxIdxIncMbType(ctxIdxOffset uint) uint {
 		case 0:
 			return false // TODO: MB_TYPE SI
 		case 3:
-			return mb.IMBType().GeneralIType() == G_I_NxN
+			return mb.IMBType().GeneralIType() != G_I_NxN
 		case 27:
-			return false // TODO: MB_TYPE B_Skip / B_Direct_16x16
+			return !mb.IsSkip() && mb.MbPartPredMode(0) != Direct
 		}
 		return false
 	}
@@ -76,7 +73,7 @@ func (p *CabacParser) CtxIdxIncMbType(ctxIdxOffset uint) uint {
 func (p *CabacParser) CtxIdxIncCodedBlockPattern73(binIdx uint) uint {
 	mbAddrA, mbAddrB, lumaBlkIdxA, lumaBlkIdxB := p.s.NeighBlocks(binIdx, BlockLumaLevel8)
 	cond := func(mb *MacroBlock, blkIdx LumaBlkIdx) bool {
-		if mb.IMBType().GeneralIType() == G_I_PCM {
+		if mb.IsPCM() {
 			return false
 		}
 
@@ -84,8 +81,7 @@ func (p *CabacParser) CtxIdxIncCodedBlockPattern73(binIdx uint) uint {
 			return true
 		}
 
-		// TODO: MB_TYPE B_Skip / P_Skip
-		if (mb.CodedBlockPatternLuma()>>uint(blkIdx))&1 != 0 {
+		if !mb.IsSkip() && (mb.CodedBlockPatternLuma()>>uint(blkIdx))&1 != 0 {
 			return false
 		}
 
@@ -103,11 +99,14 @@ func (p *CabacParser) CtxIdxIncCodedBlockPattern73(binIdx uint) uint {
 func (p *CabacParser) CtxIdxIncCodedBlockPattern77(binIdx uint) uint {
 	mbAddrA, mbAddrB := p.s.NeighMacroBlock()
 	cond := func(mb *MacroBlock) bool {
-		if mb.IMBType().GeneralIType() == G_I_PCM {
+		if mb.IsPCM() {
 			return true
 		}
 
-		// TODO: MB_TYPE B_Skip / P_Skip
+		if mb.IsSkip() {
+			return false
+		}
+
 		if mb.CodedBlockPatternChroma() == 0 && binIdx == 0 {
 			return false
 		}
@@ -129,11 +128,10 @@ func (p *CabacParser) CtxIdxIncCodedBlockPattern77(binIdx uint) uint {
 /* Clause 9.3.3.1.1.5 */
 func (p *CabacParser) CtxIdxIncMbQpDelta() uint {
 	return p.CtxIdxIncCondFlag(p.s.PrevMbAddr, func(mb *MacroBlock) bool {
-		// TODO: MB_TYPE P_Skip, B_Skip
-		if mb.IMBType().GeneralIType() == G_I_PCM {
+		if mb.IsSkip() || mb.IsPCM() {
 			return false
 		}
-		if mb.IMBType().MbPartPredMode(0) != Intra_16x16 && mb.CodedBlockPatternChroma() == 0 && mb.CodedBlockPatternLuma() == 0 {
+		if mb.MbPartPredMode(0) != Intra_16x16 && mb.CodedBlockPatternChroma() == 0 && mb.CodedBlockPatternLuma() == 0 {
 			return false
 		}
 		if mb.qpDelta == 0 {
@@ -143,12 +141,66 @@ func (p *CabacParser) CtxIdxIncMbQpDelta() uint {
 	})
 }
 
+// partRef identifies the (sub-)macroblock partition, reference list and motion vector component of a ref_idx or mvd.
+type partRef struct {
+	list         uint
+	mbPartIdx    uint
+	subMbPartIdx uint
+	compIdx      uint
+}
+
+/* Clause 9.3.3.1.1.6, without MBAFF */
+func (p *CabacParser) CtxIdxIncRefIdx() uint {
+	cond := func(nt NType) uint {
+		mbAddrN, blkIdxN := p.s.NeighPartitionN(nt, p.part.mbPartIdx, p.part.subMbPartIdx)
+		if mbAddrN == MBUnavailable {
+			return 0
+		}
+		mb := p.s.MacroBlocks[mbAddrN]
+		if mb.IsSkip() || mb.IsIntra() {
+			return 0
+		}
+		// partitions that do not use the list, are direct predicted or not decoded yet, have a negative reference index
+		if mb.refIdx[p.part.list][blkIdxN] > 0 {
+			return 1
+		}
+		return 0
+	}
+	return cond(NA) + 2*cond(NB)
+}
+
+/* Clause 9.3.3.1.1.7, without MBAFF */
+func (p *CabacParser) CtxIdxIncMvd() uint {
+	absMvdComp := func(nt NType) uint {
+		mbAddrN, blkIdxN := p.s.NeighPartitionN(nt, p.part.mbPartIdx, p.part.subMbPartIdx)
+		if mbAddrN == MBUnavailable {
+			return 0
+		}
+		mb := p.s.MacroBlocks[mbAddrN]
+		if mb.IsSkip() || mb.IsIntra() {
+			return 0
+		}
+		mvd := mb.mvd[p.part.list][blkIdxN][p.part.compIdx]
+		if mvd < 0 {
+			return uint(-mvd)
+		}
+		return uint(mvd)
+	}
+	sum := absMvdComp(NA) + absMvdComp(NB)
+	if sum < 3 {
+		return 0
+	}
+	if sum <= 32 {
+		return 1
+	}
+	return 2
+}
+
 /* Clause 9.3.3.1.1.8 */
 func (p *CabacParser) CtxIdxIncIntraChromaPredMode() uint {
 	mbAddrA, mbAddrB := p.s.NeighMacroBlock()
 	cond := func(mb *MacroBlock) bool {
-		// TODO: Pred Mode
-		if mb.IMBType().GeneralIType() == G_I_PCM || mb.intraChromaPredMode == 0 {
+		if !mb.IsIntra() || mb.IsPCM() || mb.intraChromaPredMode == 0 {
 			return false
 		}
 
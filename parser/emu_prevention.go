@@ -2,15 +2,15 @@ package parser
 
 import (
 	"io"
+	"math/bits"
 
 	"go.uber.org/zap"
 )
 
 func NewEmuPreventionReader(r BitReader, log *zap.SugaredLogger) *EmuPreventionReader {
 	reader := &EmuPreventionReader{
-		r:      r,
-		log:    log.Named("EmuPreventionReader"),
-		bufPos: 3,
+		r:   r,
+		log: log.Named("EmuPreventionReader"),
 	}
 	reader.BitReader = NewBitioReader(reader)
 
@@ -21,36 +21,93 @@ func NewEmuPreventionReader(r BitReader, log *zap.SugaredLogger) *EmuPreventionR
 type EmuPreventionReader struct {
 	// reader using this as an io.Reader
 	BitReader
-	// underlying reader
-	r      BitReader
-	log    *zap.SugaredLogger
-	buf    [3]byte
-	bufPos byte
+	// underlying reader, which returns io.EOF at the end of the NAL unit
+	r   BitReader
+	log *zap.SugaredLogger
+	// number of consecutive zero bytes read
+	zeros int
+
+	// the RBSP of the current NAL unit, read in full on first use so its end is known
+	rbsp   []byte
+	next   int
+	loaded bool
+	// bit position of BitReader at the start of the RBSP
+	start uint
 }
 
 func (r *EmuPreventionReader) Reset() {
-	r.bufPos = 3
+	r.zeros = 0
+	r.rbsp = r.rbsp[:0]
+	r.next = 0
+	r.loaded = false
 }
 
+// load reads the remaining bytes of the current NAL unit into rbsp.
+func (r *EmuPreventionReader) load() {
+	if r.loaded {
+		return
+	}
+	r.loaded = true
+	r.start = r.Position()
+	for {
+		b, err := r.readUnescaped()
+		if err != nil {
+			if err != io.EOF {
+				r.log.Warnf("Failed to read NAL unit: %v", err)
+			}
+			return
+		}
+		r.rbsp = append(r.rbsp, b)
+	}
+}
+
+// ReadByte reads the next byte of the RBSP of the current NAL unit, returning io.EOF at its end.
 func (r *EmuPreventionReader) ReadByte() (b byte, err error) {
-	if r.bufPos == 3 {
-		r.bufPos = 0
-		n, err := r.r.Read(r.buf[:])
-		if err != nil && err != io.EOF {
+	r.load()
+	if r.next >= len(r.rbsp) {
+		return 0, io.EOF
+	}
+	b = r.rbsp[r.next]
+	r.next++
+	return b, nil
+}
+
+/* Clause 7.2 */
+// MoreRBSPData returns whether there is more data before the rbsp_trailing_bits, i.e. whether the last bit equal to 1
+// of the RBSP, the rbsp_stop_one_bit, comes after the current position of BitReader.
+func (r *EmuPreventionReader) MoreRBSPData() bool {
+	r.load()
+	last := len(r.rbsp) - 1
+	for last >= 0 && r.rbsp[last] == 0 {
+		last--
+	}
+	if last < 0 {
+		return false
+	}
+	stopBit := uint(last)*8 + 7 - uint(bits.TrailingZeros8(r.rbsp[last]))
+	return r.Position()-r.start < stopBit
+}
+
+// readUnescaped reads one byte at a time from the underlying reader, skipping emulation_prevention_three_bytes.
+func (r *EmuPreventionReader) readUnescaped() (b byte, err error) {
+	b, err = r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if r.zeros >= 2 && b == 0x03 {
+		// emulation_prevention_three_byte
+		r.zeros = 0
+		b, err = r.r.ReadByte()
+		if err != nil {
 			return 0, err
 		}
-		if n == 0 {
-			return 0, io.EOF
-		}
-		if r.buf == [3]byte{0x00, 0x00, 0x03} {
-			r.bufPos++
-			r.buf = [3]byte{0x00, 0x00, 0x00}
-		}
 	}
-
-	val := r.buf[r.bufPos]
-	r.bufPos++
-	return val, nil
+	if b == 0 {
+		r.zeros++
+	} else {
+		r.zeros = 0
+	}
+	return b, nil
 }
 
 func (r *EmuPreventionReader) Read(b []byte) (n int, err error) {
@@ -0,0 +1,461 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// bitWriter writes the RBSP of test NAL units.
+type bitWriter struct {
+	buf []byte
+	cur byte
+	n   uint
+}
+
+func (w *bitWriter) bit(b uint) {
+	w.cur = w.cur<<1 | byte(b&1)
+	w.n++
+	if w.n == 8 {
+		w.buf = append(w.buf, w.cur)
+		w.cur = 0
+		w.n = 0
+	}
+}
+
+func (w *bitWriter) bits(v uint, n uint) {
+	for i := n; i > 0; i-- {
+		w.bit(v >> (i - 1))
+	}
+}
+
+func (w *bitWriter) ue(v uint) {
+	v++
+	n := uint(0)
+	for (v >> n) > 1 {
+		n++
+	}
+	w.bits(0, n)
+	w.bits(v, n+1)
+}
+
+func (w *bitWriter) se(v int) {
+	if v > 0 {
+		w.ue(uint(2*v - 1))
+	} else {
+		w.ue(uint(-2 * v))
+	}
+}
+
+func (w *bitWriter) aligned() bool {
+	return w.n == 0
+}
+
+// alignOnes writes cabac_alignment_one_bit until the next byte boundary.
+func (w *bitWriter) alignOnes() {
+	for !w.aligned() {
+		w.bit(1)
+	}
+}
+
+// trailing writes the rbsp_trailing_bits.
+func (w *bitWriter) trailing() {
+	w.bit(1)
+	for !w.aligned() {
+		w.bit(0)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// nalUnit returns the NAL unit with a start code and emulation prevention bytes.
+func nalUnit(refIdc uint, typ NALUType, rbsp []byte) []byte {
+	ret := []byte{0, 0, 0, 1, byte(refIdc<<5) | byte(typ)}
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 3 {
+			ret = append(ret, 3)
+			zeros = 0
+		}
+		ret = append(ret, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return ret
+}
+
+// testStreamParams describes the parameter sets of a test stream.
+type testStreamParams struct {
+	widthInMbs  uint
+	heightInMbs uint
+	cabac       bool
+	// pic_order_cnt_type 1 instead of 0
+	picOrderCntType1 bool
+	// field pictures, without MBAFF
+	fields bool
+	// frames with macroblock-adaptive frame/field decoding
+	mbaff bool
+	// bottom_field_pic_order_in_frame_present_flag, weighted_pred_flag and redundant_pic_cnt_present_flag
+	picOrderPresent bool
+	weightedPred    bool
+	redundantPicCnt bool
+}
+
+func flag(b bool) uint {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// sps returns a Main profile SPS.
+func (t testStreamParams) sps() []byte {
+	w := &bitWriter{}
+	w.bits(77, 8)
+	w.bits(0, 8)
+	w.bits(30, 8)
+	w.ue(0)
+	// log2_max_frame_num_minus4, pic_order_cnt_type, log2_max_pic_order_cnt_lsb_minus4
+	w.ue(0)
+	if t.picOrderCntType1 {
+		// delta_pic_order_always_zero_flag, offset_for_non_ref_pic, offset_for_top_to_bottom_field, offset_for_ref_frame
+		w.ue(1)
+		w.bit(0)
+		w.se(-1)
+		w.se(1)
+		w.ue(1)
+		w.se(2)
+	} else {
+		w.ue(0)
+		w.ue(0)
+	}
+	// max_num_ref_frames, gaps_in_frame_num_value_allowed_flag
+	w.ue(2)
+	w.bit(0)
+	w.ue(t.widthInMbs - 1)
+	w.ue(t.heightInMbs - 1)
+	// frame_mbs_only_flag, mb_adaptive_frame_field_flag, direct_8x8_inference_flag, frame_cropping_flag, vui_parameters_present_flag
+	w.bit(1 - flag(t.fields || t.mbaff))
+	if t.fields || t.mbaff {
+		w.bit(flag(t.mbaff))
+	}
+	w.bit(1)
+	w.bit(0)
+	w.bit(0)
+	w.trailing()
+	return nalUnit(3, NALU_SPS, w.bytes())
+}
+
+func (t testStreamParams) pps() []byte {
+	w := &bitWriter{}
+	w.ue(0)
+	w.ue(0)
+	w.bit(flag(t.cabac))
+	// bottom_field_pic_order_in_frame_present_flag, num_slice_groups_minus1
+	w.bit(flag(t.picOrderPresent))
+	w.ue(0)
+	// num_ref_idx_l0/l1_default_active_minus1
+	w.ue(0)
+	w.ue(0)
+	// weighted_pred_flag, weighted_bipred_idc
+	w.bit(flag(t.weightedPred))
+	w.bits(0, 2)
+	// pic_init_qp_minus26, pic_init_qs_minus26, chroma_qp_index_offset
+	w.se(0)
+	w.se(0)
+	w.se(0)
+	// deblocking_filter_control_present_flag, constrained_intra_pred_flag, redundant_pic_cnt_present_flag
+	w.bit(1)
+	w.bit(0)
+	w.bit(flag(t.redundantPicCnt))
+	// transform_8x8_mode_flag, pic_scaling_matrix_present_flag, second_chroma_qp_index_offset
+	w.bit(0)
+	w.bit(0)
+	w.se(0)
+	w.trailing()
+	return nalUnit(3, NALU_PPS, w.bytes())
+}
+
+// parseTestStream parses the NAL units and returns the parser, failing on any error.
+func parseTestStream(t *testing.T, nalus ...[]byte) *H264Parser {
+	buf := &bytes.Buffer{}
+	for _, nalu := range nalus {
+		buf.Write(nalu)
+	}
+	p := NewH264Parser(buf, zap.NewNop().Sugar())
+	p.Parse()
+	require.NoError(t, p.ErrorOrNil())
+	return p
+}
+
+// cabacEncoder is the arithmetic encoder of clause 9.3.4.2, for writing test slices.
+type cabacEncoder struct {
+	w           *bitWriter
+	contexts    [WELS_CONTEXT_COUNT]CabacElement
+	low         uint
+	rng         uint
+	firstBit    bool
+	outstanding int
+}
+
+// newCabacEncoder initializes the context variables like the decoder does, for the given slice.
+func newCabacEncoder(w *bitWriter, sliceQP int, sliceType SliceType, cabacInitIdc uint) *cabacEncoder {
+	c := &CabacParser{h: &SliceHeader{PPS: &PPSInfo{}, SliceType: uint(sliceType), SliceQPDelta: sliceQP - 26, CabacInitIdc: cabacInitIdc}}
+	c.Initialize()
+	return &cabacEncoder{
+		w:        w,
+		contexts: c.contexts,
+		rng:      510,
+		firstBit: true,
+	}
+}
+
+func (e *cabacEncoder) putBit(b uint) {
+	if e.firstBit {
+		e.firstBit = false
+	} else {
+		e.w.bit(b)
+	}
+	for ; e.outstanding > 0; e.outstanding-- {
+		e.w.bit(1 - b)
+	}
+}
+
+func (e *cabacEncoder) renorm() {
+	for e.rng < 256 {
+		if e.low < 256 {
+			e.putBit(0)
+		} else if e.low >= 512 {
+			e.low -= 512
+			e.putBit(1)
+		} else {
+			e.low -= 256
+			e.outstanding++
+		}
+		e.rng <<= 1
+		e.low <<= 1
+	}
+}
+
+func (e *cabacEncoder) decision(ctxIdx uint, bin uint) {
+	ctx := &e.contexts[ctxIdx]
+	lps := uint(g_kuiCabacRangeLps[ctx.uiState][(e.rng>>6)&3])
+	e.rng -= lps
+	if bin != ctx.uiMPS {
+		e.low += e.rng
+		e.rng = lps
+		if ctx.uiState == 0 {
+			ctx.uiMPS = 1 - ctx.uiMPS
+		}
+		ctx.uiState = uint(g_kuiStateTransTable[ctx.uiState][0])
+	} else {
+		ctx.uiState = uint(g_kuiStateTransTable[ctx.uiState][1])
+	}
+	e.renorm()
+}
+
+func (e *cabacEncoder) bypass(bin uint) {
+	e.low <<= 1
+	if bin != 0 {
+		e.low += e.rng
+	}
+	if e.low >= 1024 {
+		e.putBit(1)
+		e.low -= 1024
+	} else if e.low < 512 {
+		e.putBit(0)
+	} else {
+		e.low -= 512
+		e.outstanding++
+	}
+}
+
+// terminate encodes a bin with ctxIdx 276. Encoding a 1 flushes the encoder, including the rbsp_stop_one_bit,
+// and aligns the RBSP.
+func (e *cabacEncoder) terminate(bin uint) {
+	e.rng -= 2
+	if bin == 0 {
+		e.renorm()
+		return
+	}
+	e.low += e.rng
+	e.rng = 2
+	e.renorm()
+	e.putBit((e.low >> 9) & 1)
+	e.w.bits(((e.low>>7)&3)|1, 2)
+	for !e.w.aligned() {
+		e.w.bit(0)
+	}
+}
+
+// bins encodes the bins with their context indices.
+func (e *cabacEncoder) bins(bins []uint, ctxIdx []uint) {
+	for i, b := range bins {
+		e.decision(ctxIdx[i], b)
+	}
+}
+
+// uegk encodes the UEGk binarization of val, with the prefix bins using the context returned by ctx.
+func (e *cabacEncoder) uegk(val uint, k uint, uCoff uint, ctx func(binIdx uint) uint) {
+	prefix := val
+	if prefix > uCoff {
+		prefix = uCoff
+	}
+	for i := uint(0); i < prefix; i++ {
+		e.decision(ctx(i), 1)
+	}
+	if prefix < uCoff {
+		e.decision(ctx(prefix), 0)
+		return
+	}
+	suf := val - uCoff
+	for {
+		if suf >= 1<<k {
+			e.bypass(1)
+			suf -= 1 << k
+			k++
+			continue
+		}
+		e.bypass(0)
+		for ; k > 0; k-- {
+			e.bypass((suf >> (k - 1)) & 1)
+		}
+		return
+	}
+}
+
+// refIdx encodes the unary binarization of ref_idx, inc0 is the ctxIdxInc of the first bin.
+func (e *cabacEncoder) refIdx(val uint, inc0 uint) {
+	ctx := func(binIdx uint) uint {
+		switch binIdx {
+		case 0:
+			return 54 + inc0
+		case 1:
+			return 58
+		}
+		return 59
+	}
+	for i := uint(0); i < val; i++ {
+		e.decision(ctx(i), 1)
+	}
+	e.decision(ctx(val), 0)
+}
+
+// mvd encodes the UEG3 binarization and sign of a mvd component, ctxIdxOffset is 40 or 47 and inc0 the ctxIdxInc of the first bin.
+func (e *cabacEncoder) mvd(val int, ctxIdxOffset uint, inc0 uint) {
+	abs := val
+	if abs < 0 {
+		abs = -abs
+	}
+	e.uegk(uint(abs), 3, 9, func(binIdx uint) uint {
+		if binIdx == 0 {
+			return ctxIdxOffset + inc0
+		}
+		if binIdx >= 4 {
+			return ctxIdxOffset + 6
+		}
+		return ctxIdxOffset + binIdx + 2
+	})
+	if abs != 0 {
+		if val < 0 {
+			e.bypass(1)
+		} else {
+			e.bypass(0)
+		}
+	}
+}
+
+// Table 9-34 and 9-40, frame coded blocks with ctxBlockCat < 5
+var testCtxBlockCatOffset = [3][5]uint{
+	{0, 4, 8, 12, 16},
+	{0, 15, 29, 44, 47},
+	{0, 10, 20, 30, 39},
+}
+
+// residualBlock encodes residual_block_cabac for levels, starting at index 0.
+// cbfInc is the ctxIdxInc of the coded_block_flag, computed by the caller from the neighbouring blocks.
+func (e *cabacEncoder) residualBlock(levels []int, ctxBlockCat uint, cbfInc uint) {
+	last := -1
+	for i, l := range levels {
+		if l != 0 {
+			last = i
+		}
+	}
+	coded := uint(0)
+	if last >= 0 {
+		coded = 1
+	}
+	e.decision(85+testCtxBlockCatOffset[0][ctxBlockCat]+cbfInc, coded)
+	if coded == 0 {
+		return
+	}
+
+	sigInc := func(i int) uint {
+		if ctxBlockCat == 3 {
+			// 4:2:0, NumC8x8 is 1
+			if i > 2 {
+				return 2
+			}
+		}
+		return uint(i)
+	}
+	for i := 0; i < len(levels)-1; i++ {
+		sig := uint(0)
+		if levels[i] != 0 {
+			sig = 1
+		}
+		e.decision(105+testCtxBlockCatOffset[1][ctxBlockCat]+sigInc(i), sig)
+		if sig == 0 {
+			continue
+		}
+		if i == last {
+			e.decision(166+testCtxBlockCatOffset[1][ctxBlockCat]+sigInc(i), 1)
+			break
+		}
+		e.decision(166+testCtxBlockCatOffset[1][ctxBlockCat]+sigInc(i), 0)
+	}
+
+	eq1, gt1 := uint(0), uint(0)
+	base := 227 + testCtxBlockCatOffset[2][ctxBlockCat]
+	maxGt1 := uint(4)
+	if ctxBlockCat == 3 {
+		maxGt1 = 3
+	}
+	for i := last; i >= 0; i-- {
+		if levels[i] == 0 {
+			continue
+		}
+		abs, sign := levels[i], uint(0)
+		if abs < 0 {
+			abs, sign = -abs, 1
+		}
+		e.uegk(uint(abs-1), 0, 14, func(binIdx uint) uint {
+			if binIdx == 0 {
+				if gt1 != 0 {
+					return base
+				}
+				if 1+eq1 > 4 {
+					return base + 4
+				}
+				return base + 1 + eq1
+			}
+			if gt1 > maxGt1 {
+				return base + 5 + maxGt1
+			}
+			return base + 5 + gt1
+		})
+		e.bypass(sign)
+		if abs == 1 {
+			eq1++
+		} else {
+			gt1++
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 
 	"github.com/hashicorp/go-multierror"
@@ -59,8 +61,12 @@ func (p *H264Parser) Parse() {
 			pps := p.pps.ParseInfo()
 			p.PPSInfos[pps.Id] = pps
 			p.log.Debugf("Parsed PPS: %+v", pps)
-		case NALU_IDR:
-			hdr := p.slice.ParseHeader()
+		case NALU_IDR, NALU_NONIDR:
+			hdr := p.slice.ParseHeader(info)
+			if hdr == nil {
+				p.log.Debugf("Skipping slice without parameter sets")
+				break
+			}
 			p.log.Debugf("Parsed Slice Header: %+v", hdr)
 			data := p.slice.ParseSliceData()
 			p.log.Debugf("Parsed Slice Data: %+v", data)
@@ -71,6 +77,52 @@ func (p *H264Parser) Parse() {
 	}
 }
 
+// ErrorOrNil returns the errors encountered during parsing, or nil if there were none.
+func (p *H264Parser) ErrorOrNil() error {
+	if err, ok := p.Error().(*multierror.Error); ok {
+		return err.ErrorOrNil()
+	}
+	return p.Error()
+}
+
 func (p *H264Parser) Error() error {
 	return multierror.Append(multierror.Prefix(p.err, "H264Parser Errors"), multierror.Prefix(p.sps.err, "SPSParser Errors"), multierror.Prefix(p.pps.err, "PPSParser Errors"), multierror.Prefix(p.slice.err, "SliceParser Errors"), p.annexb.Error())
 }
+
+// ParseParameterSets parses the SPS and PPS NAL units (without start codes) in nalus, all other NAL units are ignored.
+func ParseParameterSets(nalus [][]byte, log *zap.SugaredLogger) (*H264Parser, error) {
+	buf := &bytes.Buffer{}
+	for _, nalu := range nalus {
+		if len(nalu) == 0 {
+			continue
+		}
+		t := NALUType(nalu[0] & 0x1f)
+		if t == NALU_SPS || t == NALU_PPS {
+			buf.Write([]byte{0, 0, 0, 1})
+			buf.Write(nalu)
+		}
+	}
+	p := NewH264Parser(buf, log)
+	p.Parse()
+	if p.SPS == nil {
+		return p, fmt.Errorf("No SPS found")
+	}
+	return p, p.ErrorOrNil()
+}
+
+// SlicePPSId returns the pic_parameter_set_id of the slice in nalu, a NAL unit without start code.
+func SlicePPSId(nalu []byte) (uint, error) {
+	if len(nalu) < 2 {
+		return 0, fmt.Errorf("Slice NAL unit of %d bytes is too short", len(nalu))
+	}
+	if t := NALUType(nalu[0] & 0x1f); t != NALU_IDR && t != NALU_NONIDR {
+		return 0, fmt.Errorf("Expected a slice NAL unit, got %s", t)
+	}
+	emuR := NewEmuPreventionReader(NewBitioReader(bytes.NewReader(nalu[1:])), zap.NewNop().Sugar())
+	r := NewGolombReader(emuR)
+	// first_mb_in_slice, slice_type
+	r.ReadUE()
+	r.ReadUE()
+	id := r.ReadUE()
+	return id, r.Error()
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/galli-leo/gozoom/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseParameterSets(t *testing.T) {
+	p, err := ParseParameterSets([][]byte{testdata.SPS, testdata.PPS}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	assert.EqualValues(t, 100, p.SPS.ProfileIdc)
+	assert.EqualValues(t, 31, p.SPS.LevelIdc)
+	assert.EqualValues(t, 1280, p.SPS.FrameWidth())
+	assert.EqualValues(t, 720, p.SPS.FrameHeight())
+
+	require.Contains(t, p.PPSInfos, uint(0))
+	assert.EqualValues(t, 1, p.PPSInfos[0].EntropyCodingModeFlag)
+}
+
+func TestSlicePPSId(t *testing.T) {
+	// a first_mb_in_slice with 22 leading zero bits needs an emulation_prevention_three_byte
+	w := &bitWriter{}
+	w.ue(1<<22 - 1)
+	w.ue(uint(SliceP))
+	w.ue(5)
+	w.trailing()
+	nalu := nalUnit(2, NALU_NONIDR, w.bytes())[4:]
+	require.Equal(t, []byte{0, 0, 3, 2}, nalu[1:5])
+
+	id, err := SlicePPSId(nalu)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, id)
+
+	_, err = SlicePPSId(testdata.PPS)
+	assert.Error(t, err)
+}
+
+func TestParseSliceWithoutParameterSets(t *testing.T) {
+	params := testStreamParams{widthInMbs: 1, heightInMbs: 1}
+	w := &bitWriter{}
+	cavlcSliceHeader(w, SliceP, 0)
+	w.ue(1)
+	w.trailing()
+	slice := nalUnit(2, NALU_NONIDR, w.bytes())
+
+	for name, nalus := range map[string][][]byte{
+		"no PPS": {slice},
+		"no SPS": {params.pps(), slice},
+	} {
+		buf := &bytes.Buffer{}
+		for _, nalu := range nalus {
+			buf.Write(nalu)
+		}
+		p := NewH264Parser(buf, zap.NewNop().Sugar())
+		assert.NotPanics(t, p.Parse, name)
+		assert.Error(t, p.ErrorOrNil(), name)
+	}
+}
+
+func TestParseMbaffSlice(t *testing.T) {
+	params := testStreamParams{widthInMbs: 1, heightInMbs: 1, mbaff: true}
+	w := &bitWriter{}
+	// first_mb_in_slice, slice_type I, pic_parameter_set_id, frame_num, field_pic_flag
+	w.ue(0)
+	w.ue(uint(SliceI) + 5)
+	w.ue(0)
+	w.bits(0, 4)
+	w.bit(0)
+	// idr_pic_id, pic_order_cnt_lsb, dec_ref_pic_marking, slice_qp_delta, disable_deblocking_filter_idc
+	w.ue(0)
+	w.bits(0, 4)
+	w.bits(0, 2)
+	w.se(0)
+	w.ue(1)
+	// mb_field_decoding_flag, which would be misread as the mb_type of a frame macroblock
+	w.bit(1)
+	w.ue(0)
+	w.trailing()
+
+	buf := &bytes.Buffer{}
+	for _, nalu := range [][]byte{params.sps(), params.pps(), nalUnit(3, NALU_IDR, w.bytes())} {
+		buf.Write(nalu)
+	}
+	p := NewH264Parser(buf, zap.NewNop().Sugar())
+	p.Parse()
+	require.Error(t, p.ErrorOrNil())
+	assert.Contains(t, p.ErrorOrNil().Error(), "MBAFF")
+	assert.EqualValues(t, 1, p.slice.h.MbaffFrameFlag())
+}
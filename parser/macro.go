@@ -77,6 +77,12 @@ func NewMacroBlock() *MacroBlock {
 	mb.crResidual.cat = BlockCr
 	mb.chromaResidual[0].cat = BlockChroma
 	mb.chromaResidual[1].cat = BlockChroma
+	mb.chromaResidual[1].iCbCr = 1
+	for list := range mb.refIdx {
+		for blkIdx := range mb.refIdx[list] {
+			mb.refIdx[list][blkIdx] = -1
+		}
+	}
 	return mb
 }
 
@@ -85,6 +91,7 @@ type MacroBlock struct {
 	sliceHdr *SliceHeader
 	qpVal    int
 
+	mbSkipFlag           uint
 	mbType               uint
 	qpDelta              int
 	transformSize8x8Flag uint
@@ -97,24 +104,81 @@ type MacroBlock struct {
 	prevIntraPredModeFlag []uint
 	remIntraPredMode      []uint
 	intraChromaPredMode   uint
+	// Clause 7.4.5.1 and 7.4.5.2, the reference indices and motion vector differences are stored
+	// for every 4x4 luma block of the partition they belong to. The reference index is -1 if the list is not used.
+	subMbType [4]uint
+	refIdx    [2][16]int
+	mvd       [2][16][2]int
 
 	// Residual block Semantics
 	lumaResidual   ResidualData
 	chromaResidual [2]ResidualData
 	cbResidual     ResidualData
 	crResidual     ResidualData
-	// Clause 7.4.5.3.3
-	codedBlockFlag           uint
+	// Clause 7.4.5.3.3, in decoding order
 	significantCoeffFlag     []uint
 	lastSignificantCoeffFlag []uint
 	coeffAbsLevelMinus1      []uint
 	coeffSignFlag            []uint
 }
 
+// IMBType describes intra macroblocks, it must only be used if IsIntra is true.
 func (mb *MacroBlock) IMBType() IMBType {
 	return NewIMBType(mb)
 }
 
+// PMBType describes inter macroblocks, it must only be used if IsIntra is false.
+func (mb *MacroBlock) PMBType() PMBType {
+	return NewPMBType(mb)
+}
+
+func (mb *MacroBlock) SubMBType(mbPartIdx uint) SubMBType {
+	return SubMBType{mb, mbPartIdx}
+}
+
+// intraMbTypeOffset returns the first mb_type value of intra macroblocks in the slice of mb.
+func (mb *MacroBlock) intraMbTypeOffset() uint {
+	switch mb.sliceHdr.Type() {
+	case SliceP, SliceSP:
+		return NumPMBTypes
+	case SliceB:
+		return NumBMBTypes
+	case SliceSI:
+		// SI is the first type of SI slices, followed by the I types
+		return 1
+	}
+	return 0
+}
+
+func (mb *MacroBlock) IsSkip() bool {
+	return mb.mbSkipFlag != 0
+}
+
+func (mb *MacroBlock) IsIntra() bool {
+	return !mb.IsSkip() && mb.mbType >= mb.intraMbTypeOffset()
+}
+
+func (mb *MacroBlock) IsPCM() bool {
+	return mb.IsIntra() && mb.IMBType().GeneralIType() == G_I_PCM
+}
+
+func (mb *MacroBlock) MbPartPredMode(mbPartIdx uint) MBPartPredMode {
+	if mb.IsIntra() {
+		return mb.IMBType().MbPartPredMode(mbPartIdx)
+	}
+	return mb.PMBType().MbPartPredMode(mbPartIdx)
+}
+
+// RefIdx returns the reference index into list of the partition containing the 4x4 luma block, -1 if it does not use the list.
+func (mb *MacroBlock) RefIdx(list uint, luma4x4BlkIdx uint) int {
+	return mb.refIdx[list][luma4x4BlkIdx]
+}
+
+// Mvd returns the horizontal and vertical motion vector difference of the partition containing the 4x4 luma block.
+func (mb *MacroBlock) Mvd(list uint, luma4x4BlkIdx uint) [2]int {
+	return mb.mvd[list][luma4x4BlkIdx]
+}
+
 func (mb *MacroBlock) CodedBlockPatternLuma() uint {
 	return mb.codedBlockPattern % 16
 }
@@ -128,6 +192,11 @@ func (mb *MacroBlock) Position() (uint, uint) {
 	return uint(mb.Addr) % sps.PicWidthInMbs(), uint(mb.Addr) / sps.PicWidthInMbs()
 }
 
+// ChromaResidual returns the residual of the Cb (iCbCr 0) or Cr (iCbCr 1) component, when ChromaArrayType is 1 or 2.
+func (mb *MacroBlock) ChromaResidual(iCbCr uint) *ResidualData {
+	return &mb.chromaResidual[iCbCr]
+}
+
 func (mb *MacroBlock) GetResidual(cat BlockCat) *ResidualData {
 	if cat.Luma() {
 		return &mb.lumaResidual
@@ -153,5 +222,9 @@ func (mb *MacroBlock) LumaPosition() (uint, uint) {
 
 func (mb *MacroBlock) String() string {
 	x, y := mb.Position()
-	return fmt.Sprintf("<MB %s @ %d %d (%d)>", mb.IMBType(), x, y, mb.qpVal)
+	var t interface{} = mb.PMBType()
+	if mb.IsIntra() {
+		t = mb.IMBType()
+	}
+	return fmt.Sprintf("<MB %s @ %d %d (%d)>", t, x, y, mb.qpVal)
 }
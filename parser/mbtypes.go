@@ -50,7 +50,7 @@ const (
 )
 
 func (i *IMBTypeImpl) typeConst() IMBTypeConst {
-	return IMBTypeConst(i.mb.mbType)
+	return IMBTypeConst(i.mb.mbType - i.mb.intraMbTypeOffset())
 }
 
 func (i *IMBTypeImpl) MbPartPredMode(mbPartIdx uint) MBPartPredMode {
@@ -134,3 +134,295 @@ func (i *IMBTypeImpl) String() string {
 	}
 	return fmt.Sprintf("%s (%d)", t, i.typeConst().MBType())
 }
+
+type PMBTypeConst uint
+
+/* Table 7-13 */
+const (
+	P_L0_16x16 PMBTypeConst = iota
+	P_L0_L0_16x8
+	P_L0_L0_8x16
+	P_8x8
+	P_8x8ref0
+	// P_Skip has no mb_type value, it is inferred when mb_skip_flag is set
+	P_Skip
+)
+
+// NumPMBTypes is the number of inter mb_type values in P slices, the values after them are the intra types of Table 7-11.
+const NumPMBTypes = 5
+
+func (t PMBTypeConst) MBType() uint {
+	return uint(t)
+}
+
+type mbTypeInfo struct {
+	numMbPart uint
+	predMode  [2]MBPartPredMode
+	width     uint
+	height    uint
+}
+
+var pMbTypes = map[PMBTypeConst]mbTypeInfo{
+	P_L0_16x16:   {1, [2]MBPartPredMode{Pred_L0, PartPred_Unknown}, 16, 16},
+	P_L0_L0_16x8: {2, [2]MBPartPredMode{Pred_L0, Pred_L0}, 16, 8},
+	P_L0_L0_8x16: {2, [2]MBPartPredMode{Pred_L0, Pred_L0}, 8, 16},
+	P_8x8:        {4, [2]MBPartPredMode{PartPred_Unknown, PartPred_Unknown}, 8, 8},
+	P_8x8ref0:    {4, [2]MBPartPredMode{PartPred_Unknown, PartPred_Unknown}, 8, 8},
+	P_Skip:       {1, [2]MBPartPredMode{Pred_L0, PartPred_Unknown}, 16, 16},
+}
+
+type BMBTypeConst uint
+
+/* Table 7-14 */
+const (
+	B_Direct_16x16 BMBTypeConst = iota
+	B_L0_16x16
+	B_L1_16x16
+	B_Bi_16x16
+	B_L0_L0_16x8
+	B_L0_L0_8x16
+	B_L1_L1_16x8
+	B_L1_L1_8x16
+	B_L0_L1_16x8
+	B_L0_L1_8x16
+	B_L1_L0_16x8
+	B_L1_L0_8x16
+	B_L0_Bi_16x8
+	B_L0_Bi_8x16
+	B_L1_Bi_16x8
+	B_L1_Bi_8x16
+	B_Bi_L0_16x8
+	B_Bi_L0_8x16
+	B_Bi_L1_16x8
+	B_Bi_L1_8x16
+	B_Bi_Bi_16x8
+	B_Bi_Bi_8x16
+	B_8x8
+	// B_Skip has no mb_type value, it is inferred when mb_skip_flag is set
+	B_Skip
+)
+
+// NumBMBTypes is the number of inter mb_type values in B slices, the values after them are the intra types of Table 7-11.
+const NumBMBTypes = 23
+
+func (t BMBTypeConst) MBType() uint {
+	return uint(t)
+}
+
+var bMbTypes = map[BMBTypeConst]mbTypeInfo{
+	B_Direct_16x16: {0, [2]MBPartPredMode{Direct, PartPred_Unknown}, 8, 8},
+	B_L0_16x16:     {1, [2]MBPartPredMode{Pred_L0, PartPred_Unknown}, 16, 16},
+	B_L1_16x16:     {1, [2]MBPartPredMode{Pred_L1, PartPred_Unknown}, 16, 16},
+	B_Bi_16x16:     {1, [2]MBPartPredMode{BiPred, PartPred_Unknown}, 16, 16},
+	B_L0_L0_16x8:   {2, [2]MBPartPredMode{Pred_L0, Pred_L0}, 16, 8},
+	B_L0_L0_8x16:   {2, [2]MBPartPredMode{Pred_L0, Pred_L0}, 8, 16},
+	B_L1_L1_16x8:   {2, [2]MBPartPredMode{Pred_L1, Pred_L1}, 16, 8},
+	B_L1_L1_8x16:   {2, [2]MBPartPredMode{Pred_L1, Pred_L1}, 8, 16},
+	B_L0_L1_16x8:   {2, [2]MBPartPredMode{Pred_L0, Pred_L1}, 16, 8},
+	B_L0_L1_8x16:   {2, [2]MBPartPredMode{Pred_L0, Pred_L1}, 8, 16},
+	B_L1_L0_16x8:   {2, [2]MBPartPredMode{Pred_L1, Pred_L0}, 16, 8},
+	B_L1_L0_8x16:   {2, [2]MBPartPredMode{Pred_L1, Pred_L0}, 8, 16},
+	B_L0_Bi_16x8:   {2, [2]MBPartPredMode{Pred_L0, BiPred}, 16, 8},
+	B_L0_Bi_8x16:   {2, [2]MBPartPredMode{Pred_L0, BiPred}, 8, 16},
+	B_L1_Bi_16x8:   {2, [2]MBPartPredMode{Pred_L1, BiPred}, 16, 8},
+	B_L1_Bi_8x16:   {2, [2]MBPartPredMode{Pred_L1, BiPred}, 8, 16},
+	B_Bi_L0_16x8:   {2, [2]MBPartPredMode{BiPred, Pred_L0}, 16, 8},
+	B_Bi_L0_8x16:   {2, [2]MBPartPredMode{BiPred, Pred_L0}, 8, 16},
+	B_Bi_L1_16x8:   {2, [2]MBPartPredMode{BiPred, Pred_L1}, 16, 8},
+	B_Bi_L1_8x16:   {2, [2]MBPartPredMode{BiPred, Pred_L1}, 8, 16},
+	B_Bi_Bi_16x8:   {2, [2]MBPartPredMode{BiPred, BiPred}, 16, 8},
+	B_Bi_Bi_8x16:   {2, [2]MBPartPredMode{BiPred, BiPred}, 8, 16},
+	B_8x8:          {4, [2]MBPartPredMode{PartPred_Unknown, PartPred_Unknown}, 8, 8},
+	B_Skip:         {0, [2]MBPartPredMode{Direct, PartPred_Unknown}, 8, 8},
+}
+
+var bMbTypeNames = map[BMBTypeConst]string{
+	B_Direct_16x16: "B_Direct_16x16",
+	B_L0_16x16:     "B_L0_16x16",
+	B_L1_16x16:     "B_L1_16x16",
+	B_Bi_16x16:     "B_Bi_16x16",
+	B_L0_L0_16x8:   "B_L0_L0_16x8",
+	B_L0_L0_8x16:   "B_L0_L0_8x16",
+	B_L1_L1_16x8:   "B_L1_L1_16x8",
+	B_L1_L1_8x16:   "B_L1_L1_8x16",
+	B_L0_L1_16x8:   "B_L0_L1_16x8",
+	B_L0_L1_8x16:   "B_L0_L1_8x16",
+	B_L1_L0_16x8:   "B_L1_L0_16x8",
+	B_L1_L0_8x16:   "B_L1_L0_8x16",
+	B_L0_Bi_16x8:   "B_L0_Bi_16x8",
+	B_L0_Bi_8x16:   "B_L0_Bi_8x16",
+	B_L1_Bi_16x8:   "B_L1_Bi_16x8",
+	B_L1_Bi_8x16:   "B_L1_Bi_8x16",
+	B_Bi_L0_16x8:   "B_Bi_L0_16x8",
+	B_Bi_L0_8x16:   "B_Bi_L0_8x16",
+	B_Bi_L1_16x8:   "B_Bi_L1_16x8",
+	B_Bi_L1_8x16:   "B_Bi_L1_8x16",
+	B_Bi_Bi_16x8:   "B_Bi_Bi_16x8",
+	B_Bi_Bi_8x16:   "B_Bi_Bi_8x16",
+	B_8x8:          "B_8x8",
+	B_Skip:         "B_Skip",
+}
+
+func NewPMBType(mb *MacroBlock) PMBType {
+	return &PMBTypeImpl{mb}
+}
+
+// PMBTypeImpl describes the inter macroblocks of P and B slices.
+type PMBTypeImpl struct {
+	mb *MacroBlock
+}
+
+func (i *PMBTypeImpl) typeConst() PMBTypeConst {
+	if i.mb.IsSkip() {
+		return P_Skip
+	}
+	return PMBTypeConst(i.mb.mbType)
+}
+
+// bTypeConst is the type of the macroblock, if it is in a B slice.
+func (i *PMBTypeImpl) bTypeConst() BMBTypeConst {
+	if i.mb.IsSkip() {
+		return B_Skip
+	}
+	return BMBTypeConst(i.mb.mbType)
+}
+
+func (i *PMBTypeImpl) isB() bool {
+	return i.mb.sliceHdr.Type() == SliceB
+}
+
+func (i *PMBTypeImpl) info() mbTypeInfo {
+	if i.isB() {
+		return bMbTypes[i.bTypeConst()]
+	}
+	return pMbTypes[i.typeConst()]
+}
+
+func (i *PMBTypeImpl) MbPartPredMode(mbPartIdx uint) MBPartPredMode {
+	if mbPartIdx > 1 {
+		return PartPred_Unknown
+	}
+	return i.info().predMode[mbPartIdx]
+}
+
+func (i *PMBTypeImpl) NumMbPart() uint {
+	return i.info().numMbPart
+}
+
+func (i *PMBTypeImpl) MbPartWidth() uint {
+	return i.info().width
+}
+
+func (i *PMBTypeImpl) MbPartHeight() uint {
+	return i.info().height
+}
+
+func (i *PMBTypeImpl) String() string {
+	if i.isB() {
+		return fmt.Sprintf("%s (%d)", bMbTypeNames[i.bTypeConst()], i.bTypeConst().MBType())
+	}
+	names := map[PMBTypeConst]string{
+		P_L0_16x16:   "P_L0_16x16",
+		P_L0_L0_16x8: "P_L0_L0_16x8",
+		P_L0_L0_8x16: "P_L0_L0_8x16",
+		P_8x8:        "P_8x8",
+		P_8x8ref0:    "P_8x8ref0",
+		P_Skip:       "P_Skip",
+	}
+	return fmt.Sprintf("%s (%d)", names[i.typeConst()], i.typeConst().MBType())
+}
+
+type SubMBTypeConst uint
+
+/* Table 7-17 */
+const (
+	P_L0_8x8 SubMBTypeConst = iota
+	P_L0_8x4
+	P_L0_4x8
+	P_L0_4x4
+)
+
+/* Table 7-18 */
+const (
+	B_Direct_8x8 SubMBTypeConst = iota
+	B_L0_8x8
+	B_L1_8x8
+	B_Bi_8x8
+	B_L0_8x4
+	B_L0_4x8
+	B_L1_8x4
+	B_L1_4x8
+	B_Bi_8x4
+	B_Bi_4x8
+	B_L0_4x4
+	B_L1_4x4
+	B_Bi_4x4
+)
+
+type subMbTypeInfo struct {
+	numSubMbPart uint
+	predMode     MBPartPredMode
+	width        uint
+	height       uint
+}
+
+var pSubMbTypes = map[SubMBTypeConst]subMbTypeInfo{
+	P_L0_8x8: {1, Pred_L0, 8, 8},
+	P_L0_8x4: {2, Pred_L0, 8, 4},
+	P_L0_4x8: {2, Pred_L0, 4, 8},
+	P_L0_4x4: {4, Pred_L0, 4, 4},
+}
+
+var bSubMbTypes = map[SubMBTypeConst]subMbTypeInfo{
+	B_Direct_8x8: {4, Direct, 4, 4},
+	B_L0_8x8:     {1, Pred_L0, 8, 8},
+	B_L1_8x8:     {1, Pred_L1, 8, 8},
+	B_Bi_8x8:     {1, BiPred, 8, 8},
+	B_L0_8x4:     {2, Pred_L0, 8, 4},
+	B_L0_4x8:     {2, Pred_L0, 4, 8},
+	B_L1_8x4:     {2, Pred_L1, 8, 4},
+	B_L1_4x8:     {2, Pred_L1, 4, 8},
+	B_Bi_8x4:     {2, BiPred, 8, 4},
+	B_Bi_4x8:     {2, BiPred, 4, 8},
+	B_L0_4x4:     {4, Pred_L0, 4, 4},
+	B_L1_4x4:     {4, Pred_L1, 4, 4},
+	B_Bi_4x4:     {4, BiPred, 4, 4},
+}
+
+// SubMBType describes the partitioning of one 8x8 partition of a macroblock.
+type SubMBType struct {
+	mb        *MacroBlock
+	mbPartIdx uint
+}
+
+func (t SubMBType) typeConst() SubMBTypeConst {
+	return SubMBTypeConst(t.mb.subMbType[t.mbPartIdx])
+}
+
+func (t SubMBType) info() subMbTypeInfo {
+	if t.mb.sliceHdr.Type() == SliceB {
+		return bSubMbTypes[t.typeConst()]
+	}
+	return pSubMbTypes[t.typeConst()]
+}
+
+func (t SubMBType) NumSubMbPart() uint {
+	return t.info().numSubMbPart
+}
+
+func (t SubMBType) SubMbPredMode() MBPartPredMode {
+	return t.info().predMode
+}
+
+func (t SubMBType) SubMbPartWidth() uint {
+	return t.info().width
+}
+
+func (t SubMBType) SubMbPartHeight() uint {
+	return t.info().height
+}
+
+// IsDirect returns whether the partition is B_Direct_8x8, its motion is derived instead of parsed.
+func (t SubMBType) IsDirect() bool {
+	return t.SubMbPredMode() == Direct
+}
@@ -85,6 +85,43 @@ func InverseLevel4ChromaScan(blkIdx uint) (int, int) {
 	return int(InverseRasterScan(blkIdx, 4, 4, 8, 0)), int(InverseRasterScan(blkIdx, 4, 4, 8, 1))
 }
 
+/* Clause 6.4.2.1 and 6.4.2.2, the upper-left luma sample and the size of a (sub-)macroblock partition of the current macroblock */
+func (p *SliceParser) Partition(mbPartIdx, subMbPartIdx uint) (x, y, w, h int) {
+	t := p.CurrMb.PMBType()
+	if t.NumMbPart() != 4 {
+		w, h = int(t.MbPartWidth()), int(t.MbPartHeight())
+		return int(InverseRasterScan(mbPartIdx, t.MbPartWidth(), t.MbPartHeight(), 16, 0)), int(InverseRasterScan(mbPartIdx, t.MbPartWidth(), t.MbPartHeight(), 16, 1)), w, h
+	}
+	sub := p.CurrMb.SubMBType(mbPartIdx)
+	x = int(InverseRasterScan(mbPartIdx, 8, 8, 16, 0) + InverseRasterScan(subMbPartIdx, sub.SubMbPartWidth(), sub.SubMbPartHeight(), 8, 0))
+	y = int(InverseRasterScan(mbPartIdx, 8, 8, 16, 1) + InverseRasterScan(subMbPartIdx, sub.SubMbPartWidth(), sub.SubMbPartHeight(), 8, 1))
+	return x, y, int(sub.SubMbPartWidth()), int(sub.SubMbPartHeight())
+}
+
+// PartitionBlocks returns the indices of the 4x4 luma blocks covered by a (sub-)macroblock partition of the current macroblock.
+func (p *SliceParser) PartitionBlocks(mbPartIdx, subMbPartIdx uint) []uint {
+	x, y, w, h := p.Partition(mbPartIdx, subMbPartIdx)
+	blocks := []uint{}
+	for yB := y; yB < y+h; yB += 4 {
+		for xB := x; xB < x+w; xB += 4 {
+			blocks = append(blocks, uint(Luma4BlkIdx(xB, yB)))
+		}
+	}
+	return blocks
+}
+
+/* Clause 6.4.11.7, for the neighbours A and B, which are always decoded before the partition */
+// NeighPartitionN returns the 4x4 luma block covering the neighbouring partition, as the partition data is stored per block.
+func (p *SliceParser) NeighPartitionN(nt NType, mbPartIdx, subMbPartIdx uint) (MBAddr, LumaBlkIdx) {
+	xD, yD := InOutAssign(nt)
+	x, y, _, _ := p.Partition(mbPartIdx, subMbPartIdx)
+	addr, xW, yW := p.NLocationNonMBAFF(x+xD, y+yD, 16, 16)
+	if addr == MBUnavailable {
+		return addr, LumaBlkUnavailable
+	}
+	return addr, Luma4BlkIdx(xW, yW)
+}
+
 /* Clause 6.4.12 */
 
 func (p *SliceParser) NeighLocation(xN int, yN int, cat BlockCat) (mbAddrN MBAddr, xW int, yW int) {
@@ -1,6 +1,10 @@
 package parser
 
-import "go.uber.org/zap"
+import (
+	"math"
+
+	"go.uber.org/zap"
+)
 
 func NewPPSParser(p *H264Parser) *PPSParser {
 	return &PPSParser{
@@ -25,7 +29,14 @@ type PPSInfo struct {
 	PicOrderPresentFlag   uint
 	NumSliceGroupsMinus1  uint
 
-	SliceGroupMapType uint
+	SliceGroupMapType             uint
+	RunLengthMinus1               []uint
+	TopLeft                       []uint
+	BottomRight                   []uint
+	SliceGroupChangeDirectionFlag uint
+	SliceGroupChangeRateMinus1    uint
+	PicSizeInMapUnitsMinus1       uint
+	SliceGroupId                  []uint
 
 	NumRefIdxL0DefaultActiveMinus1     uint
 	NumRefIdxL1DefaultActiveMinus1     uint
@@ -53,10 +64,8 @@ func (p *PPSParser) ParseInfo() *PPSInfo {
 	s.PicOrderPresentFlag = p.ReadBits(1)
 	s.NumSliceGroupsMinus1 = p.ReadUE()
 
-	// if (num_slice_groups_minus1 > 0)
-	// TODO
 	if s.NumSliceGroupsMinus1 > 0 {
-		panic("Not implemented yet!")
+		p.ParseSliceGroups(s)
 	}
 
 	s.NumRefIdxL0DefaultActiveMinus1 = p.ReadUE()
@@ -90,3 +99,33 @@ func (p *PPSParser) ParseInfo() *PPSInfo {
 
 	return s
 }
+
+/* Clause 7.3.2.2, the slice group map of num_slice_groups_minus1 > 0 */
+func (p *PPSParser) ParseSliceGroups(s *PPSInfo) {
+	s.SliceGroupMapType = p.ReadUE()
+	switch s.SliceGroupMapType {
+	case 0:
+		for iGroup := uint(0); iGroup <= s.NumSliceGroupsMinus1; iGroup++ {
+			s.RunLengthMinus1 = append(s.RunLengthMinus1, p.ReadUE())
+		}
+	case 2:
+		for iGroup := uint(0); iGroup < s.NumSliceGroupsMinus1; iGroup++ {
+			s.TopLeft = append(s.TopLeft, p.ReadUE())
+			s.BottomRight = append(s.BottomRight, p.ReadUE())
+		}
+	case 3, 4, 5:
+		s.SliceGroupChangeDirectionFlag = p.ReadBits(1)
+		s.SliceGroupChangeRateMinus1 = p.ReadUE()
+	case 6:
+		s.PicSizeInMapUnitsMinus1 = p.ReadUE()
+		idBits := uint8(math.Ceil(math.Log2(float64(s.NumSliceGroupsMinus1 + 1))))
+		for i := uint(0); i <= s.PicSizeInMapUnitsMinus1; i++ {
+			s.SliceGroupId = append(s.SliceGroupId, p.ReadBits(idBits))
+		}
+	}
+}
+
+// SliceGroupChangeRate is the number of slice group map units by which slice groups of map type 3 to 5 change.
+func (s *PPSInfo) SliceGroupChangeRate() uint {
+	return s.SliceGroupChangeRateMinus1 + 1
+}
@@ -97,7 +97,19 @@ func (r *GolombBitReader) ReadSE() (res int) {
 	return
 }
 
+// RBSPReader is a BitReader that knows where the RBSP of the current NAL unit ends.
+type RBSPReader interface {
+	BitReader
+	MoreRBSPData() bool
+}
+
+/* Clause 7.2 */
+// MoreRBSPData returns whether there is more data in the RBSP before the rbsp_trailing_bits.
+// It always returns true if the underlying reader is not an RBSPReader.
 func (p *GolombBitReader) MoreRBSPData() bool {
+	if r, ok := p.BitReader.(RBSPReader); ok {
+		return r.MoreRBSPData()
+	}
 	return true
 }
 
@@ -8,37 +8,77 @@ const (
 )
 
 type ResidualBlock struct {
-	cat    BlockCat
-	blkIdx uint
-	level  []int
+	cat            BlockCat
+	blkIdx         uint
+	iCbCr          uint
+	level          []int
+	codedBlockFlag *uint
+
+	// Clause 9.3.3.1.3, counted while decoding coeff_abs_level_minus1
+	numDecodAbsLevelEq1 uint
+	numDecodAbsLevelGt1 uint
+}
+
+// Level returns the transform coefficient levels of the block, in scanning order.
+func (b *ResidualBlock) Level() []int {
+	return b.level
+}
+
+// TotalCoeff returns the number of non-zero transform coefficient levels of the block.
+func (b *ResidualBlock) TotalCoeff() uint {
+	total := uint(0)
+	for _, l := range b.level {
+		if l != 0 {
+			total++
+		}
+	}
+	return total
+}
+
+// CodedBlockFlag returns whether the block contains non-zero transform coefficient levels.
+func (b *ResidualBlock) CodedBlockFlag() uint {
+	return *b.codedBlockFlag
 }
 
 type ResidualData struct {
 	cat     BlockCat
+	iCbCr   uint
 	levelDC [NUM_COEFFS]int
 	levelAC [NUM_LEVELS][NUM_COEFFS]int
 	level4  [NUM_LEVELS][NUM_COEFFS]int
 	level8  [NUM_8x8_LEVELS][NUM_8x8_COEFFS]int
+
+	codedDC uint
+	codedAC [NUM_LEVELS]uint
+	coded4  [NUM_LEVELS]uint
+	coded8  [NUM_8x8_LEVELS]uint
 }
 
 func (r *ResidualData) GetBlock(cat BlockCat, idx uint) *ResidualBlock {
 	c := r.cat | cat
 	var level []int
+	var coded *uint
 
 	switch cat.Size() {
 	case BlockDC:
 		level = r.levelDC[:]
+		coded = &r.codedDC
 	case BlockAC:
 		level = r.levelAC[idx][:]
+		coded = &r.codedAC[idx]
 	case BlockLevel:
 		level = r.level4[idx][:]
+		coded = &r.coded4[idx]
 	case BlockLevel8:
 		level = r.level8[idx][:]
+		coded = &r.coded8[idx]
 	}
 
 	return &ResidualBlock{
-		c,
-		idx,
-		level,
+		cat:            c,
+		blkIdx:         idx,
+		iCbCr:          r.iCbCr,
+		level:          level,
+		codedBlockFlag: coded,
 	}
 }
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"fmt"
+	"math"
+
 	"go.uber.org/zap"
 )
 
@@ -28,28 +31,86 @@ type SliceParser struct {
 	CurrQP      int
 }
 
+/* Table 7-6, slice_type values 5-9 mean the same as 0-4 */
+type SliceType uint
+
+const (
+	SliceP SliceType = iota
+	SliceB
+	SliceI
+	SliceSP
+	SliceSI
+)
+
 type SliceHeader struct {
+	// From the header of the NAL unit containing the slice
+	NalUnitType NALUType
+	NalRefIdc   uint
+
 	FirstMbInSlice uint
 	SliceType      uint
 	PicParamSetId  uint
 
 	PPS *PPSInfo
 
-	FrameNum       uint
-	IdrPicId       uint
-	PicOrderCntLsb uint
+	// Only present if separate_colour_plane_flag is 1
+	ColourPlaneId uint
+
+	FrameNum        uint
+	FieldPicFlag    uint
+	BottomFieldFlag uint
+	IdrPicId        uint
+
+	PicOrderCntLsb         uint
+	DeltaPicOrderCntBottom int
+	DeltaPicOrderCnt       [2]int
+
+	RedundantPicCnt uint
+
+	// Only present in B slices, 1 for the spatial and 0 for the temporal direct prediction mode (Clause 8.4.1.2)
+	DirectSpatialMvPredFlag uint
+
+	NumRefIdxActiveOverrideFlag uint
+	NumRefIdxL0ActiveMinus1     uint
+	NumRefIdxL1ActiveMinus1     uint
+	RefPicListModification      [2]*RefPicListModification
 
+	PredWeightTable  *PredWeightTable
 	DecRefPicMarking *DecRefPicMarking
 
+	CabacInitIdc uint
 	SliceQPDelta int
+	// Only present in SP and SI slices
+	SpForSwitchFlag uint
+	SliceQSDelta    int
 
 	DisableDeblockingFilterIdc uint
 	SliceAlphaC0OffsetDiv2     int
 	SliceBetaOffsetDiv2        int
+
+	SliceGroupChangeCycle uint
+}
+
+func (h *SliceHeader) Type() SliceType {
+	return SliceType(h.SliceType % 5)
+}
+
+// Intra returns whether the slice only contains intra macroblocks, i.e. it is an I or SI slice.
+func (h *SliceHeader) Intra() bool {
+	return h.Type() == SliceI || h.Type() == SliceSI
 }
 
-func (p *SliceParser) ParseHeader() *SliceHeader {
-	h := &SliceHeader{}
+// MbaffFrameFlag returns MbaffFrameFlag of equation 7-25, which is 1 for frames using macroblock-adaptive frame/field decoding.
+func (h *SliceHeader) MbaffFrameFlag() uint {
+	if h.PPS.SPS.MbAdaptiveFrameFieldFlag == 1 && h.FieldPicFlag == 0 {
+		return 1
+	}
+	return 0
+}
+
+// ParseHeader parses the slice header of nal, it returns nil if the PPS or SPS of the slice was not parsed yet.
+func (p *SliceParser) ParseHeader(nal *NALUInfo) *SliceHeader {
+	h := &SliceHeader{NalUnitType: nal.Type, NalRefIdc: nal.NalRefIdc}
 	p.h = h
 
 	h.FirstMbInSlice = p.ReadUE()
@@ -57,23 +118,95 @@ func (p *SliceParser) ParseHeader() *SliceHeader {
 	h.PicParamSetId = p.ReadUE()
 
 	h.PPS = p.h264.PPSInfos[h.PicParamSetId]
+	if h.PPS == nil {
+		p.addError(fmt.Errorf("Slice refers to PPS %d, which was not parsed", h.PicParamSetId))
+		return nil
+	}
+	sps := p.h264.SPSInfos[h.PPS.SPSId]
+	if sps == nil {
+		p.addError(fmt.Errorf("PPS %d refers to SPS %d, which was not parsed", h.PPS.Id, h.PPS.SPSId))
+		return nil
+	}
+	// the slice activates the SPS of its PPS, which may have been sent again after the PPS
+	h.PPS.SPS = sps
+	p.h264.SPS = sps
 
-	maxFrameBits := p.h264.SPS.Log2MaxFrameNumMinus4 + 4
+	if sps.SeparateColourPlaneFlag == 1 {
+		h.ColourPlaneId = p.ReadBits(2)
+	}
+
+	maxFrameBits := sps.Log2MaxFrameNumMinus4 + 4
 	h.FrameNum = p.ReadBits(uint8(maxFrameBits))
 
-	h.IdrPicId = p.ReadUE()
-	if p.h264.SPS.PicOrderCntType == 0 {
-		maxCntBits := p.h264.SPS.Log2MaxPicOrderCntLsbMinus4 + 4
+	if sps.FrameMbsOnlyFlag == 0 {
+		h.FieldPicFlag = p.ReadBits(1)
+		if h.FieldPicFlag != 0 {
+			h.BottomFieldFlag = p.ReadBits(1)
+		}
+	}
+
+	if h.NalUnitType == NALU_IDR {
+		h.IdrPicId = p.ReadUE()
+	}
+	if sps.PicOrderCntType == 0 {
+		maxCntBits := sps.Log2MaxPicOrderCntLsbMinus4 + 4
 		h.PicOrderCntLsb = p.ReadBits(uint8(maxCntBits))
-	} else {
-		p.log.Error("Cannot handle pic order cnt of type non zero!")
+		if h.PPS.PicOrderPresentFlag != 0 && h.FieldPicFlag == 0 {
+			h.DeltaPicOrderCntBottom = p.ReadSE()
+		}
+	}
+	if sps.PicOrderCntType == 1 && sps.DeltaPicOrderAlwaysZeroFlag == 0 {
+		h.DeltaPicOrderCnt[0] = p.ReadSE()
+		if h.PPS.PicOrderPresentFlag != 0 && h.FieldPicFlag == 0 {
+			h.DeltaPicOrderCnt[1] = p.ReadSE()
+		}
+	}
+
+	if h.PPS.RedundantPicCntPresentFlag != 0 {
+		h.RedundantPicCnt = p.ReadUE()
+	}
+
+	if h.Type() == SliceB {
+		h.DirectSpatialMvPredFlag = p.ReadBits(1)
+	}
+
+	h.NumRefIdxL0ActiveMinus1 = h.PPS.NumRefIdxL0DefaultActiveMinus1
+	h.NumRefIdxL1ActiveMinus1 = h.PPS.NumRefIdxL1DefaultActiveMinus1
+	if h.Type() == SliceP || h.Type() == SliceSP || h.Type() == SliceB {
+		h.NumRefIdxActiveOverrideFlag = p.ReadBits(1)
+		if h.NumRefIdxActiveOverrideFlag != 0 {
+			h.NumRefIdxL0ActiveMinus1 = p.ReadUE()
+			if h.Type() == SliceB {
+				h.NumRefIdxL1ActiveMinus1 = p.ReadUE()
+			}
+		}
+	}
+
+	if !h.Intra() {
+		h.RefPicListModification[0] = p.ParseRefPicListModification()
+	}
+	if h.Type() == SliceB {
+		h.RefPicListModification[1] = p.ParseRefPicListModification()
 	}
 
-	// TODO More shit here, that's currently not used
-	// if (nal_ref_idc != 0)
-	h.DecRefPicMarking = p.ParseDecRefPicMarking()
+	if (h.PPS.WeightedPredFlag != 0 && (h.Type() == SliceP || h.Type() == SliceSP)) || (h.PPS.WeightedBipredIdc == 1 && h.Type() == SliceB) {
+		h.PredWeightTable = p.ParsePredWeightTable()
+	}
 
+	if h.NalRefIdc != 0 {
+		h.DecRefPicMarking = p.ParseDecRefPicMarking(h.NalUnitType == NALU_IDR)
+	}
+
+	if h.PPS.EntropyCodingModeFlag != 0 && !h.Intra() {
+		h.CabacInitIdc = p.ReadUE()
+	}
 	h.SliceQPDelta = int(p.ReadSE())
+	if h.Type() == SliceSP || h.Type() == SliceSI {
+		if h.Type() == SliceSP {
+			h.SpForSwitchFlag = p.ReadBits(1)
+		}
+		h.SliceQSDelta = p.ReadSE()
+	}
 
 	if h.PPS.DeblockingFilterControlPresentFlag != 0 {
 		h.DisableDeblockingFilterIdc = p.ReadUE()
@@ -83,21 +216,158 @@ func (p *SliceParser) ParseHeader() *SliceHeader {
 		}
 	}
 
+	if h.PPS.NumSliceGroupsMinus1 > 0 && h.PPS.SliceGroupMapType >= 3 && h.PPS.SliceGroupMapType <= 5 {
+		// Clause 7.4.3, the length is Ceil(Log2(PicSizeInMapUnits / SliceGroupChangeRate + 1))
+		changeCycleBits := math.Ceil(math.Log2(float64(sps.PicSizeInMapUnits())/float64(h.PPS.SliceGroupChangeRate()) + 1))
+		h.SliceGroupChangeCycle = p.ReadBits(uint8(changeCycleBits))
+	}
+
 	return h
 }
 
+type PredWeight struct {
+	LumaWeightFlag   uint
+	LumaWeight       int
+	LumaOffset       int
+	ChromaWeightFlag uint
+	ChromaWeight     [2]int
+	ChromaOffset     [2]int
+}
+
+type PredWeightTable struct {
+	LumaLog2WeightDenom   uint
+	ChromaLog2WeightDenom uint
+	// the weights of the reference pictures in list 0 and 1, the default ones are inferred if the flags are 0
+	Weights [2][]PredWeight
+}
+
+/* Clause 7.3.3.2 */
+func (p *SliceParser) ParsePredWeightTable() *PredWeightTable {
+	t := &PredWeightTable{}
+	chroma := p.h264.SPS.ChromaArrayType() != 0
+
+	t.LumaLog2WeightDenom = p.ReadUE()
+	if chroma {
+		t.ChromaLog2WeightDenom = p.ReadUE()
+	}
+	numLists := uint(1)
+	if p.h.Type() == SliceB {
+		numLists = 2
+	}
+	for list := uint(0); list < numLists; list++ {
+		for i := uint(0); i <= p.numRefIdxActiveMinus1(list); i++ {
+			w := PredWeight{LumaWeight: 1 << t.LumaLog2WeightDenom}
+			w.ChromaWeight[0] = 1 << t.ChromaLog2WeightDenom
+			w.ChromaWeight[1] = w.ChromaWeight[0]
+			w.LumaWeightFlag = p.ReadBits(1)
+			if w.LumaWeightFlag != 0 {
+				w.LumaWeight = p.ReadSE()
+				w.LumaOffset = p.ReadSE()
+			}
+			if chroma {
+				w.ChromaWeightFlag = p.ReadBits(1)
+				if w.ChromaWeightFlag != 0 {
+					for iCbCr := 0; iCbCr < 2; iCbCr++ {
+						w.ChromaWeight[iCbCr] = p.ReadSE()
+						w.ChromaOffset[iCbCr] = p.ReadSE()
+					}
+				}
+			}
+			t.Weights[list] = append(t.Weights[list], w)
+		}
+	}
+	return t
+}
+
+type RefPicListModificationOp struct {
+	ModificationOfPicNumsIdc uint
+	AbsDiffPicNumMinus1      uint
+	LongTermPicNum           uint
+}
+
+type RefPicListModification struct {
+	RefPicListModificationFlag uint
+	Ops                        []RefPicListModificationOp
+}
+
+/* Clause 7.3.3.1, for one of the lists */
+func (p *SliceParser) ParseRefPicListModification() *RefPicListModification {
+	m := &RefPicListModification{}
+	m.RefPicListModificationFlag = p.ReadBits(1)
+	if m.RefPicListModificationFlag == 0 {
+		return m
+	}
+	for {
+		op := RefPicListModificationOp{}
+		op.ModificationOfPicNumsIdc = p.ReadUE()
+		switch op.ModificationOfPicNumsIdc {
+		case 0, 1:
+			op.AbsDiffPicNumMinus1 = p.ReadUE()
+		case 2:
+			op.LongTermPicNum = p.ReadUE()
+		case 3:
+			return m
+		default:
+			p.addError(fmt.Errorf("Invalid modification_of_pic_nums_idc %d", op.ModificationOfPicNumsIdc))
+			return m
+		}
+		m.Ops = append(m.Ops, op)
+	}
+}
+
+type MemoryManagementOp struct {
+	MemoryManagementControlOperation uint
+	DifferenceOfPicNumsMinus1        uint
+	LongTermPicNum                   uint
+	LongTermFrameIdx                 uint
+	MaxLongTermFrameIdxPlus1         uint
+}
+
 type DecRefPicMarking struct {
-	NoOutputOfPriorPicsFlag uint
-	LongTermReferenceFlag   uint
+	NoOutputOfPriorPicsFlag       uint
+	LongTermReferenceFlag         uint
+	AdaptiveRefPicMarkingModeFlag uint
+	Ops                           []MemoryManagementOp
 }
 
-func (p *SliceParser) ParseDecRefPicMarking() *DecRefPicMarking {
+/* Clause 7.3.3.3 */
+func (p *SliceParser) ParseDecRefPicMarking(idr bool) *DecRefPicMarking {
 	r := &DecRefPicMarking{}
 
-	r.NoOutputOfPriorPicsFlag = p.ReadBits(1)
-	r.LongTermReferenceFlag = p.ReadBits(1)
+	if idr {
+		r.NoOutputOfPriorPicsFlag = p.ReadBits(1)
+		r.LongTermReferenceFlag = p.ReadBits(1)
+		return r
+	}
 
-	return r
+	r.AdaptiveRefPicMarkingModeFlag = p.ReadBits(1)
+	if r.AdaptiveRefPicMarkingModeFlag == 0 {
+		return r
+	}
+	for {
+		op := MemoryManagementOp{}
+		op.MemoryManagementControlOperation = p.ReadUE()
+		switch op.MemoryManagementControlOperation {
+		case 0:
+			return r
+		case 1:
+			op.DifferenceOfPicNumsMinus1 = p.ReadUE()
+		case 2:
+			op.LongTermPicNum = p.ReadUE()
+		case 3:
+			op.DifferenceOfPicNumsMinus1 = p.ReadUE()
+			op.LongTermFrameIdx = p.ReadUE()
+		case 4:
+			op.MaxLongTermFrameIdxPlus1 = p.ReadUE()
+		case 6:
+			op.LongTermFrameIdx = p.ReadUE()
+		}
+		if op.MemoryManagementControlOperation > 6 {
+			p.addError(fmt.Errorf("Invalid memory_management_control_operation %d", op.MemoryManagementControlOperation))
+			return r
+		}
+		r.Ops = append(r.Ops, op)
+	}
 }
 
 func (p *SliceParser) CalculateSliceMap() {
@@ -107,7 +377,11 @@ func (p *SliceParser) CalculateSliceMap() {
 			p.unitToGroup[i] = 0
 		}
 	} else {
-		panic("Not implemented yet!")
+		// TODO: slice group map types of Clause 8.2.2
+		p.addError(fmt.Errorf("Slice group map type %d is not supported", p.h.PPS.SliceGroupMapType))
+		for i := uint(0); i < p.h264.SPS.PicSizeInMapUnits(); i++ {
+			p.unitToGroup[i] = 0
+		}
 	}
 	p.CalculateMbMap()
 }
@@ -137,50 +411,111 @@ func (p *SliceParser) NextMbAddress(addr MBAddr) MBAddr {
 type SliceData struct {
 }
 
+// EntropyDecoder parses the syntax elements of the slice data, which are coded with CAVLC or CABAC depending on entropy_coding_mode_flag.
+type EntropyDecoder interface {
+	ParseMBType() uint
+	ParseSubMbType() uint
+	ParseRefIdx(list, mbPartIdx uint) int
+	ParseMvd(list, mbPartIdx, subMbPartIdx, compIdx uint) int
+	ParseTransformSize8x8Flag() uint
+	ParseCodedBlockPattern(ChromaArrayType uint) uint
+	ParseMbQpDelta() int
+	ParsePrevIntraPredModeFlag() uint
+	ParseRemIntraPredMode() uint
+	ParseIntraChromaPredMode() uint
+	ParseMbFieldDecodingFlag() uint
+	ParseResidualBlock(coeffLevel *ResidualBlock, startIdx, endIdx, maxNumCoeff uint)
+}
+
+/* Clause 7.3.4 */
+// ParseSliceData parses the macroblocks of the slice, it returns nil for MBAFF frames, which are not supported.
 func (p *SliceParser) ParseSliceData() *SliceData {
-	d := &SliceData{}
+	mbaffFrameFlag := p.h.MbaffFrameFlag()
+	if mbaffFrameFlag != 0 {
+		p.addError(fmt.Errorf("Skipping slice of an MBAFF frame, macroblock-adaptive frame/field decoding is not supported"))
+		return nil
+	}
+	data := &SliceData{}
 	p.MacroBlocks = map[MBAddr]*MacroBlock{}
 	p.CalculateSliceMap()
 	p.CurrQP = p.h.PPS.PicInitQPMinus26 + 26 + p.h.SliceQPDelta
 
+	var cabac *CabacParser
+	var cavlc *CavlcParser
+	var d EntropyDecoder
 	if p.h.PPS.EntropyCodingModeFlag != 0 {
 		p.Align()
+		cabac = NewCabacParser(p.h264)
+		cabac.Initialize()
+		cabac.InitializeDecodeEngine()
+		d = cabac
+	} else {
+		cavlc = NewCavlcParser(p.h264)
+		d = cavlc
 	}
 
 	p.PrevMbAddr = MBUnavailable
-	mbaffFrameFlag := uint(0)
 
 	p.CurrMbAddr = p.h.FirstMbInSlice * (1 + mbaffFrameFlag)
 	moreDataFlag := 1
 	prevMbSkipped := 0
-	c := NewCabacParser(p.h264)
-	c.Initialize()
-	c.InitializeDecodeEngine()
 
 	for {
-		// if (slice_type != I && slice_type != SI)
-		// TODO
+		mbSkipFlag := uint(0)
+		if !p.h.Intra() {
+			moreDataFlag = 1
+			if cavlc != nil {
+				mbSkipRun := cavlc.ParseMbSkipRun()
+				prevMbSkipped = 0
+				if mbSkipRun > 0 {
+					prevMbSkipped = 1
+				}
+				for i := uint(0); i < mbSkipRun; i++ {
+					if p.CurrMbAddr >= p.h264.SPS.PicSizeInMbs() {
+						p.addError(fmt.Errorf("mb_skip_run %d continues after the last macroblock %d", mbSkipRun, p.PrevMbAddr))
+						return data
+					}
+					p.SkipMacroblock()
+					p.PrevMbAddr = MBAddr(p.CurrMbAddr)
+					p.CurrMbAddr = uint(p.NextMbAddress(p.PrevMbAddr))
+				}
+				if mbSkipRun > 0 && !p.MoreRBSPData() {
+					moreDataFlag = 0
+				}
+			} else {
+				mbSkipFlag = cabac.ParseMbSkipFlag()
+				if mbSkipFlag != 0 {
+					p.SkipMacroblock()
+					moreDataFlag = 0
+				}
+			}
+		}
 
 		if moreDataFlag != 0 {
+			if p.CurrMbAddr >= p.h264.SPS.PicSizeInMbs() {
+				p.addError(fmt.Errorf("Slice data continues after the last macroblock %d", p.PrevMbAddr))
+				break
+			}
 			if mbaffFrameFlag != 0 && (p.CurrMbAddr%2 == 0 || (p.CurrMbAddr%2 == 1 && prevMbSkipped != 0)) {
 				// TODO: What is this used for?
-				c.ParseMbFieldDecodingFlag()
+				d.ParseMbFieldDecodingFlag()
 			}
-			p.ParseMacroblockLayer(c)
+			p.ParseMacroblockLayer(d)
 		}
 
-		if p.h.PPS.EntropyCodingModeFlag == 0 {
+		if cavlc != nil {
 			moreDataFlag = 0
 			if p.MoreRBSPData() {
 				moreDataFlag = 1
 			}
 		} else {
-			// if (slice_type != I && slice_type != SI)
-			// 	prevMbSkipped = mbSkipFlag
+			if !p.h.Intra() {
+				prevMbSkipped = int(mbSkipFlag)
+			}
 			if mbaffFrameFlag != 0 && p.CurrMbAddr%2 == 0 {
 				moreDataFlag = 1
 			} else {
-				endOfSliceFlag := c.ParseEndOfSliceFlag()
+				endOfSliceFlag := cabac.ParseEndOfSliceFlag()
 				moreDataFlag = 1
 				if endOfSliceFlag != 0 {
 					moreDataFlag = 0
@@ -194,27 +529,56 @@ func (p *SliceParser) ParseSliceData() *SliceData {
 		if moreDataFlag == 0 {
 			break
 		}
+		if cabac != nil && p.CurrMbAddr >= p.h264.SPS.PicSizeInMbs() {
+			p.addError(fmt.Errorf("Slice data continues after the last macroblock %d", p.PrevMbAddr))
+			break
+		}
 	}
 
-	return d
+	return data
 }
 
-func (p *SliceParser) ParseMacroblockLayer(c *CabacParser) {
+func (p *SliceParser) newMacroBlock() *MacroBlock {
 	p.CurrMb = NewMacroBlock()
 	p.CurrMb.Addr = MBAddr(p.CurrMbAddr)
 	p.CurrMb.sliceHdr = p.h
 	p.MacroBlocks[MBAddr(p.CurrMbAddr)] = p.CurrMb
-	p.CurrMb.mbType = c.ParseMBType()
-	imbType := p.CurrMb.IMBType()
+	return p.CurrMb
+}
+
+// SkipMacroblock stores the current macroblock as skipped, it keeps the QP.
+// P_Skip predicts from the first reference picture, the prediction of B_Skip is derived like that of direct macroblocks.
+func (p *SliceParser) SkipMacroblock() {
+	mb := p.newMacroBlock()
+	mb.mbSkipFlag = 1
+	if p.h.Type() != SliceB {
+		for blkIdx := range mb.refIdx[0] {
+			mb.refIdx[0][blkIdx] = 0
+		}
+	}
+	mb.qpVal = p.CurrQP
+	p.log.Debugf("%s", mb)
+}
+
+func (p *SliceParser) ParseMacroblockLayer(d EntropyDecoder) {
+	p.newMacroBlock()
+	p.CurrMb.mbType = d.ParseMBType()
+	intra := p.CurrMb.IsIntra()
+	if intra && p.CurrMb.IMBType().GeneralIType() == G_Intra_16x16 {
+		// Clause 7.4.5, the coded block pattern is part of the macroblock type
+		imbType := p.CurrMb.IMBType()
+		p.CurrMb.codedBlockPattern = imbType.CodedBlockPatternLuma() + 16*uint(imbType.CodedBlockPatternChroma())
+	}
+	iNxN := intra && p.CurrMb.IMBType().GeneralIType() == G_I_NxN
 
 	// PCM
-	if imbType.GeneralIType() == G_I_PCM {
+	if p.CurrMb.IsPCM() {
 		// Shorter version of:
 		/*
 			while (!byte_aligned())
 				pcm_alignment_zero_bit
 		*/
-		c.Align()
+		p.Align()
 
 		pcm_sample_luma := [256]uint{}
 		for i := 0; i < 256; i++ {
@@ -230,30 +594,36 @@ func (p *SliceParser) ParseMacroblockLayer(c *CabacParser) {
 
 	} else {
 		noSubMbPartSizeLessThan8x8Flag := 1
-		//pmbType := p.CurrMb.PMBType()
-		if imbType.GeneralIType() != G_I_NxN && imbType.MbPartPredMode(0) != Intra_16x16 /* TODO: More here! */ {
-			// Probably anything not I related?
-			// TODO: even more stuff here
-			panic("Not implemented yet!")
+		if !intra && p.CurrMb.PMBType().NumMbPart() == 4 {
+			p.ParseSubMbPred(d)
+			for mbPartIdx := uint(0); mbPartIdx < 4; mbPartIdx++ {
+				sub := p.CurrMb.SubMBType(mbPartIdx)
+				if sub.IsDirect() {
+					if p.h264.SPS.Direct8x8InferenceFlag == 0 {
+						noSubMbPartSizeLessThan8x8Flag = 0
+					}
+				} else if sub.NumSubMbPart() > 1 {
+					noSubMbPartSizeLessThan8x8Flag = 0
+				}
+			}
 		} else {
-			// Intra_YxY mode
-
-			if p.h.PPS.Transform8x8ModeFlag == 1 && imbType.GeneralIType() == G_I_NxN {
-				p.CurrMb.transformSize8x8Flag = c.ParseTransformSize8x8Flag()
+			if p.h.PPS.Transform8x8ModeFlag == 1 && iNxN {
+				p.CurrMb.transformSize8x8Flag = d.ParseTransformSize8x8Flag()
 			}
 			// mb_pred
-			p.ParseMbPred(c)
+			p.ParseMbPred(d)
 		}
 
-		if imbType.MbPartPredMode(0) != Intra_16x16 {
-			p.CurrMb.codedBlockPattern = c.ParseCodedBlockPattern(p.h264.SPS.ChromaArrayType())
-			if p.CurrMb.CodedBlockPatternLuma() > 0 && p.h.PPS.Transform8x8ModeFlag == 1 && imbType.GeneralIType() != G_I_NxN && noSubMbPartSizeLessThan8x8Flag == 1 /* TODO: More conditions here */ {
-				p.CurrMb.transformSize8x8Flag = c.ParseTransformSize8x8Flag()
+		if p.CurrMb.MbPartPredMode(0) != Intra_16x16 {
+			p.CurrMb.codedBlockPattern = d.ParseCodedBlockPattern(p.h264.SPS.ChromaArrayType())
+			if p.CurrMb.CodedBlockPatternLuma() > 0 && p.h.PPS.Transform8x8ModeFlag == 1 && !iNxN && noSubMbPartSizeLessThan8x8Flag == 1 &&
+				(p.CurrMb.MbPartPredMode(0) != Direct || p.h264.SPS.Direct8x8InferenceFlag == 1) {
+				p.CurrMb.transformSize8x8Flag = d.ParseTransformSize8x8Flag()
 			}
 		}
-		if imbType.MbPartPredMode(0) == Intra_16x16 || p.CurrMb.CodedBlockPatternChroma() > 0 || p.CurrMb.CodedBlockPatternLuma() > 0 {
-			p.CurrMb.qpDelta = c.ParseMbQpDelta()
-			p.ParseResidual(0, 15, c)
+		if p.CurrMb.MbPartPredMode(0) == Intra_16x16 || p.CurrMb.CodedBlockPatternChroma() > 0 || p.CurrMb.CodedBlockPatternLuma() > 0 {
+			p.CurrMb.qpDelta = d.ParseMbQpDelta()
+			p.ParseResidual(0, 15, d)
 		}
 	}
 
@@ -263,9 +633,43 @@ func (p *SliceParser) ParseMacroblockLayer(c *CabacParser) {
 	p.log.Debugf("%s", p.CurrMb)
 }
 
-func (p *SliceParser) ParseMbPred(c *CabacParser) {
-	imbType := p.CurrMb.IMBType()
-	mbPartPred := imbType.MbPartPredMode(0)
+// usesList returns whether a partition with the prediction mode is predicted from the reference picture list.
+func usesList(mode MBPartPredMode, list uint) bool {
+	if list == 0 {
+		return mode == Pred_L0 || mode == BiPred
+	}
+	return mode == Pred_L1 || mode == BiPred
+}
+
+func (p *SliceParser) numRefIdxActiveMinus1(list uint) uint {
+	if list == 0 {
+		return p.h.NumRefIdxL0ActiveMinus1
+	}
+	return p.h.NumRefIdxL1ActiveMinus1
+}
+
+// parseRefIdx parses the ref_idx of a macroblock partition if there is more than one reference picture, 0 is inferred otherwise.
+// Interlaced macroblocks are not supported.
+func (p *SliceParser) parseRefIdx(list, mbPartIdx uint, d EntropyDecoder) int {
+	if p.numRefIdxActiveMinus1(list) > 0 {
+		return d.ParseRefIdx(list, mbPartIdx)
+	}
+	return 0
+}
+
+func (p *SliceParser) parseMvd(list, mbPartIdx, subMbPartIdx uint, d EntropyDecoder) {
+	blocks := p.PartitionBlocks(mbPartIdx, subMbPartIdx)
+	for compIdx := uint(0); compIdx < 2; compIdx++ {
+		mvd := d.ParseMvd(list, mbPartIdx, subMbPartIdx, compIdx)
+		for _, blkIdx := range blocks {
+			p.CurrMb.mvd[list][blkIdx][compIdx] = mvd
+		}
+	}
+}
+
+/* Clause 7.3.5.1 */
+func (p *SliceParser) ParseMbPred(d EntropyDecoder) {
+	mbPartPred := p.CurrMb.MbPartPredMode(0)
 	if mbPartPred == Intra_4x4 || mbPartPred == Intra_8x8 || mbPartPred == Intra_16x16 {
 		if mbPartPred == Intra_4x4 || mbPartPred == Intra_8x8 {
 			num := 16
@@ -275,51 +679,100 @@ func (p *SliceParser) ParseMbPred(c *CabacParser) {
 			p.CurrMb.prevIntraPredModeFlag = make([]uint, num)
 			p.CurrMb.remIntraPredMode = make([]uint, num)
 			for blkIdx := 0; blkIdx < num; blkIdx++ {
-				p.CurrMb.prevIntraPredModeFlag[blkIdx] = c.ParsePrevIntraPredModeFlag()
+				p.CurrMb.prevIntraPredModeFlag[blkIdx] = d.ParsePrevIntraPredModeFlag()
 				if p.CurrMb.prevIntraPredModeFlag[blkIdx] == 0 {
-					p.CurrMb.remIntraPredMode[blkIdx] = c.ParseRemIntraPredMode()
+					p.CurrMb.remIntraPredMode[blkIdx] = d.ParseRemIntraPredMode()
 				}
 			}
 		}
 		if p.h264.SPS.ChromaArrayType() == 1 || p.h264.SPS.ChromaArrayType() == 2 {
-			p.CurrMb.intraChromaPredMode = c.ParseIntraChromaPredMode()
+			p.CurrMb.intraChromaPredMode = d.ParseIntraChromaPredMode()
 		}
 	} else if mbPartPred != Direct {
-		panic("Not implemented yet!")
+		t := p.CurrMb.PMBType()
+		for list := uint(0); list < 2; list++ {
+			for mbPartIdx := uint(0); mbPartIdx < t.NumMbPart(); mbPartIdx++ {
+				if !usesList(t.MbPartPredMode(mbPartIdx), list) {
+					continue
+				}
+				refIdx := p.parseRefIdx(list, mbPartIdx, d)
+				for _, blkIdx := range p.PartitionBlocks(mbPartIdx, 0) {
+					p.CurrMb.refIdx[list][blkIdx] = refIdx
+				}
+			}
+		}
+		for list := uint(0); list < 2; list++ {
+			for mbPartIdx := uint(0); mbPartIdx < t.NumMbPart(); mbPartIdx++ {
+				if usesList(t.MbPartPredMode(mbPartIdx), list) {
+					p.parseMvd(list, mbPartIdx, 0, d)
+				}
+			}
+		}
+	}
+}
+
+/* Clause 7.3.5.2 */
+func (p *SliceParser) ParseSubMbPred(d EntropyDecoder) {
+	mb := p.CurrMb
+	for mbPartIdx := uint(0); mbPartIdx < 4; mbPartIdx++ {
+		mb.subMbType[mbPartIdx] = d.ParseSubMbType()
+	}
+	for list := uint(0); list < 2; list++ {
+		for mbPartIdx := uint(0); mbPartIdx < 4; mbPartIdx++ {
+			sub := mb.SubMBType(mbPartIdx)
+			if !usesList(sub.SubMbPredMode(), list) {
+				continue
+			}
+			refIdx := 0
+			if p.h.Type() == SliceB || PMBTypeConst(mb.mbType) != P_8x8ref0 {
+				refIdx = p.parseRefIdx(list, mbPartIdx, d)
+			}
+			for subMbPartIdx := uint(0); subMbPartIdx < sub.NumSubMbPart(); subMbPartIdx++ {
+				for _, blkIdx := range p.PartitionBlocks(mbPartIdx, subMbPartIdx) {
+					mb.refIdx[list][blkIdx] = refIdx
+				}
+			}
+		}
+	}
+	for list := uint(0); list < 2; list++ {
+		for mbPartIdx := uint(0); mbPartIdx < 4; mbPartIdx++ {
+			sub := mb.SubMBType(mbPartIdx)
+			if !usesList(sub.SubMbPredMode(), list) {
+				continue
+			}
+			for subMbPartIdx := uint(0); subMbPartIdx < sub.NumSubMbPart(); subMbPartIdx++ {
+				p.parseMvd(list, mbPartIdx, subMbPartIdx, d)
+			}
+		}
 	}
 }
 
-func (p *SliceParser) ParseResidual(start, end uint, c *CabacParser) {
-	// panic("Not implemented yet!")
-	// We assume we are always parsing CABAC. As such we can skip this first few lines here:
-	/*
-		if (!entropy_coding_mode_flag)
-			residual_block = residual_block_cavlc
-		else
-			residual_block = residual_block_cabac
-	*/
-	p.CurrMb.lumaResidual = *p.ParseResidualLuma(start, end, BlockLuma, c)
+func (p *SliceParser) ParseResidual(start, end uint, d EntropyDecoder) {
+	// residual_block is residual_block_cavlc or residual_block_cabac, depending on the entropy decoder d
+	p.ParseResidualLuma(start, end, &p.CurrMb.lumaResidual, d)
 
 	if p.h264.SPS.ChromaArrayType() == 1 || p.h264.SPS.ChromaArrayType() == 2 {
+		numC8x8 := p.h264.SPS.NumC8x8()
 		for iCbCr := 0; iCbCr < 2; iCbCr++ {
 			residual := &p.CurrMb.chromaResidual[iCbCr]
 			if (p.CurrMb.CodedBlockPatternChroma()&3) != 0 && start == 0 {
-				p.ParseResidualBlock(residual.GetBlock(BlockDC, 0), 0, 4*p.h264.SPS.NumC8x8()-1, 4*p.h264.SPS.NumC8x8(), c)
+				d.ParseResidualBlock(residual.GetBlock(BlockDC, 0), 0, 4*numC8x8-1, 4*numC8x8)
 			}
 		}
 
 		for iCbCr := 0; iCbCr < 2; iCbCr++ {
 			residual := &p.CurrMb.chromaResidual[iCbCr]
-			for i8x8 := uint(0); i8x8 < p.h264.SPS.NumC8x8(); i8x8++ {
+			for i8x8 := uint(0); i8x8 < numC8x8; i8x8++ {
 				for i4x4 := uint(0); i4x4 < 4; i4x4++ {
 					if (p.CurrMb.CodedBlockPatternChroma() & 2) != 0 {
-						p.ParseResidualBlock(residual.GetBlock(BlockAC, i8x8*4+i4x4), Max(0, start-1), end-1, 15, c)
+						d.ParseResidualBlock(residual.GetBlock(BlockAC, i8x8*4+i4x4), acStartIdx(start), end-1, 15)
 					}
 				}
 			}
 		}
 	} else if p.h264.SPS.ChromaArrayType() == 3 {
-		panic("Not implemented yet")
+		p.ParseResidualLuma(start, end, &p.CurrMb.cbResidual, d)
+		p.ParseResidualLuma(start, end, &p.CurrMb.crResidual, d)
 	}
 }
 
@@ -331,14 +784,20 @@ func Max(a, b uint) uint {
 	return b
 }
 
-func (p *SliceParser) ParseResidualLuma(startIdx, endIdx uint, cat BlockCat, c *CabacParser) *ResidualData {
-	imbType := p.CurrMb.IMBType()
-	mbPartPred := imbType.MbPartPredMode(0)
+// acStartIdx returns Max(0, startIdx-1), the first index of the AC coefficient lists, which lack the DC coefficient.
+func acStartIdx(startIdx uint) uint {
+	if startIdx == 0 {
+		return 0
+	}
+	return startIdx - 1
+}
 
-	ret := &ResidualData{cat: cat}
+/* Clause 7.3.5.3.1 */
+func (p *SliceParser) ParseResidualLuma(startIdx, endIdx uint, residual *ResidualData, d EntropyDecoder) {
+	mbPartPred := p.CurrMb.MbPartPredMode(0)
 
 	if startIdx == 0 && mbPartPred == Intra_16x16 {
-		p.ParseResidualBlock(ret.GetBlock(BlockDC, 0), 0, 15, 16, c)
+		d.ParseResidualBlock(residual.GetBlock(BlockDC, 0), 0, 15, 16)
 	}
 	for i8x8 := 0; i8x8 < 4; i8x8++ {
 		if p.CurrMb.transformSize8x8Flag == 0 || p.h.PPS.EntropyCodingModeFlag == 0 {
@@ -346,43 +805,26 @@ func (p *SliceParser) ParseResidualLuma(startIdx, endIdx uint, cat BlockCat, c *
 				blkIdx := uint(i8x8*4 + i4x4)
 				if p.CurrMb.CodedBlockPatternLuma()&(1<<i8x8) != 0 {
 					if mbPartPred == Intra_16x16 {
-						p.ParseResidualBlock(ret.GetBlock(BlockAC, blkIdx), Max(0, startIdx-1), endIdx-1, 15, c)
+						d.ParseResidualBlock(residual.GetBlock(BlockAC, blkIdx), acStartIdx(startIdx), endIdx-1, 15)
 					} else {
-						p.ParseResidualBlock(ret.GetBlock(BlockLevel, blkIdx), startIdx, endIdx, 16, c)
+						d.ParseResidualBlock(residual.GetBlock(BlockLevel, blkIdx), startIdx, endIdx, 16)
 					}
-				} else {
-					//panic("Not implemented yet")
 				}
 			}
-		} else if p.CurrMb.CodedBlockPatternLuma()&(1<<i8x8) != 0 {
-			p.ParseResidualBlock(ret.GetBlock(BlockLevel8, uint(i8x8)), 4*startIdx, 4*endIdx+3, 64, c)
-		}
-	}
-
-	return ret
-}
-
-func (p *SliceParser) ParseResidualBlock(coeffLevel *ResidualBlock, startIdx, endIdx, maxNumCoeff uint, c *CabacParser) {
-	coded_block_flag := uint(1)
-	if maxNumCoeff != 64 || p.h264.SPS.ChromaArrayType() == 3 {
-		coded_block_flag = c.ParseCodedBlockFlag(coeffLevel)
-	}
-	for i := uint(0); i < maxNumCoeff; i++ {
-		coeffLevel.level[i] = 0
-	}
-	if coded_block_flag != 0 {
-		significant_coeff_flag := make([]uint, 64)
-		numCoeff := endIdx + 1
-		i := startIdx
-		for i < numCoeff-1 {
-			significant_coeff_flag[i] = c.ParseSignificantCoeffFlag(coeffLevel)
-			if significant_coeff_flag[i] != 0 {
-				last_significant_coeff_flag := c.ParseLastSignificantCoeffFlag(coeffLevel)
-				if last_significant_coeff_flag != 0 {
-					numCoeff++
+			if p.CurrMb.transformSize8x8Flag == 1 {
+				// CAVLC codes the 8x8 block as four interleaved 4x4 blocks
+				block8 := residual.GetBlock(BlockLevel8, uint(i8x8))
+				*block8.codedBlockFlag = 0
+				for i4x4 := 0; i4x4 < 4; i4x4++ {
+					block4 := residual.GetBlock(BlockLevel, uint(i8x8*4+i4x4))
+					for i := 0; i < 16; i++ {
+						block8.level[4*i+i4x4] = block4.level[i]
+					}
+					*block8.codedBlockFlag |= *block4.codedBlockFlag
 				}
 			}
-			i++
+		} else if p.CurrMb.CodedBlockPatternLuma()&(1<<i8x8) != 0 {
+			d.ParseResidualBlock(residual.GetBlock(BlockLevel8, uint(i8x8)), 4*startIdx, 4*endIdx+3, 64)
 		}
 	}
 }
@@ -0,0 +1,600 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// idrSliceHeader writes the header of an I slice of an IDR picture, up to and including the CABAC alignment.
+func idrSliceHeader(w *bitWriter, qpDelta int) {
+	// first_mb_in_slice, slice_type (I), pic_parameter_set_id, frame_num, idr_pic_id, pic_order_cnt_lsb
+	w.ue(0)
+	w.ue(7)
+	w.ue(0)
+	w.bits(0, 4)
+	w.ue(0)
+	w.bits(0, 4)
+	// dec_ref_pic_marking: no_output_of_prior_pics_flag, long_term_reference_flag
+	w.bit(0)
+	w.bit(0)
+	w.se(qpDelta)
+	// disable_deblocking_filter_idc
+	w.ue(1)
+	w.alignOnes()
+}
+
+// luma4x4XY returns the position of a 4x4 luma block in units of 4x4 blocks.
+func luma4x4XY(blkIdx int) (int, int) {
+	return (blkIdx/4%2)*2 + blkIdx%4%2, (blkIdx/4/2)*2 + blkIdx%4/2
+}
+
+// cbfInc returns the ctxIdxInc of the coded_block_flag of the 4x4 block at x, y in a macroblock without neighbours,
+// given the blocks before it that are coded.
+func cbfInc(coded map[[2]int]bool, x, y int, intra bool) uint {
+	inc := uint(0)
+	if (x == 0 && intra) || coded[[2]int{x - 1, y}] {
+		inc++
+	}
+	if (y == 0 && intra) || coded[[2]int{x, y - 1}] {
+		inc += 2
+	}
+	return inc
+}
+
+func nonZero(levels []int) bool {
+	for _, l := range levels {
+		if l != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCabacResidualIntra16x16(t *testing.T) {
+	params := testStreamParams{widthInMbs: 1, heightInMbs: 1, cabac: true}
+
+	dc := make([]int, 16)
+	dc[0], dc[1], dc[4], dc[15] = 5, -1, 20, 1
+	ac := make([][]int, 16)
+	for i := range ac {
+		ac[i] = make([]int, 15)
+	}
+	ac[0][1] = 3
+	ac[5][0] = -1
+	ac[5][14] = -40
+	chromaDC := [2][]int{{1, 0, 0, -3}, {0, 0, 0, 0}}
+	chromaAC := [2][][]int{}
+	for iCbCr := range chromaAC {
+		for i := 0; i < 4; i++ {
+			chromaAC[iCbCr] = append(chromaAC[iCbCr], make([]int, 15))
+		}
+	}
+	chromaAC[0][3][2] = 1
+	chromaAC[1][0][0] = 2
+
+	w := &bitWriter{}
+	idrSliceHeader(w, 0)
+	e := newCabacEncoder(w, 26, SliceI, 0)
+	// mb_type I_16x16_0_2_1: prediction mode 0, chroma AC and DC, all luma AC
+	e.decision(3, 1)
+	e.terminate(0)
+	e.bins([]uint{1, 1, 1, 0, 0}, []uint{6, 7, 8, 9, 10})
+	// intra_chroma_pred_mode 2
+	e.bins([]uint{1, 1, 0}, []uint{64, 67, 67})
+	// mb_qp_delta -2
+	e.bins([]uint{1, 1, 1, 1, 0}, []uint{60, 62, 63, 63, 63})
+
+	e.residualBlock(dc, 0, 3)
+	coded := map[[2]int]bool{}
+	for blkIdx := 0; blkIdx < 16; blkIdx++ {
+		x, y := luma4x4XY(blkIdx)
+		e.residualBlock(ac[blkIdx], 1, cbfInc(coded, x, y, true))
+		coded[[2]int{x, y}] = nonZero(ac[blkIdx])
+	}
+	for iCbCr := 0; iCbCr < 2; iCbCr++ {
+		e.residualBlock(chromaDC[iCbCr], 3, 3)
+	}
+	for iCbCr := 0; iCbCr < 2; iCbCr++ {
+		coded := map[[2]int]bool{}
+		for blkIdx := 0; blkIdx < 4; blkIdx++ {
+			x, y := blkIdx%2, blkIdx/2
+			e.residualBlock(chromaAC[iCbCr][blkIdx], 4, cbfInc(coded, x, y, true))
+			coded[[2]int{x, y}] = nonZero(chromaAC[iCbCr][blkIdx])
+		}
+	}
+	e.terminate(1)
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(3, NALU_IDR, w.bytes()))
+	require.Len(t, p.slice.MacroBlocks, 1)
+	mb := p.slice.MacroBlocks[0]
+	assert.EqualValues(t, I_16x16_0_2_1, mb.mbType)
+	assert.EqualValues(t, 2, mb.intraChromaPredMode)
+	assert.Equal(t, -2, mb.qpDelta)
+	assert.Equal(t, 24, mb.qpVal)
+
+	assert.Equal(t, dc, mb.lumaResidual.GetBlock(BlockDC, 0).Level())
+	for blkIdx := uint(0); blkIdx < 16; blkIdx++ {
+		block := mb.lumaResidual.GetBlock(BlockAC, blkIdx)
+		assert.Equal(t, ac[blkIdx], block.Level()[:15], "luma AC block %d", blkIdx)
+		assert.Equal(t, nonZero(ac[blkIdx]), block.CodedBlockFlag() == 1)
+	}
+	for iCbCr := uint(0); iCbCr < 2; iCbCr++ {
+		residual := mb.ChromaResidual(iCbCr)
+		assert.Equal(t, chromaDC[iCbCr], residual.GetBlock(BlockDC, 0).Level()[:4])
+		for blkIdx := uint(0); blkIdx < 4; blkIdx++ {
+			assert.Equal(t, chromaAC[iCbCr][blkIdx], residual.GetBlock(BlockAC, blkIdx).Level()[:15], "chroma %d AC block %d", iCbCr, blkIdx)
+		}
+	}
+	// 4 luma DC, 3 luma AC, 2 chroma DC and 2 chroma AC levels
+	assert.Equal(t, []uint{0, 19, 0, 4, 2, 39, 0, 2, 0, 0, 1}, mb.coeffAbsLevelMinus1)
+	assert.Equal(t, []uint{0, 0, 1, 0, 0, 1, 1, 1, 0, 0, 0}, mb.coeffSignFlag)
+}
+
+func TestCabacResidualIntra4x4(t *testing.T) {
+	params := testStreamParams{widthInMbs: 1, heightInMbs: 1, cabac: true}
+
+	levels := make([][]int, 16)
+	for i := range levels {
+		levels[i] = make([]int, 16)
+	}
+	levels[0][0] = 7
+	levels[1][3] = -2
+	levels[1][4] = 1
+	levels[3][15] = -1
+
+	w := &bitWriter{}
+	idrSliceHeader(w, 2)
+	e := newCabacEncoder(w, 28, SliceI, 0)
+	// mb_type I_NxN
+	e.decision(3, 0)
+	for blkIdx := 0; blkIdx < 16; blkIdx++ {
+		// prev_intra4x4_pred_mode_flag, rem_intra4x4_pred_mode 5 for odd blocks
+		if blkIdx%2 == 0 {
+			e.decision(68, 1)
+		} else {
+			e.decision(68, 0)
+			e.bins([]uint{1, 0, 1}, []uint{69, 69, 69})
+		}
+	}
+	// intra_chroma_pred_mode 0
+	e.decision(64, 0)
+	// coded_block_pattern: luma 0b0001, chroma 0
+	e.bins([]uint{1, 0, 0, 0}, []uint{73, 73 + 0, 73 + 0, 73 + 3})
+	e.decision(77, 0)
+	// mb_qp_delta 0
+	e.decision(60, 0)
+	coded := map[[2]int]bool{}
+	for blkIdx := 0; blkIdx < 4; blkIdx++ {
+		x, y := luma4x4XY(blkIdx)
+		e.residualBlock(levels[blkIdx], 2, cbfInc(coded, x, y, true))
+		coded[[2]int{x, y}] = nonZero(levels[blkIdx])
+	}
+	e.terminate(1)
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(3, NALU_IDR, w.bytes()))
+	mb := p.slice.MacroBlocks[0]
+	assert.EqualValues(t, I_NxN, mb.mbType)
+	assert.Equal(t, []uint{0, 5, 0, 5, 0, 5, 0, 5, 0, 5, 0, 5, 0, 5, 0, 5}, mb.remIntraPredMode)
+	assert.EqualValues(t, 1, mb.CodedBlockPatternLuma())
+	assert.EqualValues(t, 0, mb.CodedBlockPatternChroma())
+	assert.Equal(t, 28, mb.qpVal)
+	for blkIdx := uint(0); blkIdx < 16; blkIdx++ {
+		assert.Equal(t, levels[blkIdx], mb.lumaResidual.GetBlock(BlockLevel, blkIdx).Level(), "block %d", blkIdx)
+	}
+}
+
+// pSliceHeader writes the header of a P slice of a reference picture with two active reference pictures,
+// up to and including the CABAC alignment.
+func pSliceHeader(w *bitWriter, cabacInitIdc uint, qpDelta int) {
+	// first_mb_in_slice, slice_type (P), pic_parameter_set_id, frame_num, pic_order_cnt_lsb
+	w.ue(0)
+	w.ue(5)
+	w.ue(0)
+	w.bits(1, 4)
+	w.bits(2, 4)
+	// num_ref_idx_active_override_flag, num_ref_idx_l0_active_minus1
+	w.bit(1)
+	w.ue(1)
+	// ref_pic_list_modification_flag_l0, modification_of_pic_nums_idc 0 and 3
+	w.bit(1)
+	w.ue(0)
+	w.ue(0)
+	w.ue(3)
+	// adaptive_ref_pic_marking_mode_flag, memory_management_control_operation 1 and 0
+	w.bit(1)
+	w.ue(1)
+	w.ue(0)
+	w.ue(0)
+	w.ue(cabacInitIdc)
+	w.se(qpDelta)
+	// disable_deblocking_filter_idc
+	w.ue(1)
+	w.alignOnes()
+}
+
+func TestCabacPSlice(t *testing.T) {
+	params := testStreamParams{widthInMbs: 4, heightInMbs: 1, cabac: true}
+	levels := make([]int, 16)
+	levels[0], levels[2] = 3, -1
+
+	w := &bitWriter{}
+	pSliceHeader(w, 1, 0)
+	e := newCabacEncoder(w, 26, SliceP, 1)
+
+	// MB 0: P_L0_L0_16x8
+	e.decision(11, 0)
+	e.bins([]uint{0, 1, 1}, []uint{14, 15, 17})
+	// ref_idx_l0 1 and 0, the second partition has the first above it
+	e.refIdx(1, 0)
+	e.refIdx(0, 2)
+	// mvd_l0 (5, -20) and (0, 1)
+	e.mvd(5, 40, 0)
+	e.mvd(-20, 47, 0)
+	e.mvd(0, 40, 1)
+	e.mvd(1, 47, 1)
+	// coded_block_pattern: luma 0b0001, chroma 0
+	e.bins([]uint{1, 0, 0, 0}, []uint{73, 73, 73, 76})
+	e.decision(77, 0)
+	// mb_qp_delta 0
+	e.decision(60, 0)
+	coded := map[[2]int]bool{}
+	for blkIdx := 0; blkIdx < 4; blkIdx++ {
+		x, y := luma4x4XY(blkIdx)
+		blkLevels := make([]int, 16)
+		if blkIdx == 0 {
+			blkLevels = levels
+		}
+		e.residualBlock(blkLevels, 2, cbfInc(coded, x, y, false))
+		coded[[2]int{x, y}] = nonZero(blkLevels)
+	}
+	e.terminate(0)
+
+	// MB 1: P_Skip
+	e.decision(12, 1)
+	e.terminate(0)
+
+	// MB 2: P_8x8, next to a skipped macroblock
+	e.decision(11, 0)
+	e.bins([]uint{0, 0, 1}, []uint{14, 15, 16})
+	// sub_mb_type P_L0_8x8, P_L0_8x4, P_L0_4x8, P_L0_4x4
+	e.decision(21, 1)
+	e.bins([]uint{0, 0}, []uint{21, 22})
+	e.bins([]uint{0, 1, 1}, []uint{21, 22, 23})
+	e.bins([]uint{0, 1, 0}, []uint{21, 22, 23})
+	// ref_idx_l0 0, 1, 0, 0
+	e.refIdx(0, 0)
+	e.refIdx(1, 0)
+	e.refIdx(0, 0)
+	e.refIdx(0, 2)
+	// mvd_l0 of the sub-macroblock partitions
+	e.mvd(1, 40, 0)
+	e.mvd(0, 47, 0)
+	e.mvd(0, 40, 0)
+	e.mvd(2, 47, 0)
+	e.mvd(-3, 40, 0)
+	e.mvd(0, 47, 0)
+	e.mvd(0, 40, 0)
+	e.mvd(0, 47, 0)
+	e.mvd(40, 40, 0)
+	e.mvd(0, 47, 0)
+	e.mvd(0, 40, 2)
+	e.mvd(-1, 47, 0)
+	e.mvd(0, 40, 1)
+	e.mvd(0, 47, 0)
+	e.mvd(0, 40, 2)
+	e.mvd(0, 47, 0)
+	e.mvd(0, 40, 0)
+	e.mvd(0, 47, 0)
+	// coded_block_pattern 0, the skipped macroblock counts as having coded luma blocks
+	e.bins([]uint{0, 0, 0, 0}, []uint{74, 74, 76, 76})
+	e.decision(77, 0)
+	e.terminate(0)
+
+	// MB 3: I_16x16_0_0_0
+	e.decision(12, 0)
+	e.decision(14, 1)
+	e.decision(17, 1)
+	e.terminate(0)
+	e.bins([]uint{0, 0, 0, 0}, []uint{18, 19, 20, 20})
+	// intra_chroma_pred_mode 0
+	e.decision(64, 0)
+	// mb_qp_delta 1
+	e.bins([]uint{1, 0}, []uint{60, 62})
+	// luma DC without coefficients, the inter macroblock to the left has no DC block
+	e.decision(85+2, 0)
+	e.terminate(1)
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(2, NALU_NONIDR, w.bytes()))
+	hdr := p.slice.h
+	assert.Equal(t, SliceP, hdr.Type())
+	assert.EqualValues(t, 1, hdr.FrameNum)
+	assert.EqualValues(t, 2, hdr.PicOrderCntLsb)
+	assert.EqualValues(t, 1, hdr.NumRefIdxL0ActiveMinus1)
+	assert.EqualValues(t, 1, hdr.CabacInitIdc)
+	assert.Equal(t, []RefPicListModificationOp{{ModificationOfPicNumsIdc: 0}}, hdr.RefPicListModification[0].Ops)
+	assert.EqualValues(t, 1, hdr.DecRefPicMarking.AdaptiveRefPicMarkingModeFlag)
+	assert.Equal(t, []MemoryManagementOp{{MemoryManagementControlOperation: 1}}, hdr.DecRefPicMarking.Ops)
+
+	require.Len(t, p.slice.MacroBlocks, 4)
+	mb := p.slice.MacroBlocks[0]
+	assert.False(t, mb.IsIntra())
+	assert.EqualValues(t, P_L0_L0_16x8, mb.mbType)
+	assert.EqualValues(t, 2, mb.PMBType().NumMbPart())
+	assert.Equal(t, 1, mb.RefIdx(0, 0))
+	assert.Equal(t, 0, mb.RefIdx(0, 10))
+	assert.Equal(t, -1, mb.RefIdx(1, 0))
+	assert.Equal(t, [2]int{5, -20}, mb.Mvd(0, 5))
+	assert.Equal(t, [2]int{0, 1}, mb.Mvd(0, 15))
+	assert.Equal(t, levels, mb.lumaResidual.GetBlock(BlockLevel, 0).Level())
+
+	mb = p.slice.MacroBlocks[1]
+	assert.True(t, mb.IsSkip())
+	assert.Equal(t, 26, mb.qpVal)
+
+	mb = p.slice.MacroBlocks[2]
+	assert.EqualValues(t, P_8x8, mb.mbType)
+	assert.Equal(t, [4]uint{0, 1, 2, 3}, mb.subMbType)
+	assert.Equal(t, 1, mb.RefIdx(0, 4))
+	assert.Equal(t, 0, mb.RefIdx(0, 12))
+	assert.Equal(t, [2]int{1, 0}, mb.Mvd(0, 3))
+	assert.Equal(t, [2]int{-3, 0}, mb.Mvd(0, 6))
+	assert.Equal(t, [2]int{40, 0}, mb.Mvd(0, 9))
+	assert.Equal(t, [2]int{0, -1}, mb.Mvd(0, 12))
+	assert.Equal(t, [2]int{0, 0}, mb.Mvd(0, 13))
+
+	mb = p.slice.MacroBlocks[3]
+	assert.True(t, mb.IsIntra())
+	assert.EqualValues(t, I_16x16_0_0_0, mb.IMBType().(*IMBTypeImpl).typeConst())
+	assert.Equal(t, 27, mb.qpVal)
+}
+
+// bSliceHeader writes the header of a B slice of a non-reference picture, with two active reference pictures in list 0
+// and one in list 1, up to and including the CABAC alignment.
+func bSliceHeader(w *bitWriter, cabacInitIdc uint) {
+	// first_mb_in_slice, slice_type (B), pic_parameter_set_id, frame_num, pic_order_cnt_lsb
+	w.ue(0)
+	w.ue(6)
+	w.ue(0)
+	w.bits(2, 4)
+	w.bits(4, 4)
+	// direct_spatial_mv_pred_flag
+	w.bit(1)
+	// num_ref_idx_active_override_flag, num_ref_idx_l0_active_minus1, num_ref_idx_l1_active_minus1
+	w.bit(1)
+	w.ue(1)
+	w.ue(0)
+	// ref_pic_list_modification_flag_l0 and _l1
+	w.bit(0)
+	w.bit(0)
+	w.ue(cabacInitIdc)
+	// slice_qp_delta, disable_deblocking_filter_idc
+	w.se(0)
+	w.ue(1)
+	w.alignOnes()
+}
+
+func TestCabacBSlice(t *testing.T) {
+	params := testStreamParams{widthInMbs: 4, heightInMbs: 1, cabac: true}
+
+	w := &bitWriter{}
+	bSliceHeader(w, 2)
+	e := newCabacEncoder(w, 26, SliceB, 2)
+
+	// MB 0: B_Direct_16x16, nothing but the coded_block_pattern 0
+	e.decision(24, 0)
+	e.decision(27, 0)
+	e.bins([]uint{0, 0, 0, 0}, []uint{73, 74, 75, 76})
+	e.decision(77, 0)
+	e.terminate(0)
+
+	// MB 1: B_Skip
+	e.decision(25, 1)
+	e.terminate(0)
+
+	// MB 2: B_L0_Bi_16x8, the skipped macroblock to the left does not count for the mb_type
+	e.decision(24, 0)
+	e.bins([]uint{1, 1, 1, 0, 0, 0, 0}, []uint{27, 30, 31, 32, 32, 32, 32})
+	// ref_idx_l0 1 and 1, ref_idx_l1 is inferred with a single reference picture
+	e.refIdx(1, 0)
+	e.refIdx(1, 2)
+	// mvd_l0 (3, 0) and (0, 4), mvd_l1 (-2, 0) of the second partition
+	e.mvd(3, 40, 0)
+	e.mvd(0, 47, 0)
+	e.mvd(0, 40, 1)
+	e.mvd(4, 47, 0)
+	e.mvd(-2, 40, 0)
+	e.mvd(0, 47, 0)
+	e.bins([]uint{0, 0, 0, 0}, []uint{74, 74, 76, 76})
+	e.decision(77, 0)
+	e.terminate(0)
+
+	// MB 3: B_8x8
+	e.decision(25, 0)
+	e.bins([]uint{1, 1, 1, 1, 1, 1}, []uint{28, 30, 31, 32, 32, 32})
+	// sub_mb_type B_Direct_8x8, B_L1_8x8, B_Bi_8x8, B_L0_4x8
+	e.decision(36, 0)
+	e.bins([]uint{1, 0, 1}, []uint{36, 37, 39})
+	e.bins([]uint{1, 1, 0, 0, 0}, []uint{36, 37, 38, 39, 39})
+	e.bins([]uint{1, 1, 0, 1, 0}, []uint{36, 37, 38, 39, 39})
+	// ref_idx_l0 1 and 0, the direct partition above does not count
+	e.refIdx(1, 1)
+	e.refIdx(0, 1)
+	// mvd_l0 (5, 0), (1, 1) and (0, 0)
+	e.mvd(5, 40, 0)
+	e.mvd(0, 47, 1)
+	e.mvd(1, 40, 1)
+	e.mvd(1, 47, 0)
+	e.mvd(0, 40, 0)
+	e.mvd(0, 47, 0)
+	// mvd_l1 (0, -7) and (2, 2)
+	e.mvd(0, 40, 0)
+	e.mvd(-7, 47, 0)
+	e.mvd(2, 40, 0)
+	e.mvd(2, 47, 0)
+	e.bins([]uint{0, 0, 0, 0}, []uint{74, 74, 76, 76})
+	e.decision(77, 0)
+	e.terminate(1)
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(0, NALU_NONIDR, w.bytes()))
+	hdr := p.slice.h
+	assert.Equal(t, SliceB, hdr.Type())
+	assert.EqualValues(t, 1, hdr.DirectSpatialMvPredFlag)
+	assert.EqualValues(t, 1, hdr.NumRefIdxL0ActiveMinus1)
+	assert.EqualValues(t, 0, hdr.NumRefIdxL1ActiveMinus1)
+	assert.EqualValues(t, 2, hdr.CabacInitIdc)
+	assert.Nil(t, hdr.DecRefPicMarking)
+
+	require.Len(t, p.slice.MacroBlocks, 4)
+	mb := p.slice.MacroBlocks[0]
+	assert.False(t, mb.IsIntra())
+	assert.EqualValues(t, B_Direct_16x16, mb.mbType)
+	assert.Equal(t, Direct, mb.MbPartPredMode(0))
+	assert.Equal(t, -1, mb.RefIdx(0, 0))
+
+	mb = p.slice.MacroBlocks[1]
+	assert.True(t, mb.IsSkip())
+	assert.Equal(t, Direct, mb.MbPartPredMode(0))
+	assert.Equal(t, -1, mb.RefIdx(0, 0))
+
+	mb = p.slice.MacroBlocks[2]
+	assert.EqualValues(t, B_L0_Bi_16x8, mb.mbType)
+	assert.Equal(t, BiPred, mb.MbPartPredMode(1))
+	assert.Equal(t, 1, mb.RefIdx(0, 0))
+	assert.Equal(t, 1, mb.RefIdx(0, 8))
+	assert.Equal(t, -1, mb.RefIdx(1, 0))
+	assert.Equal(t, 0, mb.RefIdx(1, 8))
+	assert.Equal(t, [2]int{3, 0}, mb.Mvd(0, 0))
+	assert.Equal(t, [2]int{0, 4}, mb.Mvd(0, 10))
+	assert.Equal(t, [2]int{-2, 0}, mb.Mvd(1, 10))
+	assert.Equal(t, [2]int{0, 0}, mb.Mvd(1, 0))
+
+	mb = p.slice.MacroBlocks[3]
+	assert.EqualValues(t, B_8x8, mb.mbType)
+	assert.Equal(t, [4]uint{0, 2, 3, 5}, mb.subMbType)
+	assert.True(t, mb.SubMBType(0).IsDirect())
+	assert.Equal(t, -1, mb.RefIdx(0, 0))
+	assert.Equal(t, -1, mb.RefIdx(0, 4))
+	assert.Equal(t, 0, mb.RefIdx(1, 4))
+	assert.Equal(t, 1, mb.RefIdx(0, 8))
+	assert.Equal(t, 0, mb.RefIdx(1, 8))
+	assert.Equal(t, 0, mb.RefIdx(0, 12))
+	assert.Equal(t, [2]int{0, -7}, mb.Mvd(1, 4))
+	assert.Equal(t, [2]int{5, 0}, mb.Mvd(0, 8))
+	assert.Equal(t, [2]int{2, 2}, mb.Mvd(1, 8))
+	assert.Equal(t, [2]int{1, 1}, mb.Mvd(0, 14))
+	assert.Equal(t, [2]int{0, 0}, mb.Mvd(0, 13))
+}
+
+func TestSliceHeaderFieldPOCType1(t *testing.T) {
+	params := testStreamParams{widthInMbs: 2, heightInMbs: 1, picOrderCntType1: true, fields: true, picOrderPresent: true, weightedPred: true, redundantPicCnt: true}
+
+	w := &bitWriter{}
+	// first_mb_in_slice, slice_type (P), pic_parameter_set_id, frame_num
+	w.ue(0)
+	w.ue(5)
+	w.ue(0)
+	w.bits(3, 4)
+	// field_pic_flag, bottom_field_flag
+	w.bit(1)
+	w.bit(1)
+	// delta_pic_order_cnt[0], the one of the bottom field is absent in field pictures, redundant_pic_cnt
+	w.se(-3)
+	w.ue(1)
+	// num_ref_idx_active_override_flag, num_ref_idx_l0_active_minus1, ref_pic_list_modification_flag_l0
+	w.bit(1)
+	w.ue(1)
+	w.bit(0)
+	// pred_weight_table: luma_log2_weight_denom, chroma_log2_weight_denom
+	w.ue(6)
+	w.ue(5)
+	// weights of the first reference picture
+	w.bit(1)
+	w.se(70)
+	w.se(-2)
+	w.bit(0)
+	// weights of the second reference picture
+	w.bit(0)
+	w.bit(1)
+	w.se(30)
+	w.se(1)
+	w.se(31)
+	w.se(-1)
+	// adaptive_ref_pic_marking_mode_flag, slice_qp_delta, disable_deblocking_filter_idc
+	w.bit(0)
+	w.se(2)
+	w.ue(1)
+	// slice data: mb_skip_run
+	w.ue(1)
+	w.trailing()
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(2, NALU_NONIDR, w.bytes()))
+	assert.EqualValues(t, 1, p.SPS.PicOrderCntType)
+	assert.Equal(t, -1, p.SPS.OffsetForNonRefPic)
+	assert.Equal(t, []int{2}, p.SPS.OffsetForRefFrame)
+	hdr := p.slice.h
+	assert.Equal(t, SliceP, hdr.Type())
+	assert.EqualValues(t, 3, hdr.FrameNum)
+	assert.EqualValues(t, 1, hdr.FieldPicFlag)
+	assert.EqualValues(t, 1, hdr.BottomFieldFlag)
+	assert.Equal(t, [2]int{-3, 0}, hdr.DeltaPicOrderCnt)
+	assert.EqualValues(t, 1, hdr.RedundantPicCnt)
+	assert.EqualValues(t, 1, hdr.NumRefIdxL0ActiveMinus1)
+	assert.Equal(t, &PredWeightTable{
+		LumaLog2WeightDenom:   6,
+		ChromaLog2WeightDenom: 5,
+		Weights: [2][]PredWeight{{
+			{LumaWeightFlag: 1, LumaWeight: 70, LumaOffset: -2, ChromaWeight: [2]int{32, 32}},
+			{LumaWeight: 64, ChromaWeightFlag: 1, ChromaWeight: [2]int{30, 31}, ChromaOffset: [2]int{1, -1}},
+		}},
+	}, hdr.PredWeightTable)
+	assert.EqualValues(t, 0, hdr.DecRefPicMarking.AdaptiveRefPicMarkingModeFlag)
+	assert.Equal(t, 2, hdr.SliceQPDelta)
+	assert.EqualValues(t, 1, hdr.DisableDeblockingFilterIdc)
+	require.Len(t, p.slice.MacroBlocks, 1)
+	assert.True(t, p.slice.MacroBlocks[0].IsSkip())
+}
+
+func TestSliceHeaderSP(t *testing.T) {
+	params := testStreamParams{widthInMbs: 2, heightInMbs: 1, picOrderPresent: true}
+
+	w := &bitWriter{}
+	// first_mb_in_slice, slice_type (SP), pic_parameter_set_id, frame_num, pic_order_cnt_lsb, delta_pic_order_cnt_bottom
+	w.ue(1)
+	w.ue(8)
+	w.ue(0)
+	w.bits(1, 4)
+	w.bits(4, 4)
+	w.se(-1)
+	// num_ref_idx_active_override_flag, ref_pic_list_modification_flag_l0
+	w.bit(0)
+	w.bit(0)
+	// the picture is not a reference, so dec_ref_pic_marking is absent
+	// slice_qp_delta, sp_for_switch_flag, slice_qs_delta, disable_deblocking_filter_idc, slice_alpha/beta_offset_div2
+	w.se(-4)
+	w.bit(1)
+	w.se(3)
+	w.ue(0)
+	w.se(-2)
+	w.se(1)
+	// slice data: mb_skip_run
+	w.ue(1)
+	w.trailing()
+
+	p := parseTestStream(t, params.sps(), params.pps(), nalUnit(0, NALU_NONIDR, w.bytes()))
+	hdr := p.slice.h
+	assert.Equal(t, SliceSP, hdr.Type())
+	assert.EqualValues(t, 1, hdr.FirstMbInSlice)
+	assert.EqualValues(t, 4, hdr.PicOrderCntLsb)
+	assert.Equal(t, -1, hdr.DeltaPicOrderCntBottom)
+	assert.Nil(t, hdr.PredWeightTable)
+	assert.Nil(t, hdr.DecRefPicMarking)
+	assert.Equal(t, -4, hdr.SliceQPDelta)
+	assert.EqualValues(t, 1, hdr.SpForSwitchFlag)
+	assert.Equal(t, 3, hdr.SliceQSDelta)
+	assert.Equal(t, -2, hdr.SliceAlphaC0OffsetDiv2)
+	assert.Equal(t, 1, hdr.SliceBetaOffsetDiv2)
+	require.Len(t, p.slice.MacroBlocks, 1)
+	assert.True(t, p.slice.MacroBlocks[1].IsSkip())
+}
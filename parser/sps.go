@@ -32,6 +32,11 @@ type SPSInfo struct {
 	Log2MaxFrameNumMinus4       uint
 	PicOrderCntType             uint
 	Log2MaxPicOrderCntLsbMinus4 uint
+	// Only present for pic_order_cnt_type 1
+	DeltaPicOrderAlwaysZeroFlag uint
+	OffsetForNonRefPic          int
+	OffsetForTopToBottomField   int
+	OffsetForRefFrame           []int
 
 	BitDepthLumaMinus8   uint
 	BitDepthChromaMinus8 uint
@@ -43,6 +48,7 @@ type SPSInfo struct {
 	PicHeightInMapUnitsMinus1 uint
 	FrameMbsOnlyFlag          uint
 	MbAdaptiveFrameFieldFlag  uint
+	Direct8x8InferenceFlag    uint
 	// MbWidth                   uint
 	// MbHeight                  uint
 
@@ -106,7 +112,7 @@ func (s *SPSInfo) MbHeightC() uint {
 		return 0
 	}
 
-	return 16 / s.SubWidthC()
+	return 16 / s.SubHeightC()
 }
 
 func (s *SPSInfo) PicWidthInMbs() uint {
@@ -137,6 +143,32 @@ func (s *SPSInfo) PicSizeInMbs() uint {
 	return s.PicWidthInMbs() * s.PicHeightInMbs()
 }
 
+// CropUnitX is the horizontal unit of the frame cropping offsets.
+func (s *SPSInfo) CropUnitX() uint {
+	if s.ChromaArrayType() == 0 {
+		return 1
+	}
+	return s.SubWidthC()
+}
+
+// CropUnitY is the vertical unit of the frame cropping offsets.
+func (s *SPSInfo) CropUnitY() uint {
+	if s.ChromaArrayType() == 0 {
+		return 2 - s.FrameMbsOnlyFlag
+	}
+	return s.SubHeightC() * (2 - s.FrameMbsOnlyFlag)
+}
+
+// FrameWidth returns the width of the decoded frame in luma samples, after cropping.
+func (s *SPSInfo) FrameWidth() uint {
+	return s.PicWidthInSamplesL() - s.CropUnitX()*(s.CropLeft+s.CropRight)
+}
+
+// FrameHeight returns the height of the decoded frame in luma samples, after cropping.
+func (s *SPSInfo) FrameHeight() uint {
+	return s.FrameHeightInMbs()*16 - s.CropUnitY()*(s.CropTop+s.CropBottom)
+}
+
 func (s *SPSInfo) ChromaArrayType() uint {
 	if s.SeparateColourPlaneFlag == 0 {
 		return s.ChromaFormatIdc
@@ -157,6 +189,9 @@ func (p *SPSParser) ParseInfo() *SPSInfo {
 	// seq_parameter_set_id
 	s.Id = p.ReadUE()
 
+	// inferred to be 4:2:0 when not present
+	s.ChromaFormatIdc = 1
+
 	if s.ProfileIdc == 100 || s.ProfileIdc == 110 ||
 		s.ProfileIdc == 122 || s.ProfileIdc == 244 ||
 		s.ProfileIdc == 44 || s.ProfileIdc == 83 ||
@@ -221,16 +256,13 @@ func (p *SPSParser) ParseInfo() *SPSInfo {
 		// log2_max_pic_order_cnt_lsb_minus4
 		s.Log2MaxPicOrderCntLsbMinus4 = p.ReadUE()
 	} else if pic_order_cnt_type == 1 {
-		// delta_pic_order_always_zero_flag
-		p.ReadBits(1)
-		// offset_for_non_ref_pic
-		p.ReadSE()
-		// offset_for_top_to_bottom_field
-		p.ReadSE()
+		s.DeltaPicOrderAlwaysZeroFlag = p.ReadBits(1)
+		s.OffsetForNonRefPic = p.ReadSE()
+		s.OffsetForTopToBottomField = p.ReadSE()
 		var num_ref_frames_in_pic_order_cnt_cycle uint
 		num_ref_frames_in_pic_order_cnt_cycle = p.ReadUE()
 		for i := uint(0); i < num_ref_frames_in_pic_order_cnt_cycle; i++ {
-			p.ReadSE()
+			s.OffsetForRefFrame = append(s.OffsetForRefFrame, p.ReadSE())
 		}
 	}
 
@@ -251,8 +283,7 @@ func (p *SPSParser) ParseInfo() *SPSInfo {
 		s.MbAdaptiveFrameFieldFlag = p.ReadBits(1)
 	}
 
-	// direct_8x8_inference_flag
-	p.ReadBits(1)
+	s.Direct8x8InferenceFlag = p.ReadBits(1)
 
 	var frame_cropping_flag uint
 	frame_cropping_flag = p.ReadBits(1)
@@ -526,14 +526,14 @@ var kuiCtxIdxBlockCatOffset = [4][14]uint{
 	{0, 10, 20, 30, 39, 0, 0, 10, 20, 0, 0, 10, 20, 0},
 }
 
-/* Table 9-41 */
+/* Table 9-41, indexed by the value of the prior bin b1 or b3 */
 var kuiCtxIdxIncPrior = map[uint][2]uint{
-	3:  {5, 6}, // use code to decide between two possibilities
+	3:  {6, 5}, // binIdx 5 uses one more, see CtxIdxIncPrior
 	14: {2, 3},
-	17: {2, 3},
-	27: {4, 5},
-	32: {2, 3},
-	36: {2, 3},
+	17: {3, 2},
+	27: {5, 4},
+	32: {3, 2},
+	36: {3, 2},
 }
 
 /* Table 9-43 */
@@ -553,3 +553,670 @@ var kuiLastSignificantCoeffFlagOffset8x8 = [63]uint{
 	3, 3, 3, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4, 4, 4, 4,
 	5, 5, 5, 5, 6, 6, 6, 6, 7, 7, 7, 7, 8, 8, 8,
 }
+
+/* Table 9-4, coded_block_pattern of codeNum for Intra_4x4 / Intra_8x8 and Inter macroblocks, when ChromaArrayType is 1 or 2 */
+var kuiCodedBlockPatternMe = [2][48]uint{
+	{
+		47, 31, 15, 0, 23, 27, 29, 30, 7, 11, 13, 14, 39, 43, 45, 46,
+		16, 3, 5, 10, 12, 19, 21, 26, 28, 35, 37, 42, 44, 1, 2, 4,
+		8, 17, 18, 20, 24, 6, 9, 22, 25, 32, 33, 34, 36, 40, 38, 41,
+	},
+	{
+		0, 16, 1, 2, 4, 8, 32, 3, 5, 10, 12, 15, 47, 7, 11, 13,
+		14, 6, 9, 31, 35, 37, 42, 44, 33, 34, 36, 40, 39, 43, 45, 46,
+		17, 18, 20, 24, 19, 21, 26, 28, 23, 27, 29, 30, 22, 25, 38, 41,
+	},
+}
+
+/* Table 9-4, coded_block_pattern of codeNum for Intra_4x4 / Intra_8x8 and Inter macroblocks, when ChromaArrayType is 0 or 3 */
+var kuiCodedBlockPatternMeNoChroma = [2][16]uint{
+	{15, 0, 7, 11, 13, 14, 3, 5, 10, 12, 1, 2, 4, 8, 6, 9},
+	{0, 1, 2, 4, 8, 3, 5, 10, 12, 15, 7, 11, 13, 14, 6, 9},
+}
+
+/* Table 9-5, coeff_token for the columns 0 <= nC < 2, 2 <= nC < 4, 4 <= nC < 8, 8 <= nC, nC == -1, nC == -2 */
+var kuiCoeffTokenTable = [6]map[string]uint{
+	// 0 <= nC < 2
+	{
+		"1":                coeffToken(0, 0),
+		"01":               coeffToken(1, 1),
+		"001":              coeffToken(2, 2),
+		"00011":            coeffToken(3, 3),
+		"000011":           coeffToken(3, 4),
+		"000100":           coeffToken(1, 2),
+		"000101":           coeffToken(0, 1),
+		"0000100":          coeffToken(3, 5),
+		"0000101":          coeffToken(2, 3),
+		"00000100":         coeffToken(3, 6),
+		"00000101":         coeffToken(2, 4),
+		"00000110":         coeffToken(1, 3),
+		"00000111":         coeffToken(0, 2),
+		"000000100":        coeffToken(3, 7),
+		"000000101":        coeffToken(2, 5),
+		"000000110":        coeffToken(1, 4),
+		"000000111":        coeffToken(0, 3),
+		"0000000100":       coeffToken(3, 8),
+		"0000000101":       coeffToken(2, 6),
+		"0000000110":       coeffToken(1, 5),
+		"0000000111":       coeffToken(0, 4),
+		"00000000100":      coeffToken(3, 9),
+		"00000000101":      coeffToken(2, 7),
+		"00000000110":      coeffToken(1, 6),
+		"00000000111":      coeffToken(0, 5),
+		"0000000001000":    coeffToken(0, 8),
+		"0000000001001":    coeffToken(2, 9),
+		"0000000001010":    coeffToken(1, 8),
+		"0000000001011":    coeffToken(0, 7),
+		"0000000001100":    coeffToken(3, 10),
+		"0000000001101":    coeffToken(2, 8),
+		"0000000001110":    coeffToken(1, 7),
+		"0000000001111":    coeffToken(0, 6),
+		"00000000001000":   coeffToken(3, 12),
+		"00000000001001":   coeffToken(2, 11),
+		"00000000001010":   coeffToken(1, 10),
+		"00000000001011":   coeffToken(0, 10),
+		"00000000001100":   coeffToken(3, 11),
+		"00000000001101":   coeffToken(2, 10),
+		"00000000001110":   coeffToken(1, 9),
+		"00000000001111":   coeffToken(0, 9),
+		"000000000000001":  coeffToken(1, 13),
+		"000000000001000":  coeffToken(3, 14),
+		"000000000001001":  coeffToken(2, 13),
+		"000000000001010":  coeffToken(1, 12),
+		"000000000001011":  coeffToken(0, 12),
+		"000000000001100":  coeffToken(3, 13),
+		"000000000001101":  coeffToken(2, 12),
+		"000000000001110":  coeffToken(1, 11),
+		"000000000001111":  coeffToken(0, 11),
+		"0000000000000100": coeffToken(0, 16),
+		"0000000000000101": coeffToken(2, 16),
+		"0000000000000110": coeffToken(1, 16),
+		"0000000000000111": coeffToken(0, 15),
+		"0000000000001000": coeffToken(3, 16),
+		"0000000000001001": coeffToken(2, 15),
+		"0000000000001010": coeffToken(1, 15),
+		"0000000000001011": coeffToken(0, 14),
+		"0000000000001100": coeffToken(3, 15),
+		"0000000000001101": coeffToken(2, 14),
+		"0000000000001110": coeffToken(1, 14),
+		"0000000000001111": coeffToken(0, 13),
+	},
+	// 2 <= nC < 4
+	{
+		"10":             coeffToken(1, 1),
+		"11":             coeffToken(0, 0),
+		"011":            coeffToken(2, 2),
+		"0100":           coeffToken(3, 4),
+		"0101":           coeffToken(3, 3),
+		"00110":          coeffToken(3, 5),
+		"00111":          coeffToken(1, 2),
+		"000100":         coeffToken(3, 7),
+		"000101":         coeffToken(2, 4),
+		"000110":         coeffToken(1, 4),
+		"000111":         coeffToken(0, 2),
+		"001000":         coeffToken(3, 6),
+		"001001":         coeffToken(2, 3),
+		"001010":         coeffToken(1, 3),
+		"001011":         coeffToken(0, 1),
+		"0000100":        coeffToken(3, 8),
+		"0000101":        coeffToken(2, 5),
+		"0000110":        coeffToken(1, 5),
+		"0000111":        coeffToken(0, 3),
+		"00000100":       coeffToken(0, 5),
+		"00000101":       coeffToken(2, 6),
+		"00000110":       coeffToken(1, 6),
+		"00000111":       coeffToken(0, 4),
+		"000000100":      coeffToken(3, 9),
+		"000000101":      coeffToken(2, 7),
+		"000000110":      coeffToken(1, 7),
+		"000000111":      coeffToken(0, 6),
+		"00000001000":    coeffToken(3, 11),
+		"00000001001":    coeffToken(2, 9),
+		"00000001010":    coeffToken(1, 9),
+		"00000001011":    coeffToken(0, 8),
+		"00000001100":    coeffToken(3, 10),
+		"00000001101":    coeffToken(2, 8),
+		"00000001110":    coeffToken(1, 8),
+		"00000001111":    coeffToken(0, 7),
+		"000000001000":   coeffToken(0, 11),
+		"000000001001":   coeffToken(2, 11),
+		"000000001010":   coeffToken(1, 11),
+		"000000001011":   coeffToken(0, 10),
+		"000000001100":   coeffToken(3, 12),
+		"000000001101":   coeffToken(2, 10),
+		"000000001110":   coeffToken(1, 10),
+		"000000001111":   coeffToken(0, 9),
+		"0000000000001":  coeffToken(3, 15),
+		"0000000000110":  coeffToken(2, 14),
+		"0000000000111":  coeffToken(0, 14),
+		"0000000001000":  coeffToken(3, 14),
+		"0000000001001":  coeffToken(2, 13),
+		"0000000001010":  coeffToken(1, 13),
+		"0000000001011":  coeffToken(0, 13),
+		"0000000001100":  coeffToken(3, 13),
+		"0000000001101":  coeffToken(2, 12),
+		"0000000001110":  coeffToken(1, 12),
+		"0000000001111":  coeffToken(0, 12),
+		"00000000000100": coeffToken(3, 16),
+		"00000000000101": coeffToken(2, 16),
+		"00000000000110": coeffToken(1, 16),
+		"00000000000111": coeffToken(0, 16),
+		"00000000001000": coeffToken(1, 15),
+		"00000000001001": coeffToken(0, 15),
+		"00000000001010": coeffToken(2, 15),
+		"00000000001011": coeffToken(1, 14),
+	},
+	// 4 <= nC < 8
+	{
+		"1000":       coeffToken(3, 7),
+		"1001":       coeffToken(3, 6),
+		"1010":       coeffToken(3, 5),
+		"1011":       coeffToken(3, 4),
+		"1100":       coeffToken(3, 3),
+		"1101":       coeffToken(2, 2),
+		"1110":       coeffToken(1, 1),
+		"1111":       coeffToken(0, 0),
+		"01000":      coeffToken(1, 5),
+		"01001":      coeffToken(2, 5),
+		"01010":      coeffToken(1, 4),
+		"01011":      coeffToken(2, 4),
+		"01100":      coeffToken(1, 3),
+		"01101":      coeffToken(3, 8),
+		"01110":      coeffToken(2, 3),
+		"01111":      coeffToken(1, 2),
+		"001000":     coeffToken(0, 3),
+		"001001":     coeffToken(2, 7),
+		"001010":     coeffToken(1, 7),
+		"001011":     coeffToken(0, 2),
+		"001100":     coeffToken(3, 9),
+		"001101":     coeffToken(2, 6),
+		"001110":     coeffToken(1, 6),
+		"001111":     coeffToken(0, 1),
+		"0001000":    coeffToken(0, 7),
+		"0001001":    coeffToken(0, 6),
+		"0001010":    coeffToken(2, 9),
+		"0001011":    coeffToken(0, 5),
+		"0001100":    coeffToken(3, 10),
+		"0001101":    coeffToken(2, 8),
+		"0001110":    coeffToken(1, 8),
+		"0001111":    coeffToken(0, 4),
+		"00001000":   coeffToken(3, 12),
+		"00001001":   coeffToken(2, 11),
+		"00001010":   coeffToken(1, 10),
+		"00001011":   coeffToken(0, 9),
+		"00001100":   coeffToken(3, 11),
+		"00001101":   coeffToken(2, 10),
+		"00001110":   coeffToken(1, 9),
+		"00001111":   coeffToken(0, 8),
+		"000000111":  coeffToken(1, 13),
+		"000001000":  coeffToken(0, 12),
+		"000001001":  coeffToken(2, 13),
+		"000001010":  coeffToken(1, 12),
+		"000001011":  coeffToken(0, 11),
+		"000001100":  coeffToken(3, 13),
+		"000001101":  coeffToken(2, 12),
+		"000001110":  coeffToken(1, 11),
+		"000001111":  coeffToken(0, 10),
+		"0000000001": coeffToken(0, 16),
+		"0000000010": coeffToken(3, 16),
+		"0000000011": coeffToken(2, 16),
+		"0000000100": coeffToken(1, 16),
+		"0000000101": coeffToken(0, 15),
+		"0000000110": coeffToken(3, 15),
+		"0000000111": coeffToken(2, 15),
+		"0000001000": coeffToken(1, 15),
+		"0000001001": coeffToken(0, 14),
+		"0000001010": coeffToken(3, 14),
+		"0000001011": coeffToken(2, 14),
+		"0000001100": coeffToken(1, 14),
+		"0000001101": coeffToken(0, 13),
+	},
+	// 8 <= nC
+	{
+		"000000": coeffToken(0, 1),
+		"000001": coeffToken(1, 1),
+		"000011": coeffToken(0, 0),
+		"000100": coeffToken(0, 2),
+		"000101": coeffToken(1, 2),
+		"000110": coeffToken(2, 2),
+		"001000": coeffToken(0, 3),
+		"001001": coeffToken(1, 3),
+		"001010": coeffToken(2, 3),
+		"001011": coeffToken(3, 3),
+		"001100": coeffToken(0, 4),
+		"001101": coeffToken(1, 4),
+		"001110": coeffToken(2, 4),
+		"001111": coeffToken(3, 4),
+		"010000": coeffToken(0, 5),
+		"010001": coeffToken(1, 5),
+		"010010": coeffToken(2, 5),
+		"010011": coeffToken(3, 5),
+		"010100": coeffToken(0, 6),
+		"010101": coeffToken(1, 6),
+		"010110": coeffToken(2, 6),
+		"010111": coeffToken(3, 6),
+		"011000": coeffToken(0, 7),
+		"011001": coeffToken(1, 7),
+		"011010": coeffToken(2, 7),
+		"011011": coeffToken(3, 7),
+		"011100": coeffToken(0, 8),
+		"011101": coeffToken(1, 8),
+		"011110": coeffToken(2, 8),
+		"011111": coeffToken(3, 8),
+		"100000": coeffToken(0, 9),
+		"100001": coeffToken(1, 9),
+		"100010": coeffToken(2, 9),
+		"100011": coeffToken(3, 9),
+		"100100": coeffToken(0, 10),
+		"100101": coeffToken(1, 10),
+		"100110": coeffToken(2, 10),
+		"100111": coeffToken(3, 10),
+		"101000": coeffToken(0, 11),
+		"101001": coeffToken(1, 11),
+		"101010": coeffToken(2, 11),
+		"101011": coeffToken(3, 11),
+		"101100": coeffToken(0, 12),
+		"101101": coeffToken(1, 12),
+		"101110": coeffToken(2, 12),
+		"101111": coeffToken(3, 12),
+		"110000": coeffToken(0, 13),
+		"110001": coeffToken(1, 13),
+		"110010": coeffToken(2, 13),
+		"110011": coeffToken(3, 13),
+		"110100": coeffToken(0, 14),
+		"110101": coeffToken(1, 14),
+		"110110": coeffToken(2, 14),
+		"110111": coeffToken(3, 14),
+		"111000": coeffToken(0, 15),
+		"111001": coeffToken(1, 15),
+		"111010": coeffToken(2, 15),
+		"111011": coeffToken(3, 15),
+		"111100": coeffToken(0, 16),
+		"111101": coeffToken(1, 16),
+		"111110": coeffToken(2, 16),
+		"111111": coeffToken(3, 16),
+	},
+	// nC == -1
+	{
+		"1":        coeffToken(1, 1),
+		"01":       coeffToken(0, 0),
+		"001":      coeffToken(2, 2),
+		"000010":   coeffToken(0, 4),
+		"000011":   coeffToken(0, 3),
+		"000100":   coeffToken(0, 2),
+		"000101":   coeffToken(3, 3),
+		"000110":   coeffToken(1, 2),
+		"000111":   coeffToken(0, 1),
+		"0000000":  coeffToken(3, 4),
+		"0000010":  coeffToken(2, 3),
+		"0000011":  coeffToken(1, 3),
+		"00000010": coeffToken(2, 4),
+		"00000011": coeffToken(1, 4),
+	},
+	// nC == -2
+	{
+		"1":             coeffToken(0, 0),
+		"01":            coeffToken(1, 1),
+		"001":           coeffToken(2, 2),
+		"00001":         coeffToken(3, 3),
+		"000001":        coeffToken(3, 4),
+		"0001000":       coeffToken(3, 6),
+		"0001001":       coeffToken(3, 5),
+		"0001010":       coeffToken(2, 4),
+		"0001011":       coeffToken(2, 3),
+		"0001100":       coeffToken(1, 3),
+		"0001101":       coeffToken(1, 2),
+		"0001110":       coeffToken(0, 2),
+		"0001111":       coeffToken(0, 1),
+		"000000100":     coeffToken(2, 5),
+		"000000101":     coeffToken(1, 4),
+		"000000110":     coeffToken(0, 4),
+		"000000111":     coeffToken(0, 3),
+		"0000000100":    coeffToken(3, 7),
+		"0000000101":    coeffToken(2, 6),
+		"0000000110":    coeffToken(1, 5),
+		"0000000111":    coeffToken(0, 5),
+		"00000000100":   coeffToken(3, 8),
+		"00000000101":   coeffToken(2, 7),
+		"00000000110":   coeffToken(1, 6),
+		"00000000111":   coeffToken(0, 6),
+		"000000000100":  coeffToken(2, 8),
+		"000000000101":  coeffToken(1, 8),
+		"000000000110":  coeffToken(1, 7),
+		"000000000111":  coeffToken(0, 7),
+		"0000000000111": coeffToken(0, 8),
+	},
+}
+
+/* Tables 9-7 and 9-8, total_zeros of 4x4 blocks, indexed by tzVlcIndex - 1 */
+var kuiTotalZeros4x4 = [15]map[string]uint{
+	// tzVlcIndex 1
+	{
+		"1":         0,
+		"010":       2,
+		"011":       1,
+		"0010":      4,
+		"0011":      3,
+		"00010":     6,
+		"00011":     5,
+		"000010":    8,
+		"000011":    7,
+		"0000010":   10,
+		"0000011":   9,
+		"00000010":  12,
+		"00000011":  11,
+		"000000001": 15,
+		"000000010": 14,
+		"000000011": 13,
+	},
+	// tzVlcIndex 2
+	{
+		"011":    4,
+		"100":    3,
+		"101":    2,
+		"110":    1,
+		"111":    0,
+		"0010":   8,
+		"0011":   7,
+		"0100":   6,
+		"0101":   5,
+		"00010":  10,
+		"00011":  9,
+		"000000": 14,
+		"000001": 13,
+		"000010": 12,
+		"000011": 11,
+	},
+	// tzVlcIndex 3
+	{
+		"011":    7,
+		"100":    6,
+		"101":    3,
+		"110":    2,
+		"111":    1,
+		"0010":   8,
+		"0011":   5,
+		"0100":   4,
+		"0101":   0,
+		"00001":  12,
+		"00010":  10,
+		"00011":  9,
+		"000000": 13,
+		"000001": 11,
+	},
+	// tzVlcIndex 4
+	{
+		"011":   8,
+		"100":   6,
+		"101":   5,
+		"110":   4,
+		"111":   1,
+		"0010":  9,
+		"0011":  7,
+		"0100":  3,
+		"0101":  2,
+		"00000": 12,
+		"00001": 11,
+		"00010": 10,
+		"00011": 0,
+	},
+	// tzVlcIndex 5
+	{
+		"011":   7,
+		"100":   6,
+		"101":   5,
+		"110":   4,
+		"111":   3,
+		"0001":  10,
+		"0010":  8,
+		"0011":  2,
+		"0100":  1,
+		"0101":  0,
+		"00000": 11,
+		"00001": 9,
+	},
+	// tzVlcIndex 6
+	{
+		"001":    9,
+		"010":    7,
+		"011":    6,
+		"100":    5,
+		"101":    4,
+		"110":    3,
+		"111":    2,
+		"0001":   8,
+		"00001":  1,
+		"000000": 10,
+		"000001": 0,
+	},
+	// tzVlcIndex 7
+	{
+		"11":     5,
+		"001":    8,
+		"010":    6,
+		"011":    4,
+		"100":    3,
+		"101":    2,
+		"0001":   7,
+		"00001":  1,
+		"000000": 9,
+		"000001": 0,
+	},
+	// tzVlcIndex 8
+	{
+		"10":     5,
+		"11":     4,
+		"001":    7,
+		"010":    6,
+		"011":    3,
+		"0001":   1,
+		"00001":  2,
+		"000000": 8,
+		"000001": 0,
+	},
+	// tzVlcIndex 9
+	{
+		"01":     6,
+		"10":     4,
+		"11":     3,
+		"001":    5,
+		"0001":   2,
+		"00001":  7,
+		"000000": 1,
+		"000001": 0,
+	},
+	// tzVlcIndex 10
+	{
+		"01":    5,
+		"10":    4,
+		"11":    3,
+		"001":   2,
+		"0001":  6,
+		"00000": 1,
+		"00001": 0,
+	},
+	// tzVlcIndex 11
+	{
+		"1":    4,
+		"001":  2,
+		"010":  3,
+		"011":  5,
+		"0000": 0,
+		"0001": 1,
+	},
+	// tzVlcIndex 12
+	{
+		"1":    3,
+		"01":   2,
+		"001":  4,
+		"0000": 0,
+		"0001": 1,
+	},
+	// tzVlcIndex 13
+	{
+		"1":   2,
+		"01":  3,
+		"000": 0,
+		"001": 1,
+	},
+	// tzVlcIndex 14
+	{
+		"1":  2,
+		"00": 0,
+		"01": 1,
+	},
+	// tzVlcIndex 15
+	{
+		"0": 0,
+		"1": 1,
+	},
+}
+
+/* Table 9-9 a), total_zeros of 2x2 chroma DC blocks, indexed by tzVlcIndex - 1 */
+var kuiTotalZerosChromaDC420 = [3]map[string]uint{
+	// tzVlcIndex 1
+	{
+		"1":   0,
+		"01":  1,
+		"000": 3,
+		"001": 2,
+	},
+	// tzVlcIndex 2
+	{
+		"1":  0,
+		"00": 2,
+		"01": 1,
+	},
+	// tzVlcIndex 3
+	{
+		"0": 1,
+		"1": 0,
+	},
+}
+
+/* Table 9-9 b), total_zeros of 2x4 chroma DC blocks, indexed by tzVlcIndex - 1 */
+var kuiTotalZerosChromaDC422 = [7]map[string]uint{
+	// tzVlcIndex 1
+	{
+		"1":     0,
+		"010":   1,
+		"011":   2,
+		"0001":  5,
+		"0010":  3,
+		"0011":  4,
+		"00000": 7,
+		"00001": 6,
+	},
+	// tzVlcIndex 2
+	{
+		"01":  1,
+		"000": 0,
+		"001": 2,
+		"100": 3,
+		"101": 4,
+		"110": 5,
+		"111": 6,
+	},
+	// tzVlcIndex 3
+	{
+		"01":  2,
+		"10":  3,
+		"000": 0,
+		"001": 1,
+		"110": 4,
+		"111": 5,
+	},
+	// tzVlcIndex 4
+	{
+		"00":  1,
+		"01":  2,
+		"10":  3,
+		"110": 0,
+		"111": 4,
+	},
+	// tzVlcIndex 5
+	{
+		"00": 0,
+		"01": 1,
+		"10": 2,
+		"11": 3,
+	},
+	// tzVlcIndex 6
+	{
+		"1":  2,
+		"00": 0,
+		"01": 1,
+	},
+	// tzVlcIndex 7
+	{
+		"0": 0,
+		"1": 1,
+	},
+}
+
+/* Table 9-10, run_before, indexed by Min(zerosLeft, 7) - 1 */
+var kuiRunBefore = [7]map[string]uint{
+	// zerosLeft 1
+	{
+		"0": 1,
+		"1": 0,
+	},
+	// zerosLeft 2
+	{
+		"1":  0,
+		"00": 2,
+		"01": 1,
+	},
+	// zerosLeft 3
+	{
+		"00": 3,
+		"01": 2,
+		"10": 1,
+		"11": 0,
+	},
+	// zerosLeft 4
+	{
+		"01":  2,
+		"10":  1,
+		"11":  0,
+		"000": 4,
+		"001": 3,
+	},
+	// zerosLeft 5
+	{
+		"10":  1,
+		"11":  0,
+		"000": 5,
+		"001": 4,
+		"010": 3,
+		"011": 2,
+	},
+	// zerosLeft 6
+	{
+		"11":  0,
+		"000": 1,
+		"001": 2,
+		"010": 4,
+		"011": 3,
+		"100": 6,
+		"101": 5,
+	},
+	// zerosLeft 7
+	{
+		"001":         6,
+		"010":         5,
+		"011":         4,
+		"100":         3,
+		"101":         2,
+		"110":         1,
+		"111":         0,
+		"0001":        7,
+		"00001":       8,
+		"000001":      9,
+		"0000001":     10,
+		"00000001":    11,
+		"000000001":   12,
+		"0000000001":  13,
+		"00000000001": 14,
+	},
+}
@@ -0,0 +1,174 @@
+package zoom
+
+import (
+	"fmt"
+)
+
+// AudioCodec is the framing of the data of Audio packets.
+type AudioCodec int
+
+const (
+	AudioUnknown AudioCodec = iota
+	// AudioOpus packets each contain one Opus packet as described in RFC 6716.
+	AudioOpus
+	// AudioADTS packets contain AAC frames with ADTS headers.
+	AudioADTS
+)
+
+//go:generate enumer -type=AudioCodec -trimprefix=Audio
+
+// OpusSampleRate is the rate at which Opus timestamps and durations are counted.
+const OpusSampleRate = 48000
+
+// opusFrameSamples is the number of samples per frame, at 48 kHz, for every TOC configuration.
+var opusFrameSamples = [32]int{
+	// SILK: 10, 20, 40 and 60ms for narrow, medium and wide band
+	480, 960, 1920, 2880, 480, 960, 1920, 2880, 480, 960, 1920, 2880,
+	// Hybrid: 10 and 20ms for super wide and full band
+	480, 960, 480, 960,
+	// CELT: 2.5, 5, 10 and 20ms for narrow, wide, super wide and full band
+	120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960, 120, 240, 480, 960,
+}
+
+const opusMaxFrameSize = 1275
+
+// OpusPacketSamples checks the framing of an Opus packet, following the requirements of RFC 6716 section 3.4,
+// and returns the number of samples it decodes to at 48 kHz.
+func OpusPacketSamples(pkt []byte) (int, error) {
+	if len(pkt) < 1 {
+		return 0, fmt.Errorf("Empty Opus packet")
+	}
+	toc := pkt[0]
+	frameSamples := opusFrameSamples[toc>>3]
+	body := pkt[1:]
+	switch toc & 3 {
+	case 0:
+		if len(body) > opusMaxFrameSize {
+			return 0, fmt.Errorf("Opus frame of %d bytes is too large", len(body))
+		}
+		return frameSamples, nil
+	case 1:
+		if len(body)%2 != 0 || len(body)/2 > opusMaxFrameSize {
+			return 0, fmt.Errorf("Invalid size %d of two equal Opus frames", len(body))
+		}
+		return 2 * frameSamples, nil
+	case 2:
+		first, n, err := opusFrameLength(body)
+		if err != nil {
+			return 0, err
+		}
+		if first > len(body)-n || len(body)-n-first > opusMaxFrameSize {
+			return 0, fmt.Errorf("Invalid Opus frame sizes")
+		}
+		return 2 * frameSamples, nil
+	}
+
+	// code 3: arbitrary number of frames
+	if len(body) < 1 {
+		return 0, fmt.Errorf("Opus packet is missing the frame count")
+	}
+	count := int(body[0] & 0x3f)
+	vbr := body[0]&0x80 != 0
+	padded := body[0]&0x40 != 0
+	body = body[1:]
+	if count == 0 || count*frameSamples > 120*OpusSampleRate/1000 {
+		return 0, fmt.Errorf("Invalid Opus frame count %d", count)
+	}
+	padding := 0
+	for padded {
+		if len(body) < 1 {
+			return 0, fmt.Errorf("Opus packet is missing its padding length")
+		}
+		p := int(body[0])
+		body = body[1:]
+		padded = p == 255
+		if padded {
+			p = 254
+		}
+		padding += p
+	}
+	if padding > len(body) {
+		return 0, fmt.Errorf("Opus padding of %d bytes is larger than the packet", padding)
+	}
+	body = body[:len(body)-padding]
+	if !vbr {
+		if len(body)%count != 0 || len(body)/count > opusMaxFrameSize {
+			return 0, fmt.Errorf("Invalid size %d of %d equal Opus frames", len(body), count)
+		}
+		return count * frameSamples, nil
+	}
+	total := 0
+	for i := 0; i < count-1; i++ {
+		l, n, err := opusFrameLength(body)
+		if err != nil {
+			return 0, err
+		}
+		body = body[n:]
+		total += l
+	}
+	if total > len(body) || len(body)-total > opusMaxFrameSize {
+		return 0, fmt.Errorf("Invalid Opus frame sizes")
+	}
+	return count * frameSamples, nil
+}
+
+// opusFrameLength decodes a frame length coded in one or two bytes.
+func opusFrameLength(b []byte) (length int, n int, err error) {
+	if len(b) < 1 {
+		return 0, 0, fmt.Errorf("Opus packet is missing a frame length")
+	}
+	if b[0] < 252 {
+		return int(b[0]), 1, nil
+	}
+	if len(b) < 2 {
+		return 0, 0, fmt.Errorf("Opus packet is missing a frame length")
+	}
+	return int(b[1])*4 + int(b[0]), 2, nil
+}
+
+// OpusChannels returns the number of channels signalled in the TOC byte of an Opus packet.
+func OpusChannels(pkt []byte) int {
+	if len(pkt) > 0 && pkt[0]&0x04 != 0 {
+		return 2
+	}
+	return 1
+}
+
+// isADTS checks for the sync word and layer of an ADTS header.
+func isADTS(data []byte) bool {
+	return len(data) >= 7 && data[0] == 0xff && data[1]&0xf6 == 0xf0
+}
+
+// DetectAudioCodec looks at the data of a number of Audio packets to determine their framing.
+// Almost all packets have to agree on the framing, a few damaged ones are tolerated.
+func DetectAudioCodec(packets [][]byte) AudioCodec {
+	if len(packets) == 0 {
+		return AudioUnknown
+	}
+	adts, opus := 0, 0
+	configs := map[byte]int{}
+	for _, data := range packets {
+		if isADTS(data) {
+			adts++
+		}
+		if _, err := OpusPacketSamples(data); err == nil {
+			opus++
+			configs[data[0]>>3]++
+		}
+	}
+	enough := len(packets) * 9 / 10
+	if enough == 0 {
+		enough = 1
+	}
+	switch {
+	case adts >= enough:
+		return AudioADTS
+	case opus >= enough:
+		// Opus encoders rarely switch between more than a few configurations,
+		// random data on the other hand is spread over all of them.
+		if len(packets) < 8 || len(configs) <= 4 {
+			return AudioOpus
+		}
+	}
+	return AudioUnknown
+}
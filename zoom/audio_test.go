@@ -0,0 +1,78 @@
+package zoom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpusPacketSamples(t *testing.T) {
+	tests := []struct {
+		pkt     []byte
+		samples int
+		valid   bool
+	}{
+		// CELT full band 20ms, one frame
+		{[]byte{0xf8, 0xff, 0xfe}, 960, true},
+		// SILK wide band 60ms, two equal frames
+		{[]byte{0x59, 1, 2}, 2 * 2880, true},
+		{[]byte{0x59, 1, 2, 3}, 0, false},
+		// two frames of different sizes, the first one 2 bytes long
+		{[]byte{0xfa, 2, 1, 2, 3}, 1920, true},
+		{[]byte{0xfa, 5, 1, 2}, 0, false},
+		// three CBR frames with one byte of padding
+		{[]byte{0xfb, 0x43, 1, 1, 2, 3, 0}, 3 * 960, true},
+		// 7 x 20ms exceeds the limit of 120ms
+		{[]byte{0xfb, 0x07, 1, 2, 3, 4, 5, 6, 7}, 0, false},
+		{[]byte{}, 0, false},
+	}
+	for _, test := range tests {
+		samples, err := OpusPacketSamples(test.pkt)
+		if test.valid {
+			assert.NoError(t, err, "%x", test.pkt)
+			assert.Equal(t, test.samples, samples, "%x", test.pkt)
+		} else {
+			assert.Error(t, err, "%x", test.pkt)
+		}
+	}
+}
+
+func TestDetectAudioCodec(t *testing.T) {
+	opus := [][]byte{}
+	adts := [][]byte{}
+	random := [][]byte{}
+	for i := 0; i < 20; i++ {
+		opus = append(opus, []byte{0xfc, byte(i), byte(i * 7)})
+		adts = append(adts, []byte{0xff, 0xf1, 0x50, 0x80, 0x02, 0x1f, 0xfc, byte(i)})
+		random = append(random, []byte{byte(i * 8), byte(i * 13), byte(i * 29), 0x55})
+	}
+	assert.Equal(t, AudioOpus, DetectAudioCodec(opus))
+	assert.Equal(t, AudioADTS, DetectAudioCodec(adts))
+	assert.Equal(t, AudioUnknown, DetectAudioCodec(random))
+	assert.Equal(t, AudioUnknown, DetectAudioCodec(nil))
+	assert.Equal(t, 2, OpusChannels(opus[0]))
+}
+
+func TestAudioProp(t *testing.T) {
+	prop := []byte{0, 0x10, 0, 0, 0x80, 0xbb, 0, 0, 1, 0, 0, 0, 7, 0, 0, 0, 0xaa, 0xbb}
+	aud := newTestSample(Audio, 0, prop, nil).AudioProp()
+	if assert.NotNil(t, aud) {
+		assert.Equal(t, AudioProp{NameIdent: 0x1000, Unknown4: 48000, Unknown8: 1, Unknown12: 7, Rest: []byte{0xaa, 0xbb}}, *aud)
+	}
+
+	// short properties only fill the leading fields
+	aud = newTestSample(Audio, 0, prop[:8], nil).AudioProp()
+	if assert.NotNil(t, aud) {
+		assert.Equal(t, AudioProp{NameIdent: 0x1000, Unknown4: 48000}, *aud)
+	}
+
+	// bytes past PropertySize are padding
+	pkt := newTestSample(Audio, 0, prop, nil)
+	pkt.PropertySize = 17
+	if aud = pkt.AudioProp(); assert.NotNil(t, aud) {
+		assert.Equal(t, []byte{0xaa}, aud.Rest)
+	}
+
+	assert.Nil(t, newTestSample(VideoScreenShare, 0, prop, nil).AudioProp())
+	assert.Nil(t, newTestSample(Audio, 0, nil, nil).AudioProp())
+}
@@ -0,0 +1,70 @@
+package zoom
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+)
+
+// AvatarImage is a distinct profile picture of a participant.
+type AvatarImage struct {
+	Ident uint32
+	// Timing of the first packet carrying the picture.
+	Timing int64
+	Data   []byte
+	// Hash is the hex encoded SHA-1 of Data.
+	Hash string
+}
+
+// Ext returns the file extension matching the image format of the avatar.
+func (a *AvatarImage) Ext() string {
+	switch http.DetectContentType(a.Data) {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/bmp":
+		return ".bmp"
+	case "image/webp":
+		return ".webp"
+	}
+	return ".bin"
+}
+
+// AvatarCollector deduplicates the avatars of a recording, which are usually sent more than once.
+type AvatarCollector struct {
+	Avatars []*AvatarImage
+	seen    map[uint32]map[string]bool
+}
+
+// Add records the picture of an avatar packet and returns it, or nil if the packet is no avatar
+// or the participant already had the same picture.
+func (c *AvatarCollector) Add(pkt *SamplePacket) *AvatarImage {
+	prop := pkt.AvatarProp()
+	if prop == nil || len(pkt.Data) == 0 {
+		return nil
+	}
+	if c.seen == nil {
+		c.seen = map[uint32]map[string]bool{}
+	}
+	ident := uint32(prop.NameIdent)
+	sum := sha1.Sum(pkt.Data)
+	hash := hex.EncodeToString(sum[:])
+	if c.seen[ident] == nil {
+		c.seen[ident] = map[string]bool{}
+	}
+	if c.seen[ident][hash] {
+		return nil
+	}
+	c.seen[ident][hash] = true
+	a := &AvatarImage{
+		Ident:  ident,
+		Timing: pkt.TimingA,
+		Data:   pkt.Data,
+		Hash:   hash,
+	}
+	c.Avatars = append(c.Avatars, a)
+	return a
+}
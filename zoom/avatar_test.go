@@ -0,0 +1,42 @@
+package zoom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvatarCollector(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n....")
+	jpeg := []byte("\xff\xd8\xff\xe0....")
+	alice := []byte{0, 0x10, 0, 0}
+	bob := []byte{0, 0x20, 0, 0}
+
+	c := &AvatarCollector{}
+	a := c.Add(newTestSample(Avatar, 10, alice, png))
+	require.NotNil(t, a)
+	assert.Equal(t, uint32(0x1000), a.Ident)
+	assert.Equal(t, int64(10), a.Timing)
+	assert.Equal(t, ".png", a.Ext())
+	// the same picture again is skipped, but not for another participant
+	assert.Nil(t, c.Add(newTestSample(Avatar, 20, alice, png)))
+	assert.NotNil(t, c.Add(newTestSample(Avatar, 30, bob, png)))
+	b := c.Add(newTestSample(Avatar, 40, alice, jpeg))
+	require.NotNil(t, b)
+	assert.Equal(t, ".jpg", b.Ext())
+	assert.Nil(t, c.Add(newTestSample(Cursor, 50, alice, jpeg)))
+	assert.Len(t, c.Avatars, 3)
+
+	joined := NewCmdPacket()
+	joined.Type = testCmdType(CmdParticipantJoined)
+	joined.NameIdent = 0x1000
+	joined.AdditionalData = []byte("Alice")
+	joined.AdditionalSize = 5
+	r := NewRoster(testCmdTypes.DecodeEvents([]*CmdPacket{joined}))
+	r.AddAvatars(c.Avatars)
+	require.Len(t, r.Participants(), 2)
+	assert.Equal(t, []*AvatarImage{a, b}, r.Get(0x1000).Avatars)
+	assert.Equal(t, "Alice", r.Get(0x1000).Name)
+	assert.Len(t, r.Get(0x2000).Avatars, 1)
+}
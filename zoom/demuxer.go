@@ -0,0 +1,142 @@
+package zoom
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/galli-leo/gozoom/parser"
+	"github.com/nareix/joy5/av"
+	"github.com/nareix/joy5/codec/h264"
+	"go.uber.org/zap"
+)
+
+// Demuxer presents the H.264 packets of a SampleReader as a joy5 av.PacketReader,
+// so they can be written with flv.Muxer or published with rtmp.
+// Timestamps are relative to the first keyframe.
+type Demuxer struct {
+	reader *SampleReader
+	filter SampleDataFilter
+	log    *zap.SugaredLogger
+
+	sps        []byte
+	pps        []byte
+	codec      *h264.Codec
+	configSent bool
+
+	start   int64
+	started bool
+	pending *av.Packet
+}
+
+// NewDemuxer returns a demuxer over the video packets of r accepted by filter, e.g. only the screen share.
+func NewDemuxer(r *SampleReader, filter SampleDataFilter, log *zap.SugaredLogger) *Demuxer {
+	return &Demuxer{
+		reader: r,
+		filter: filter,
+		log:    log.Named("Demuxer"),
+	}
+}
+
+// Codec returns the codec data of the last parameter sets, nil before the first SPS and PPS were read.
+func (d *Demuxer) Codec() *h264.Codec {
+	return d.codec
+}
+
+// setParameterSets parses sps and pps with the parser package and builds the codec data from them.
+func (d *Demuxer) setParameterSets(sps, pps []byte) error {
+	p, err := parser.ParseParameterSets([][]byte{sps, pps}, d.log)
+	if err != nil {
+		return err
+	}
+	var ppsInfo *parser.PPSInfo
+	for _, info := range p.PPSInfos {
+		ppsInfo = info
+	}
+	if ppsInfo == nil {
+		return fmt.Errorf("No PPS found")
+	}
+
+	codec := h264.NewCodec()
+	codec.SPS[int(p.SPS.Id)] = sps
+	codec.PPS[int(ppsInfo.Id)] = pps
+	codec.W = int(p.SPS.FrameWidth())
+	codec.H = int(p.SPS.FrameHeight())
+	n := 0
+	codec.ToConfig(nil, &n)
+	codec.ConfigBytes = make([]byte, n)
+	n = 0
+	codec.ToConfig(codec.ConfigBytes, &n)
+
+	d.sps = sps
+	d.pps = pps
+	d.codec = codec
+	d.configSent = false
+	return nil
+}
+
+// ReadPacket returns the next H.264 packet, preceded by an H264DecoderConfig packet whenever the parameter sets change.
+// Packets before the first keyframe are dropped. Returns io.EOF at the end of the file.
+func (d *Demuxer) ReadPacket() (av.Packet, error) {
+	if d.pending != nil {
+		pkt := *d.pending
+		d.pending = nil
+		return pkt, nil
+	}
+
+	for d.reader.Next() {
+		pkt := d.reader.Current()
+		if !pkt.IsVideo() || len(pkt.Data) == 0 || !d.filter(pkt) {
+			continue
+		}
+
+		sps, pps, frame := pkt.ParameterSets()
+		if sps != nil && pps != nil && (!bytes.Equal(sps, d.sps) || !bytes.Equal(pps, d.pps)) {
+			if err := d.setParameterSets(sps, pps); err != nil {
+				d.log.Warnf("Failed to parse parameter sets at timing %d: %v", pkt.TimingA, err)
+			}
+		}
+
+		keyframe := pkt.IsKeyframe()
+		if d.codec == nil || len(frame) == 0 || (!d.started && !keyframe) {
+			continue
+		}
+		if !d.started {
+			d.start = pkt.TimingA
+			d.started = true
+		}
+
+		// TimingB is not known to be a presentation time, so CTime stays zero
+		ret := av.Packet{
+			Type:       av.H264,
+			IsKeyFrame: keyframe,
+			Time:       d.time(pkt.TimingA),
+			Data:       h264.JoinNALUsAVCC(frame),
+			H264:       d.codec,
+		}
+		if !d.configSent {
+			d.configSent = true
+			d.pending = &ret
+			return av.Packet{
+				Type: av.H264DecoderConfig,
+				Time: ret.Time,
+				Data: d.codec.ConfigBytes,
+				H264: d.codec,
+			}, nil
+		}
+		return ret, nil
+	}
+
+	if err := d.reader.Err(); err != nil {
+		return av.Packet{}, err
+	}
+	return av.Packet{}, io.EOF
+}
+
+func (d *Demuxer) time(timing int64) time.Duration {
+	if timing < d.start {
+		return 0
+	}
+	return TimingToDuration(timing - d.start)
+}
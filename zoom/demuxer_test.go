@@ -0,0 +1,88 @@
+package zoom
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/galli-leo/gozoom/internal/testdata"
+	"github.com/nareix/joy5/av"
+	"github.com/nareix/joy5/format/flv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func demuxerSamples() []*SamplePacket {
+	idr := []byte{0, 0, 0, 1}
+	idr = append(idr, testdata.SPS...)
+	idr = append(idr, 0, 0, 0, 1)
+	idr = append(idr, testdata.PPS...)
+	idr = append(idr, 0, 0, 0, 1, 0x65, 0x88, 0x84)
+	videoProp := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0, 5, 0, 0, 0xd0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	return []*SamplePacket{
+		newTestSample(VideoScreenShare, 1000, videoProp, nonIdrFrame),
+		newTestSample(VideoScreenShare, 1040, videoProp, idr),
+		newTestSample(Audio, 1050, []byte{1, 2, 3}, []byte{0x11}),
+		newTestSample(VideoScreenShare, 1080, videoProp, nonIdrFrame),
+		newTestSample(VideoScreenShare, 1120, videoProp, idr),
+	}
+}
+
+func readAllPackets(t *testing.T, r av.PacketReader) []av.Packet {
+	pkts := []av.Packet{}
+	for {
+		pkt, err := r.ReadPacket()
+		if err == io.EOF {
+			return pkts
+		}
+		require.NoError(t, err)
+		pkts = append(pkts, pkt)
+	}
+}
+
+func openTestDemuxer(t *testing.T) *Demuxer {
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.Open(writeTestFile(t, demuxerSamples())))
+	t.Cleanup(sr.Close)
+	return NewDemuxer(sr, func(p *SamplePacket) bool {
+		return p.MediaType() == VideoScreenShare
+	}, testLog)
+}
+
+func TestDemuxer(t *testing.T) {
+	pkts := readAllPackets(t, openTestDemuxer(t))
+
+	// the frame before the first keyframe is dropped
+	require.Len(t, pkts, 4)
+	assert.Equal(t, av.H264DecoderConfig, pkts[0].Type)
+	assert.Equal(t, 1280, pkts[0].H264.W)
+	assert.Equal(t, 720, pkts[0].H264.H)
+	assert.Equal(t, []byte{1, 0x64, 0x00, 0x1f}, pkts[0].Data[:4])
+
+	assert.Equal(t, av.H264, pkts[1].Type)
+	assert.True(t, pkts[1].IsKeyFrame)
+	assert.Equal(t, time.Duration(0), pkts[1].Time)
+	assert.Equal(t, []byte{0, 0, 0, 3, 0x65, 0x88, 0x84}, pkts[1].Data)
+
+	assert.False(t, pkts[2].IsKeyFrame)
+	assert.Equal(t, 40*time.Millisecond, pkts[2].Time)
+	// unchanged parameter sets are not sent again
+	assert.Equal(t, av.H264, pkts[3].Type)
+	assert.Equal(t, 80*time.Millisecond, pkts[3].Time)
+}
+
+func TestDemuxerFLV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := flv.NewMuxer(buf)
+	m.HasVideo = true
+	for _, pkt := range readAllPackets(t, openTestDemuxer(t)) {
+		require.NoError(t, m.WritePacket(pkt))
+	}
+
+	d := flv.NewDemuxer(buf)
+	pkts := readAllPackets(t, d)
+	require.Len(t, pkts, 4)
+	assert.Equal(t, av.H264DecoderConfig, pkts[0].Type)
+	assert.Equal(t, 80*time.Millisecond, pkts[3].Time)
+}
@@ -0,0 +1,217 @@
+package zoom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CmdType is the kind of event a command packet carries.
+type CmdType int32
+
+// The values of CmdHeader.Type that carry these events have not been confirmed against captured recordings,
+// so they are not hardcoded: a CmdTypeTable maps them to event kinds.
+const (
+	CmdRaw CmdType = iota
+	CmdParticipantJoined
+	CmdParticipantLeft
+	CmdChatMessage
+	CmdShareStarted
+	CmdShareStopped
+	CmdActiveSpeaker
+)
+
+//go:generate enumer -type=CmdType -trimprefix=Cmd
+
+// CmdTypeTable maps values of CmdHeader.Type to the kind of event they carry.
+// Types missing from the table, and all types of a nil table, are decoded as a RawEvent.
+type CmdTypeTable map[int32]CmdType
+
+// ParseCmdTypeTable parses a comma separated list of kind=type pairs, e.g. "ChatMessage=3,ParticipantJoined=1".
+// The kinds are the names of the CmdType constants without the Cmd prefix, matched case insensitively.
+func ParseCmdTypeTable(spec string) (CmdTypeTable, error) {
+	t := CmdTypeTable{}
+	if strings.TrimSpace(spec) == "" {
+		return t, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid command type %q, expected kind=type", pair)
+		}
+		kind := CmdRaw
+		for _, k := range CmdTypeValues() {
+			if strings.EqualFold(k.String(), parts[0]) {
+				kind = k
+			}
+		}
+		if kind == CmdRaw {
+			return nil, fmt.Errorf("Unknown command kind %q, expected one of %v", parts[0], CmdTypeValues()[1:])
+		}
+		typ, err := strconv.ParseInt(parts[1], 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid command type %q: %w", parts[1], err)
+		}
+		t[int32(typ)] = kind
+	}
+	return t, nil
+}
+
+// Kind returns the kind of event carried by p.
+func (t CmdTypeTable) Kind(p *CmdPacket) CmdType {
+	if kind, ok := t[p.Type]; ok {
+		return kind
+	}
+	return CmdRaw
+}
+
+// Text returns the additional data as a string, without trailing NUL bytes.
+func (p *CmdPacket) Text() string {
+	if p.AdditionalSize <= 0 {
+		return ""
+	}
+	return strings.TrimRight(string(p.AdditionalData), "\x00")
+}
+
+// SetText replaces the additional data, keeping a trailing NUL byte if there was one,
+// and adjusts the packet sizes.
+func (p *CmdPacket) SetText(text string) {
+	data := []byte(text)
+	if p.AdditionalSize > 0 && strings.HasSuffix(string(p.AdditionalData[:p.AdditionalSize]), "\x00") {
+		data = append(data, 0)
+	}
+	old := uint32(0)
+	if p.AdditionalSize > 0 {
+		old = uint32(p.AdditionalSize)
+	}
+	if p.SizeToRead >= old {
+		p.SizeToRead += uint32(len(data)) - old
+	}
+	p.AdditionalData = data
+	p.AdditionalSize = int32(len(data))
+}
+
+// Event is a decoded command packet.
+type Event interface {
+	// Timing returns the TimingA of the packet.
+	Timing() int64
+	// Participant returns the NameIdent of the participant the event is about.
+	Participant() uint32
+	// Packet returns the underlying command packet.
+	Packet() *CmdPacket
+}
+
+type baseEvent struct {
+	pkt *CmdPacket
+}
+
+func (e *baseEvent) Timing() int64 {
+	return int64(e.pkt.TimingA)
+}
+
+func (e *baseEvent) Participant() uint32 {
+	return e.pkt.NameIdent
+}
+
+func (e *baseEvent) Packet() *CmdPacket {
+	return e.pkt
+}
+
+type ParticipantJoinedEvent struct {
+	baseEvent
+	Name string
+}
+
+func (e *ParticipantJoinedEvent) String() string {
+	return fmt.Sprintf("<Joined @ 0x%x: 0x%x %q>", e.Timing(), e.Participant(), e.Name)
+}
+
+type ParticipantLeftEvent struct {
+	baseEvent
+}
+
+func (e *ParticipantLeftEvent) String() string {
+	return fmt.Sprintf("<Left @ 0x%x: 0x%x>", e.Timing(), e.Participant())
+}
+
+type ChatEvent struct {
+	baseEvent
+	Text string
+}
+
+func (e *ChatEvent) String() string {
+	return fmt.Sprintf("<Chat @ 0x%x: 0x%x %q>", e.Timing(), e.Participant(), e.Text)
+}
+
+type ShareStartedEvent struct {
+	baseEvent
+}
+
+func (e *ShareStartedEvent) String() string {
+	return fmt.Sprintf("<Share started @ 0x%x: 0x%x>", e.Timing(), e.Participant())
+}
+
+type ShareStoppedEvent struct {
+	baseEvent
+}
+
+func (e *ShareStoppedEvent) String() string {
+	return fmt.Sprintf("<Share stopped @ 0x%x: 0x%x>", e.Timing(), e.Participant())
+}
+
+type ActiveSpeakerEvent struct {
+	baseEvent
+}
+
+func (e *ActiveSpeakerEvent) String() string {
+	return fmt.Sprintf("<Active speaker @ 0x%x: 0x%x>", e.Timing(), e.Participant())
+}
+
+// RawEvent is a command packet of unknown type.
+type RawEvent struct {
+	baseEvent
+}
+
+func (e *RawEvent) String() string {
+	return fmt.Sprintf("<Raw @ 0x%x: %s>", e.Timing(), e.pkt)
+}
+
+// DecodeEvent converts a command packet to a typed event.
+func (t CmdTypeTable) DecodeEvent(p *CmdPacket) Event {
+	base := baseEvent{p}
+	switch t.Kind(p) {
+	case CmdParticipantJoined:
+		return &ParticipantJoinedEvent{base, p.Text()}
+	case CmdParticipantLeft:
+		return &ParticipantLeftEvent{base}
+	case CmdChatMessage:
+		return &ChatEvent{base, p.Text()}
+	case CmdShareStarted:
+		return &ShareStartedEvent{base}
+	case CmdShareStopped:
+		return &ShareStoppedEvent{base}
+	case CmdActiveSpeaker:
+		return &ActiveSpeakerEvent{base}
+	}
+	return &RawEvent{base}
+}
+
+// DecodeEvents converts command packets to typed events.
+func (t CmdTypeTable) DecodeEvents(pkts []*CmdPacket) []Event {
+	ret := make([]Event, len(pkts))
+	for i, p := range pkts {
+		ret[i] = t.DecodeEvent(p)
+	}
+	return ret
+}
+
+// AllRaw returns whether there are events and none of them could be decoded,
+// which usually means that the command type table does not match the recording.
+func AllRaw(events []Event) bool {
+	for _, e := range events {
+		if _, ok := e.(*RawEvent); !ok {
+			return false
+		}
+	}
+	return len(events) > 0
+}
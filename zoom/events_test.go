@@ -0,0 +1,87 @@
+package zoom
+
+import (
+	"testing"
+
+	"github.com/galli-leo/gozoom/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCmdTypes is the command type table of the test recordings, the example table of the testdata.
+var testCmdTypes = func() CmdTypeTable {
+	t, err := ParseCmdTypeTable(testdata.CmdTypes)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}()
+
+// testCmdType returns the type of the test recordings for an event kind.
+func testCmdType(kind CmdType) int32 {
+	for typ, k := range testCmdTypes {
+		if k == kind {
+			return typ
+		}
+	}
+	return 0x77
+}
+
+func TestParseCmdTypeTable(t *testing.T) {
+	table, err := ParseCmdTypeTable("participantjoined=0x11, ParticipantLeft=18,ChatMessage=0x13")
+	require.NoError(t, err)
+	assert.Equal(t, CmdTypeTable{0x11: CmdParticipantJoined, 0x12: CmdParticipantLeft, 0x13: CmdChatMessage}, table)
+	assert.Len(t, testCmdTypes, len(CmdTypeValues())-1)
+
+	_, err = ParseCmdTypeTable("Chat=3")
+	assert.Error(t, err)
+	_, err = ParseCmdTypeTable("Raw=3")
+	assert.Error(t, err)
+	_, err = ParseCmdTypeTable("ChatMessage")
+	assert.Error(t, err)
+}
+
+func TestDecodeEvents(t *testing.T) {
+	newCmd := func(typ CmdType, ident uint32, text string) *CmdPacket {
+		p := NewCmdPacket()
+		p.Type = testCmdType(typ)
+		p.NameIdent = ident
+		p.TimingA = 42
+		p.AdditionalData = []byte(text)
+		p.AdditionalSize = int32(len(text))
+		return p
+	}
+	cmds := []*CmdPacket{
+		newCmd(CmdParticipantJoined, 1, "Alice\x00"),
+		newCmd(CmdChatMessage, 1, "hi"),
+		newCmd(CmdShareStarted, 2, ""),
+		newCmd(CmdActiveSpeaker, 2, ""),
+		newCmd(CmdRaw, 2, ""),
+	}
+
+	events := testCmdTypes.DecodeEvents(cmds)
+	require.Len(t, events, 5)
+	assert.False(t, AllRaw(events))
+
+	joined, ok := events[0].(*ParticipantJoinedEvent)
+	require.True(t, ok)
+	assert.Equal(t, "Alice", joined.Name)
+	assert.EqualValues(t, 1, joined.Participant())
+	assert.EqualValues(t, 42, joined.Timing())
+
+	chat, ok := events[1].(*ChatEvent)
+	require.True(t, ok)
+	assert.Equal(t, "hi", chat.Text)
+
+	assert.IsType(t, &ShareStartedEvent{}, events[2])
+	assert.IsType(t, &ActiveSpeakerEvent{}, events[3])
+	assert.IsType(t, &RawEvent{}, events[4])
+
+	// without a table nothing is decoded
+	raw := CmdTypeTable(nil).DecodeEvents(cmds)
+	for _, e := range raw {
+		assert.IsType(t, &RawEvent{}, e)
+	}
+	assert.True(t, AllRaw(raw))
+	assert.False(t, AllRaw(nil))
+}
@@ -3,6 +3,7 @@ package zoom
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,10 +12,35 @@ import (
 )
 
 type File struct {
-	file       *os.File
+	r          io.ReadSeeker
+	ra         io.ReaderAt
+	closer     io.Closer
 	size       int64
 	currOffset int64
 	log        *zap.SugaredLogger
+
+	// Header is the file header, available after ReadBeginning was called.
+	Header *Header
+
+	recovery bool
+	follow   bool
+	skipped  []SkippedRange
+}
+
+// ErrIncomplete is returned when the file ends in the middle of what was read,
+// for example because it is still being written.
+var ErrIncomplete = errors.New("Unexpected end of file")
+
+// SkippedRange is a part of the file that was skipped, because no valid packet could be read from it.
+type SkippedRange struct {
+	Start int64
+	End   int64
+	// Err is the error encountered when trying to read a packet at Start.
+	Err error
+}
+
+func (r SkippedRange) String() string {
+	return fmt.Sprintf("0x%x-0x%x (%d bytes): %v", r.Start, r.End, r.End-r.Start, r.Err)
 }
 
 func OpenFile(filename string, log *zap.SugaredLogger) (*File, error) {
@@ -22,16 +48,29 @@ func OpenFile(filename string, log *zap.SugaredLogger) (*File, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Failed to open file %s: %w", filename, err)
 	}
-	fileSize, err := file.Seek(0, io.SeekEnd)
+	f, err := NewFile(file, log)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	f.ra = file
+	f.closer = file
+	return f, nil
+}
+
+// NewFile reads a .zoom file from r, for example a bytes.Reader or an archive member.
+// The read position of r is moved to the start.
+func NewFile(r io.ReadSeeker, log *zap.SugaredLogger) (*File, error) {
+	fileSize, err := r.Seek(0, io.SeekEnd)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to seek to end: %w", err)
 	}
-	_, err = file.Seek(0, io.SeekStart)
+	_, err = r.Seek(0, io.SeekStart)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to seek to start: %w", err)
 	}
 	f := &File{
-		file:       file,
+		r:          r,
 		log:        log.Named("file"),
 		size:       fileSize,
 		currOffset: 0,
@@ -39,14 +78,100 @@ func OpenFile(filename string, log *zap.SugaredLogger) (*File, error) {
 	return f, nil
 }
 
+// NewFileAt reads a .zoom file of the given size from r.
+// Files created this way can be forked, see Fork.
+func NewFileAt(r io.ReaderAt, size int64, log *zap.SugaredLogger) *File {
+	return &File{
+		r:    io.NewSectionReader(r, 0, size),
+		ra:   r,
+		log:  log.Named("file"),
+		size: size,
+	}
+}
+
+// Fork returns a new File reading the same data, but with its own read position.
+// Forks can be used concurrently from different goroutines, as long as the underlying io.ReaderAt allows it (*os.File does).
+// Closing a fork does not close the underlying reader.
+func (f *File) Fork() (*File, error) {
+	if f.ra == nil {
+		return nil, fmt.Errorf("File is not backed by an io.ReaderAt, cannot fork it")
+	}
+	fork := NewFileAt(f.ra, f.size, f.log)
+	fork.Header = f.Header
+	fork.recovery = f.recovery
+	if err := fork.SeekTo(f.currOffset); err != nil {
+		return nil, err
+	}
+	return fork, nil
+}
+
 func (f *File) Close() {
-	f.file.Close()
+	if f.closer != nil {
+		f.closer.Close()
+	}
+}
+
+// SetRecovery enables or disables recovery mode.
+// In recovery mode, ReadPacket skips over damaged data by scanning for the next valid packet, instead of failing.
+func (f *File) SetRecovery(enabled bool) {
+	f.recovery = enabled
+}
+
+// SetFollow enables or disables follow mode, for files that are still being written.
+// In follow mode, ReadPacket does not treat a packet the file ends in the middle of as damaged.
+// It returns ErrIncomplete and leaves the read position at the start of the packet instead,
+// so it can be read again after Refresh picked up the rest of it.
+func (f *File) SetFollow(enabled bool) {
+	f.follow = enabled
+}
+
+// Refresh updates the size of the file, to include data appended since it was opened.
+// The read position is left unchanged.
+func (f *File) Refresh() (int64, error) {
+	size, err := f.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return f.size, fmt.Errorf("Failed to seek to end: %w", err)
+	}
+	if err := f.SeekTo(f.currOffset); err != nil {
+		return f.size, err
+	}
+	if size < f.size {
+		return f.size, fmt.Errorf("File shrank from %d to %d bytes", f.size, size)
+	}
+	f.size = size
+	return size, nil
+}
+
+// Skipped returns all byte ranges skipped in recovery mode so far.
+func (f *File) Skipped() []SkippedRange {
+	return f.skipped
+}
+
+func (f *File) checkAvailable(number int) error {
+	if number < 0 {
+		return fmt.Errorf("Cannot read %d bytes at 0x%x", number, f.currOffset)
+	}
+	if f.currOffset+int64(number) > f.size {
+		return fmt.Errorf("Cannot read %d bytes at 0x%x, file has only %d bytes: %w", number, f.currOffset, f.size, ErrIncomplete)
+	}
+	return nil
+}
+
+// incomplete replaces the errors of reading past the end of the file with ErrIncomplete.
+func incomplete(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrIncomplete
+	}
+	return err
 }
 
 func (f *File) ReadExactBytes(number int) ([]byte, error) {
+	if err := f.checkAvailable(number); err != nil {
+		return nil, err
+	}
 	bytes := make([]byte, number)
 
-	num, err := f.file.Read(bytes)
+	num, err := io.ReadFull(f.r, bytes)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to read %d bytes: %w", number, err)
 	}
@@ -64,9 +189,12 @@ func (f *File) ReadExactBytes(number int) ([]byte, error) {
 func (f *File) ReadBytes(number int) ([]byte, error) {
 	// round to nearest 4
 	number = (number + 3) & 0xfffffffc
+	if err := f.checkAvailable(number); err != nil {
+		return nil, err
+	}
 	bytes := make([]byte, number)
 
-	num, err := f.file.Read(bytes)
+	num, err := io.ReadFull(f.r, bytes)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to read %d bytes: %w", number, err)
 	}
@@ -98,7 +226,12 @@ func (f *File) ReadStruct(number int, struc interface{}) error {
 func (f *File) ReadBeginning() error {
 	head := &Header{}
 
-	f.ReadStruct(HeaderSize, head)
+	if err := f.SeekTo(0); err != nil {
+		return err
+	}
+	if err := f.ReadStruct(HeaderSize, head); err != nil {
+		return fmt.Errorf("Failed to read file header: %w", err)
+	}
 	if head.Header != header {
 		return fmt.Errorf("Failed to read file header, expected header %x, got %x", header, head.Header)
 	}
@@ -109,20 +242,44 @@ func (f *File) ReadBeginning() error {
 
 	f.log.Infow("Read beginning of file", "initial_offset", head.FileOffset, "version_info", head.VersionInfo, "version_number", head.VersionNumber())
 
-	act, err := f.file.Seek(int64(head.FileOffset), io.SeekStart)
+	f.Header = head
+
+	return f.SeekTo(int64(head.FileOffset))
+}
+
+// SeekTo moves the read position to the absolute offset in the file.
+func (f *File) SeekTo(offset int64) error {
+	act, err := f.r.Seek(offset, io.SeekStart)
 	if err != nil {
-		return fmt.Errorf("Failed to seek to %d: %w", head.FileOffset, err)
+		return fmt.Errorf("Failed to seek to %d: %w", offset, err)
 	}
 
-	if act != int64(head.FileOffset) {
-		return fmt.Errorf("Failed to seek to %d, only seeked to %d", head.FileOffset, act)
+	if act != offset {
+		return fmt.Errorf("Failed to seek to %d, only seeked to %d", offset, act)
 	}
 
-	f.currOffset = int64(head.FileOffset)
+	f.currOffset = offset
 
 	return nil
 }
 
+// Offset returns the current read position in the file.
+func (f *File) Offset() int64 {
+	return f.currOffset
+}
+
+// DataOffset returns the offset of the first packet in the file.
+func (f *File) DataOffset() int64 {
+	if f.Header == nil {
+		return 0
+	}
+	return int64(f.Header.FileOffset)
+}
+
+func (f *File) Size() int64 {
+	return f.size
+}
+
 func (f *File) HasData() bool {
 	return f.currOffset < f.size
 }
@@ -133,13 +290,80 @@ type Packet interface {
 	ReadPacket(f *File) error
 }
 
+// ReadPacket reads the next packet into pkt.
+// In recovery mode, a damaged packet is skipped by scanning forward for the next packet that can be read completely.
+// The skipped bytes are available via Skipped.
 func (f *File) ReadPacket(pkt Packet) error {
+	start := f.currOffset
+	err := f.readPacket(pkt)
+	if err != nil && f.follow && errors.Is(err, ErrIncomplete) {
+		if seekErr := f.SeekTo(start); seekErr != nil {
+			return seekErr
+		}
+		return err
+	}
+	if err == nil || !f.recovery {
+		return err
+	}
+
+	from := start + 1
+	for {
+		candidate, scanErr := f.scanHeader(from)
+		if scanErr != nil {
+			f.skip(SkippedRange{start, f.size, err})
+			f.SeekTo(f.size)
+			return fmt.Errorf("Failed to recover packet at 0x%x: %w", start, err)
+		}
+		if seekErr := f.SeekTo(candidate); seekErr != nil {
+			return seekErr
+		}
+		if f.readPacket(pkt) == nil {
+			f.skip(SkippedRange{start, candidate, err})
+			return nil
+		}
+		from = candidate + 1
+	}
+}
+
+func (f *File) skip(r SkippedRange) {
+	f.log.Warnf("Skipped damaged data at %s", r)
+	f.skipped = append(f.skipped, r)
+}
+
+// scanHeader returns the offset of the next packet header magic at or after from.
+func (f *File) scanHeader(from int64) (int64, error) {
+	magic := make([]byte, 4)
+	binary.LittleEndian.PutUint32(magic, header)
+	buf := make([]byte, 64*1024)
+
+	for from+4 <= f.size {
+		if err := f.SeekTo(from); err != nil {
+			return 0, err
+		}
+		n, err := io.ReadFull(f.r, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, fmt.Errorf("Failed to scan for header at 0x%x: %w", from, err)
+		}
+		if idx := bytes.Index(buf[:n], magic); idx >= 0 {
+			return from + int64(idx), nil
+		}
+		// keep the last 3 bytes, in case the magic is split between reads
+		from += int64(n) - 3
+		if n < len(buf) {
+			break
+		}
+	}
+
+	return 0, io.EOF
+}
+
+func (f *File) readPacket(pkt Packet) error {
 	headTrail := uint32(0)
 
-	err := binary.Read(f.file, binary.LittleEndian, &headTrail)
+	err := binary.Read(f.r, binary.LittleEndian, &headTrail)
 	f.currOffset += 4
 	if err != nil {
-		return fmt.Errorf("Failed to read header: %w", err)
+		return fmt.Errorf("Failed to read header: %w", incomplete(err))
 	}
 	if headTrail != header {
 		return fmt.Errorf("Failed to read header, expected %x, got %x", header, headTrail)
@@ -149,10 +373,10 @@ func (f *File) ReadPacket(pkt Packet) error {
 		return err
 	}
 
-	err = binary.Read(f.file, binary.LittleEndian, &headTrail)
+	err = binary.Read(f.r, binary.LittleEndian, &headTrail)
 	f.currOffset += 4
 	if err != nil {
-		return fmt.Errorf("Failed to read trail: %w", err)
+		return fmt.Errorf("Failed to read trail: %w", incomplete(err))
 	}
 
 	if headTrail != trailer {
@@ -0,0 +1,183 @@
+package zoom
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAllTimings(t *testing.T, filename string) ([]int64, []SkippedRange) {
+	sr := NewSampleReader(testLog)
+	sr.SetRecovery(true)
+	require.NoError(t, sr.Open(filename))
+	defer sr.Close()
+
+	timings := []int64{}
+	for sr.Next() {
+		timings = append(timings, sr.Current().TimingA)
+	}
+	return timings, sr.Skipped()
+}
+
+func TestRecoveryDamagedPacket(t *testing.T) {
+	filename := writeTestFile(t, testSamples())
+
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.Open(filename))
+	idx, err := sr.Index()
+	require.NoError(t, err)
+	sr.Close()
+
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	// overwrite the header magic and data size of the third packet
+	damaged := idx.Entries[2]
+	copy(data[damaged.Offset:], []byte{0xde, 0xad, 0xbe, 0xef})
+	copy(data[damaged.Offset+4+40:], []byte{0xff, 0xff, 0xff, 0x0f})
+	require.NoError(t, ioutil.WriteFile(filename, data, 0644))
+
+	timings, skipped := readAllTimings(t, filename)
+	assert.Equal(t, []int64{10, 20, 35, 40, 50}, timings)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, damaged.Offset, skipped[0].Start)
+	assert.Equal(t, idx.Entries[3].Offset, skipped[0].End)
+}
+
+func TestRecoveryTruncated(t *testing.T) {
+	filename := writeTestFile(t, testSamples())
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(filename, info.Size()-6))
+
+	timings, skipped := readAllTimings(t, filename)
+	assert.Equal(t, []int64{10, 20, 30, 35, 40}, timings)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, info.Size()-6, skipped[0].End)
+}
+
+func TestFileFromMemory(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestFile(t, testSamples()))
+	require.NoError(t, err)
+
+	f, err := NewFile(bytes.NewReader(data), testLog)
+	require.NoError(t, err)
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.OpenFile(f))
+	num := 0
+	for sr.Next() {
+		num++
+	}
+	assert.Equal(t, 6, num)
+
+	_, err = f.Fork()
+	assert.Error(t, err)
+}
+
+func TestFileForkConcurrent(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestFile(t, testSamples()))
+	require.NoError(t, err)
+
+	f := NewFileAt(bytes.NewReader(data), int64(len(data)), testLog)
+	require.NoError(t, f.ReadBeginning())
+	idx, err := BuildIndex(f)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([]int64, idx.Len())
+	for i, e := range idx.Entries {
+		wg.Add(1)
+		go func(i int, e IndexEntry) {
+			defer wg.Done()
+			fork, err := f.Fork()
+			if !assert.NoError(t, err) {
+				return
+			}
+			fork.SeekTo(e.Offset)
+			pkt := NewSamplePacket()
+			if assert.NoError(t, fork.ReadPacket(pkt)) {
+				results[i] = pkt.TimingA
+			}
+		}(i, e)
+	}
+	wg.Wait()
+	assert.Equal(t, []int64{10, 20, 30, 35, 40, 50}, results)
+}
+
+func TestFollow(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestFile(t, testSamples()))
+	require.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "growing.zoom")
+	out, err := os.Create(filename)
+	require.NoError(t, err)
+	defer out.Close()
+	// start in the middle of the file header
+	_, err = out.Write(data[:50])
+	require.NoError(t, err)
+
+	interval := FollowPollInterval
+	FollowPollInterval = time.Millisecond
+	defer func() { FollowPollInterval = interval }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sr := NewSampleReader(testLog)
+	sr.Follow(ctx)
+	timings := make(chan int64)
+	done := make(chan error)
+	go func() {
+		if err := sr.Open(filename); err != nil {
+			done <- err
+			return
+		}
+		defer sr.Close()
+		for sr.Next() {
+			timings <- sr.Current().TimingA
+		}
+		done <- sr.Err()
+	}()
+
+	// append the rest in pieces that end in the middle of packets
+	received := []int64{}
+	for pos := 50; pos < len(data); pos += 37 {
+		end := pos + 37
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err = out.Write(data[pos:end])
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+	drain:
+		for {
+			select {
+			case timing := <-timings:
+				received = append(received, timing)
+			default:
+				break drain
+			}
+		}
+	}
+	for len(received) < 6 {
+		select {
+		case timing := <-timings:
+			received = append(received, timing)
+		case err := <-done:
+			require.NoError(t, err)
+			t.Fatalf("Reader stopped after %v", received)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out after %v", received)
+		}
+	}
+	assert.Equal(t, []int64{10, 20, 30, 35, 40, 50}, received)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Empty(t, sr.Skipped())
+}
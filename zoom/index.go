@@ -0,0 +1,109 @@
+package zoom
+
+import (
+	"fmt"
+	"sort"
+)
+
+// IndexEntry describes a single SamplePacket inside a file, without its data.
+type IndexEntry struct {
+	// Offset of the packet header magic in the file.
+	Offset       int64
+	Type         MediaType
+	TimingA      int64
+	TimingB      int64
+	DataSize     int32
+	PropertySize int32
+	// Keyframe is set for video packets containing an IDR slice.
+	Keyframe bool
+}
+
+// Index allows random access to the packets of a file.
+// Entries are kept in file order.
+type Index struct {
+	Entries []IndexEntry
+}
+
+// BuildIndex reads every packet of f, starting at the beginning of the data, and records its position.
+// Afterwards the read position of f is at the end of the last packet that could be read.
+func BuildIndex(f *File) (*Index, error) {
+	idx := &Index{}
+	if err := f.SeekTo(f.DataOffset()); err != nil {
+		return nil, err
+	}
+
+	for f.HasData() {
+		offset := f.Offset()
+		numSkipped := len(f.Skipped())
+		pkt := NewSamplePacket()
+		if err := f.ReadPacket(pkt); err != nil {
+			if f.recovery {
+				// the rest of the file was skipped
+				break
+			}
+			return idx, fmt.Errorf("Failed to index packet at 0x%x: %w", offset, err)
+		}
+		if skipped := f.Skipped(); len(skipped) > numSkipped {
+			offset = skipped[len(skipped)-1].End
+		}
+		idx.Entries = append(idx.Entries, IndexEntry{
+			Offset:       offset,
+			Type:         pkt.MediaType(),
+			TimingA:      pkt.TimingA,
+			TimingB:      pkt.TimingB,
+			DataSize:     pkt.DataSize,
+			PropertySize: pkt.PropertySize,
+			Keyframe:     pkt.IsKeyframe(),
+		})
+	}
+
+	return idx, nil
+}
+
+// Len returns the number of indexed packets.
+func (i *Index) Len() int {
+	return len(i.Entries)
+}
+
+// SearchTime returns the position of the first entry with a TimingA of at least t.
+// Returns Len() if there is no such entry.
+// Packets are not strictly ordered by timing, so the entries before the returned one can still contain later timings.
+func (i *Index) SearchTime(t int64) int {
+	for pos, e := range i.Entries {
+		if e.TimingA >= t {
+			return pos
+		}
+	}
+	return len(i.Entries)
+}
+
+// SearchOffset returns the position of the entry starting at or containing offset.
+// Returns -1 if the offset lies before the first packet.
+func (i *Index) SearchOffset(offset int64) int {
+	return sort.Search(len(i.Entries), func(pos int) bool {
+		return i.Entries[pos].Offset > offset
+	}) - 1
+}
+
+// PrecedingKeyframe returns the position of the last keyframe of the given media type with a TimingA of at most t.
+// Returns -1 if there is no such keyframe.
+func (i *Index) PrecedingKeyframe(t int64, typ MediaType) int {
+	found := -1
+	for pos, e := range i.Entries {
+		if e.Type == typ && e.Keyframe && e.TimingA <= t {
+			found = pos
+		}
+	}
+	return found
+}
+
+// Filter returns all entries of the given media type.
+func (i *Index) Filter(typ MediaType) []IndexEntry {
+	ret := []IndexEntry{}
+	for _, e := range i.Entries {
+		if e.Type == typ {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,238 @@
+package zoom
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultSessionGap is the time left between two merged sessions, in TimingA units.
+const DefaultSessionGap = TimingScale
+
+// Session is one recording of a paused or restarted meeting, placed on a merged timeline.
+type Session struct {
+	*Recording
+	// Offset is added to the timings of the recording to place it on the merged timeline.
+	Offset int64
+}
+
+// Merged is a sequence of recordings on one continuous timeline.
+type Merged struct {
+	Sessions []*Session
+	// CmdTypes decodes the command packets of all sessions.
+	CmdTypes CmdTypeTable
+}
+
+// SortRecordingsByPath orders the recordings by their path. Zoom names the recording folders after the date and time
+// of the meeting, so this is the order in which they were recorded.
+func SortRecordingsByPath(recs []*Recording) {
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].Path < recs[j].Path
+	})
+}
+
+// SortRecordingsByStart orders the recordings by the timing of their first packet. This only gives the order in
+// which they were recorded if all of them share a timing base, a session whose clock restarted at zero comes first.
+func SortRecordingsByStart(recs []*Recording) {
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].Start < recs[j].Start
+	})
+}
+
+// MergeRecordings places the recordings one after the other in the given order, separated by gap.
+// Every recording has its own timing base, so their timings are not used to order them.
+func MergeRecordings(recs []*Recording, gap int64) *Merged {
+	m := &Merged{}
+	var end int64
+	for i, rec := range recs {
+		s := &Session{Recording: rec}
+		if i > 0 {
+			s.Offset = end + gap - rec.Start
+		}
+		end = rec.End + s.Offset
+		m.Sessions = append(m.Sessions, s)
+	}
+	return m
+}
+
+// Start returns the first timing of the merged timeline.
+func (m *Merged) Start() int64 {
+	return m.Sessions[0].Start + m.Sessions[0].Offset
+}
+
+// End returns the last timing of the merged timeline.
+func (m *Merged) End() int64 {
+	last := m.Sessions[len(m.Sessions)-1]
+	return last.End + last.Offset
+}
+
+// Rel converts a timing of the merged timeline to one starting at zero.
+func (m *Merged) Rel(t int64) int64 {
+	return t - m.Start()
+}
+
+// Duration returns the length of the merged timeline, in TimingA units.
+func (m *Merged) Duration() int64 {
+	return m.End() - m.Start()
+}
+
+// Rebase moves the timings of a packet of the session onto the merged timeline.
+func (s *Session) Rebase(pkt *SamplePacket) {
+	pkt.TimingA += s.Offset
+	pkt.TimingB += s.Offset
+}
+
+// Commands reads the command packets of all sessions, with rebased timings.
+// It fails if a rebased timing does not fit the 32 bit TimingA of command packets.
+func (m *Merged) Commands() ([]*CmdPacket, error) {
+	ret := []*CmdPacket{}
+	for _, s := range m.Sessions {
+		cmds, err := s.Commands()
+		if err != nil {
+			return ret, err
+		}
+		for _, c := range cmds {
+			timing := int64(c.TimingA) + s.Offset
+			if timing < 0 || timing > math.MaxUint32 {
+				return ret, fmt.Errorf("Failed to rebase command at timing %d of %s: %d is out of range", c.TimingA, s.Path, timing)
+			}
+			c.TimingA = uint32(timing)
+		}
+		ret = append(ret, cmds...)
+	}
+	return ret, nil
+}
+
+// Roster returns the participants of all sessions, together with their avatars.
+// Participants are expected to keep their ident across sessions.
+func (m *Merged) Roster() (*Roster, error) {
+	cmds, err := m.Commands()
+	roster := NewRoster(m.CmdTypes.DecodeEvents(cmds))
+	for _, s := range m.Sessions {
+		avatars, avatarErr := s.Avatars()
+		roster.AddAvatars(avatars)
+		if err == nil {
+			err = avatarErr
+		}
+	}
+	return roster, err
+}
+
+func (m *Merged) Close() {
+	for _, s := range m.Sessions {
+		s.Close()
+	}
+}
+
+// MergeStats counts what MergeSamples did to the video streams.
+type MergeStats struct {
+	Packets int
+	// Dropped is the number of video packets at the start of a session that preceded its first keyframe.
+	Dropped int
+	// ParameterChanges is the number of sessions in which a video stream used different parameter sets than before.
+	ParameterChanges int
+}
+
+type videoStreamKey struct {
+	typ   MediaType
+	ident int32
+}
+
+// MergeSamples writes the packets of all sample files of all sessions to w, with rebased timings.
+// The header is taken from the first sample file.
+// Every video stream restarts at its first keyframe in each session, which gets the parameter sets prepended if it has none.
+func (m *Merged) MergeSamples(w *Writer) (MergeStats, error) {
+	stats := MergeStats{}
+	first := m.Sessions[0].SampleFiles[0]
+	if err := w.WriteBeginning(first.Header); err != nil {
+		return stats, err
+	}
+
+	params := map[videoStreamKey][2][]byte{}
+	for _, s := range m.Sessions {
+		started := map[videoStreamKey]bool{}
+		for _, sf := range s.SampleFiles {
+			f, err := sf.Fork()
+			if err != nil {
+				return stats, err
+			}
+			err = m.mergeFile(f, s, w, started, params, &stats)
+			f.Close()
+			if err != nil {
+				return stats, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+func (m *Merged) mergeFile(f *File, s *Session, w *Writer, started map[videoStreamKey]bool, params map[videoStreamKey][2][]byte, stats *MergeStats) error {
+	idx, err := BuildIndex(f)
+	if err != nil {
+		return err
+	}
+	for pos, e := range idx.Entries {
+		pkt := NewSamplePacket()
+		if err := f.SeekTo(e.Offset); err != nil {
+			return err
+		}
+		if err := f.ReadPacket(pkt); err != nil {
+			return fmt.Errorf("Failed to read packet at 0x%x: %w", e.Offset, err)
+		}
+
+		if vid := pkt.VideoProp(); vid != nil {
+			key := videoStreamKey{pkt.MediaType(), vid.NameIdent}
+			if key.typ == VideoScreenShare {
+				// the screen share is a single stream, regardless of who shares
+				key.ident = 0
+			}
+			if !started[key] {
+				if !pkt.IsKeyframe() {
+					stats.Dropped++
+					continue
+				}
+				started[key] = true
+				prev, seen := params[key]
+				sps, pps, _ := pkt.ParameterSets()
+				if sps == nil || pps == nil {
+					if err := prependParameterSets(f, idx, pos, pkt); err != nil {
+						if !seen {
+							return err
+						}
+						// take them from the previous session
+						setParameterSets(pkt, prev[0], prev[1])
+					}
+					sps, pps, _ = pkt.ParameterSets()
+				}
+				if seen && (!bytes.Equal(sps, prev[0]) || !bytes.Equal(pps, prev[1])) {
+					stats.ParameterChanges++
+				}
+				params[key] = [2][]byte{sps, pps}
+			} else if sps, pps, _ := pkt.ParameterSets(); sps != nil && pps != nil {
+				params[key] = [2][]byte{sps, pps}
+			}
+		}
+
+		s.Rebase(pkt)
+		if err := w.WritePacket(pkt); err != nil {
+			return err
+		}
+		stats.Packets++
+	}
+	return nil
+}
+
+// MergeCommands writes the command packets of all sessions to w, with rebased timings.
+func (m *Merged) MergeCommands(w *Writer) (int, error) {
+	cmds, err := m.Commands()
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range cmds {
+		if err := w.WritePacket(c); err != nil {
+			return 0, err
+		}
+	}
+	return len(cmds), nil
+}
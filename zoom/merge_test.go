@@ -0,0 +1,93 @@
+package zoom
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeRecordings(t *testing.T) {
+	videoProp := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0x80, 0x07, 0, 0, 0x38, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	otherIDR := []byte{0, 0, 0, 1, 0x67, 0x64, 0x00, 0x28, 0, 0, 0, 1, 0x68, 0xef, 0, 0, 0, 1, 0x65, 0x88, 0x84}
+	idrOnly := []byte{0, 0, 0, 1, 0x65, 0x88, 0x84}
+	first := writeTestFile(t, []*SamplePacket{
+		newTestSample(VideoScreenShare, 1000, videoProp, idrFrame),
+		newTestSample(Audio, 1010, []byte{1, 2, 3}, []byte{0x11}),
+		newTestSample(VideoScreenShare, 1040, videoProp, nonIdrFrame),
+	})
+	// the second session restarts its timing and starts with a frame that cannot be decoded on its own
+	second := writeTestFile(t, []*SamplePacket{
+		newTestSample(VideoScreenShare, 0, videoProp, nonIdrFrame),
+		newTestSample(VideoScreenShare, 40, videoProp, otherIDR),
+		newTestSample(Audio, 50, []byte{1, 2, 3}, []byte{0x22}),
+	})
+	third := writeTestFile(t, []*SamplePacket{
+		newTestSample(VideoScreenShare, 500, videoProp, idrOnly),
+	})
+
+	var recs []*Recording
+	for _, filename := range []string{third, first, second} {
+		rec, err := OpenRecordingFiles([]string{filename}, testLog)
+		require.NoError(t, err)
+		recs = append(recs, rec)
+	}
+	byStart := append([]*Recording{}, recs...)
+	SortRecordingsByStart(byStart)
+	assert.Equal(t, []*Recording{recs[2], recs[0], recs[1]}, byStart)
+	// the paths are in the order the sessions were written
+	SortRecordingsByPath(recs)
+	m := MergeRecordings(recs, 100)
+	defer m.Close()
+
+	require.Len(t, m.Sessions, 3)
+	for i, filename := range []string{first, second, third} {
+		assert.Equal(t, filepath.Dir(filename), m.Sessions[i].Path)
+	}
+	assert.Equal(t, int64(0), m.Sessions[0].Offset)
+	// the second session starts 100 after the end of the first one
+	assert.Equal(t, int64(1140), m.Sessions[1].Offset)
+	assert.Equal(t, int64(1190+100-500), m.Sessions[2].Offset)
+	assert.Equal(t, int64(1000), m.Start())
+	assert.Equal(t, int64(290), m.Duration())
+
+	out := filepath.Join(t.TempDir(), "merged.zoom")
+	w, err := CreateFile(out, testLog)
+	require.NoError(t, err)
+	stats, err := m.MergeSamples(w)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, MergeStats{Packets: 6, Dropped: 1, ParameterChanges: 1}, stats)
+
+	pkts := readTestFile(t, out)
+	timings := []int64{}
+	for _, p := range pkts {
+		timings = append(timings, p.TimingA)
+	}
+	assert.Equal(t, []int64{1000, 1010, 1040, 1180, 1190, 1290}, timings)
+	// the keyframe of the last session gets the parameter sets of the one before
+	sps, pps, _ := pkts[5].ParameterSets()
+	assert.Equal(t, []byte{0x67, 0x64, 0x00, 0x28}, sps)
+	assert.Equal(t, []byte{0x68, 0xef}, pps)
+}
+
+func TestMergedCommandsOutOfRange(t *testing.T) {
+	dir := writeTestRecording(t)
+	rec, err := OpenRecording(dir, testLog)
+	require.NoError(t, err)
+	m := MergeRecordings([]*Recording{rec}, 0)
+	defer m.Close()
+
+	cmds, err := m.Commands()
+	require.NoError(t, err)
+	assert.Len(t, cmds, 2)
+
+	m.Sessions[0].Offset = -10
+	_, err = m.Commands()
+	assert.Error(t, err)
+	m.Sessions[0].Offset = math.MaxUint32
+	_, err = m.Commands()
+	assert.Error(t, err)
+}
@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"image"
+	"time"
+
+	"github.com/nareix/joy5/codec/h264"
 )
 
 const (
@@ -11,6 +15,15 @@ const (
 	trailer        = 0x84AD52E2
 )
 
+// TimingScale is the number of TimingA / TimingB ticks per second.
+// The timings in all recordings seen so far are milliseconds.
+const TimingScale = 1000
+
+// TimingToDuration converts a TimingA / TimingB value to a duration.
+func TimingToDuration(t int64) time.Duration {
+	return time.Duration(t) * time.Second / TimingScale
+}
+
 const (
 	HeaderSize int = 96
 	CmdSize    int = 64
@@ -21,13 +34,13 @@ type Header struct {
 	Header  uint32
 	Trailer uint32
 	// + 8
-	_ [8]byte // Unknown
-	_ [8]byte // Unknown but has something to do with file size?
-	_ [8]byte // Unknown
+	Unknown0 [8]byte // Unknown
+	Unknown1 [8]byte // Unknown but has something to do with file size?
+	Unknown2 [8]byte // Unknown
 	// + 32
 	VersionInfo uint32
 	FileOffset  uint32   // offset to start of actual data
-	_           [56]byte // Unknown and unused at the moment.
+	Unused      [56]byte // Unknown and unused at the moment.
 }
 
 func (h *Header) IsMC() bool {
@@ -59,15 +72,15 @@ func (h *Header) VersionNumber() int {
 type CmdHeader struct {
 	SizeToRead     uint32
 	Type           int32
-	_              [8]byte // Unknown
+	Unknown0       [8]byte // Unknown
 	NameIdent      uint32
-	_              [4]byte // Unknown
+	Unknown1       [4]byte // Unknown
 	TimingA        uint32
-	_              [22]byte // Unknown
+	Unknown2       [22]byte // Unknown
 	SomeType       uint16
-	_              [4]byte
+	Unknown3       [4]byte
 	AdditionalSize int32
-	_              [4]byte // Unknown
+	Unknown4       [4]byte // Unknown
 }
 
 type CmdPacket struct {
@@ -90,6 +103,7 @@ func (p *CmdPacket) String() string {
 }
 
 func (p *CmdPacket) ReadPacket(f *File) error {
+	p.AdditionalData = nil
 	if err := f.ReadStruct(CmdSize, p.CmdHeader); err != nil {
 		return fmt.Errorf("Failed to read CmdHeader: %w", err)
 	}
@@ -104,6 +118,18 @@ func (p *CmdPacket) ReadPacket(f *File) error {
 	return nil
 }
 
+func (p *CmdPacket) WritePacket(w *Writer) error {
+	if err := w.WriteStruct(p.CmdHeader); err != nil {
+		return fmt.Errorf("Failed to write CmdHeader: %w", err)
+	}
+	if p.AdditionalSize > 0 {
+		if err := w.WriteExactBytes(p.AdditionalData[:p.AdditionalSize]); err != nil {
+			return fmt.Errorf("Failed to write %d bytes of additional data: %w", p.AdditionalSize, err)
+		}
+	}
+	return nil
+}
+
 type MediaType int32
 
 const (
@@ -114,21 +140,27 @@ const (
 	Cursor           MediaType = 0x1000
 )
 
+//go:generate enumer -type=MediaType
+
 type SampleHeader struct {
 	Type         int32
-	_            [4]byte // + 8
+	Unknown0     [4]byte // + 8
 	TimingA      int64   // + 16
 	TimingB      int64   // + 24
-	_            [8]byte // + 32
+	Unknown1     [8]byte // + 32
 	DataSize     int32
 	PropertySize int32 // + 40
-	_            [8]byte
+	Unknown2     [8]byte
 }
 
 type SamplePacket struct {
 	*SampleHeader
 	Data     []byte
 	Property []byte
+
+	// padding read after Property and Data, kept so WritePacket reproduces the packet byte for byte
+	propertyPadding []byte
+	dataPadding     []byte
 }
 
 func NewSamplePacket() *SamplePacket {
@@ -142,16 +174,26 @@ func (p *SamplePacket) ReadPacket(f *File) error {
 		return fmt.Errorf("Failed to read sample packet: %w", err)
 	}
 
+	p.Property = nil
+	p.Data = nil
+	p.propertyPadding = nil
+	p.dataPadding = nil
+
 	if p.Type < 0 {
 		return nil
 	}
 
+	if p.DataSize < 0 || p.PropertySize < 0 {
+		return fmt.Errorf("Invalid sample packet sizes: data %d, property %d", p.DataSize, p.PropertySize)
+	}
+
 	if p.PropertySize > 0 {
 		data, err := f.ReadBytes(int(p.PropertySize))
 		if err != nil {
 			return fmt.Errorf("Failed to read property: %w", err)
 		}
-		p.Property = data
+		p.Property = data[:p.PropertySize]
+		p.propertyPadding = data[p.PropertySize:]
 	}
 
 	if p.DataSize > 0 {
@@ -160,6 +202,7 @@ func (p *SamplePacket) ReadPacket(f *File) error {
 			return fmt.Errorf("Failed to read data: %w", err)
 		}
 		p.Data = data[:p.DataSize]
+		p.dataPadding = data[p.DataSize:]
 	}
 
 	// f.log.Debugf("Read Packet: %v, %d, %d", p.SampleHeader, p.DataSize, p.PropertySize)
@@ -167,10 +210,97 @@ func (p *SamplePacket) ReadPacket(f *File) error {
 	return nil
 }
 
+func (p *SamplePacket) WritePacket(w *Writer) error {
+	if err := w.WriteStruct(p.SampleHeader); err != nil {
+		return fmt.Errorf("Failed to write sample packet: %w", err)
+	}
+
+	if p.Type < 0 {
+		return nil
+	}
+
+	if p.PropertySize > 0 {
+		if err := writePadded(w, p.Property, int(p.PropertySize), p.propertyPadding); err != nil {
+			return fmt.Errorf("Failed to write property: %w", err)
+		}
+	}
+
+	if p.DataSize > 0 {
+		if err := writePadded(w, p.Data, int(p.DataSize), p.dataPadding); err != nil {
+			return fmt.Errorf("Failed to write data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writePadded writes the first size bytes of data followed by the padding read with it.
+// The padding is only reused if it still fits size, otherwise WriteBytes pads with zeros.
+func writePadded(w *Writer, data []byte, size int, padding []byte) error {
+	if len(data) >= size && size+len(padding) == (size+3)&0xfffffffc {
+		buf := make([]byte, 0, size+len(padding))
+		buf = append(append(buf, data[:size]...), padding...)
+		return w.WriteBytes(buf, size)
+	}
+	return w.WriteBytes(data, size)
+}
+
 func (p *SamplePacket) MediaType() MediaType {
 	return MediaType(p.Type)
 }
 
+func (p *SamplePacket) IsVideo() bool {
+	return p.MediaType() == VideoWebCam || p.MediaType() == VideoScreenShare
+}
+
+// NALUs splits the data of a video packet into its NAL units (without start codes).
+func (p *SamplePacket) NALUs() [][]byte {
+	nalus, _ := h264.SplitNALUs(p.Data)
+	return nalus
+}
+
+// ParameterSets splits the NAL units of a video packet into its last SPS and PPS, nil if it has none,
+// and all other NAL units.
+func (p *SamplePacket) ParameterSets() (sps, pps []byte, others [][]byte) {
+	for _, nalu := range p.NALUs() {
+		if len(nalu) == 0 {
+			continue
+		}
+		switch h264.NALUType(nalu) {
+		case h264.NALU_SPS:
+			sps = nalu
+		case h264.NALU_PPS:
+			pps = nalu
+		default:
+			others = append(others, nalu)
+		}
+	}
+	return sps, pps, others
+}
+
+// IsKeyframe returns true if the packet is a video packet containing an IDR slice.
+func (p *SamplePacket) IsKeyframe() bool {
+	if !p.IsVideo() {
+		return false
+	}
+	for _, nalu := range p.NALUs() {
+		if len(nalu) > 0 && h264.NALUType(nalu) == h264.NALU_IDR {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyBytes returns the property, extended with zeros to at least size bytes.
+func (p *SamplePacket) propertyBytes(size int) []byte {
+	if len(p.Property) >= size {
+		return p.Property
+	}
+	prop := make([]byte, size)
+	copy(prop, p.Property)
+	return prop
+}
+
 type VideoProp struct {
 	NameIdent int32
 	_         [4]byte
@@ -179,12 +309,13 @@ type VideoProp struct {
 	_         [8]byte
 }
 
+// VideoProp returns the property of screen share and webcam packets, nil for all other packets.
 func (p *SamplePacket) VideoProp() *VideoProp {
-	if p.MediaType() == VideoScreenShare {
+	if p.IsVideo() {
 		if p.PropertySize > 8 {
 			vid := &VideoProp{}
 
-			buf := bytes.NewBuffer(p.Property)
+			buf := bytes.NewBuffer(p.propertyBytes(binary.Size(vid)))
 			binary.Read(buf, binary.LittleEndian, vid)
 			return vid
 		}
@@ -207,7 +338,7 @@ func (p *SamplePacket) CursorProp() *CursorProp {
 		if p.PropertySize > 8 {
 			cur := &CursorProp{}
 
-			buf := bytes.NewBuffer(p.Property)
+			buf := bytes.NewBuffer(p.propertyBytes(binary.Size(cur)))
 			binary.Read(buf, binary.LittleEndian, cur)
 			return cur
 		}
@@ -215,3 +346,74 @@ func (p *SamplePacket) CursorProp() *CursorProp {
 
 	return nil
 }
+
+// AvatarProp is the property of avatar packets, which carry the profile picture of a participant.
+type AvatarProp struct {
+	NameIdent int32
+}
+
+// AvatarProp returns the property of avatar packets, nil for all other packets.
+func (p *SamplePacket) AvatarProp() *AvatarProp {
+	if p.MediaType() == Avatar && len(p.Property) >= 4 {
+		return &AvatarProp{NameIdent: int32(binary.LittleEndian.Uint32(p.Property))}
+	}
+
+	return nil
+}
+
+// CursorImage returns the cursor shape carried by cursor packets, nil if the packet has no image.
+// Packets that only move the cursor carry no data.
+func (p *SamplePacket) CursorImage() (*image.RGBA, error) {
+	cur := p.CursorProp()
+	if cur == nil || len(p.Data) == 0 {
+		return nil, nil
+	}
+	if cur.Width <= 0 || cur.Height <= 0 || len(p.Data) != int(cur.Width)*int(cur.Height)*4 {
+		return nil, fmt.Errorf("Cursor image of %d bytes does not match its size %dx%d", len(p.Data), cur.Width, cur.Height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(cur.Width), int(cur.Height)))
+	copy(img.Pix, p.Data)
+	return img, nil
+}
+
+// AudioProp is the property of audio packets.
+// None of its fields were verified against the client. NameIdent is assumed to come first, as in the properties of
+// the other media types, the meaning of the words after it is a guess. The audioprop command tabulates them to pin them down.
+type AudioProp struct {
+	NameIdent int32
+	// Unknown4 might be the sample rate.
+	Unknown4 int32
+	// Unknown8 might be the channel count.
+	Unknown8 int32
+	// Unknown12 might be a sequence number.
+	Unknown12 int32
+	// Rest holds the bytes following the fields above.
+	Rest []byte
+}
+
+// audioPropSize is the size of the fixed fields of AudioProp.
+const audioPropSize = 16
+
+// AudioProp returns the property of audio packets, nil for all other packets.
+// Fields missing from a short property are left zero.
+func (p *SamplePacket) AudioProp() *AudioProp {
+	prop := p.Property
+	if int(p.PropertySize) < len(prop) {
+		prop = prop[:p.PropertySize]
+	}
+	if p.MediaType() != Audio || len(prop) < 4 {
+		return nil
+	}
+	fixed := make([]byte, audioPropSize)
+	copy(fixed, prop)
+	aud := &AudioProp{
+		NameIdent: int32(binary.LittleEndian.Uint32(fixed[0:])),
+		Unknown4:  int32(binary.LittleEndian.Uint32(fixed[4:])),
+		Unknown8:  int32(binary.LittleEndian.Uint32(fixed[8:])),
+		Unknown12: int32(binary.LittleEndian.Uint32(fixed[12:])),
+	}
+	if len(prop) > audioPropSize {
+		aud.Rest = prop[audioPropSize:]
+	}
+	return aud
+}
@@ -0,0 +1,38 @@
+package zoom
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cursorProp(x, y, w, h int32) []byte {
+	b := make([]byte, 24)
+	for i, v := range []int32{x, y, w, h, 0x1000, 1} {
+		binary.LittleEndian.PutUint32(b[i*4:], uint32(v))
+	}
+	return b
+}
+
+func TestCursorImage(t *testing.T) {
+	data := make([]byte, 2*3*4)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	img, err := newTestSample(Cursor, 0, cursorProp(10, 20, 2, 3), data).CursorImage()
+	require.NoError(t, err)
+	require.NotNil(t, img)
+	assert.Equal(t, 2, img.Rect.Dx())
+	assert.Equal(t, 3, img.Rect.Dy())
+	assert.Equal(t, data, img.Pix)
+
+	// position updates carry no image
+	img, err = newTestSample(Cursor, 0, cursorProp(10, 20, 2, 3), nil).CursorImage()
+	assert.NoError(t, err)
+	assert.Nil(t, img)
+
+	_, err = newTestSample(Cursor, 0, cursorProp(10, 20, 4, 4), data).CursorImage()
+	assert.Error(t, err)
+}
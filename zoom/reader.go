@@ -1,7 +1,11 @@
 package zoom
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -11,18 +15,93 @@ func NewSampleReader(log *zap.SugaredLogger) *SampleReader {
 }
 
 type SampleReader struct {
-	f    *File
-	log  *zap.SugaredLogger
-	curr *SamplePacket
+	f        *File
+	log      *zap.SugaredLogger
+	curr     *SamplePacket
+	err      error
+	index    *Index
+	recovery bool
+	follow   context.Context
 }
 
+// FollowPollInterval is how often a following SampleReader checks whether the file grew.
+var FollowPollInterval = 250 * time.Millisecond
+
 func (s *SampleReader) Open(filename string) error {
-	var err error
-	s.f, err = OpenFile(filename, s.log)
-	if err == nil {
-		err = s.f.ReadBeginning()
+	f, err := OpenFile(filename, s.log)
+	if err != nil {
+		return err
+	}
+	return s.OpenFile(f)
+}
+
+// OpenFile reads samples from an already opened file, for example one created with NewFile or NewFileAt.
+func (s *SampleReader) OpenFile(f *File) error {
+	s.f = f
+	s.index = nil
+	s.err = nil
+	s.f.SetRecovery(s.recovery)
+	s.f.SetFollow(s.follow != nil)
+	for {
+		err := s.f.ReadBeginning()
+		if err == nil || s.follow == nil || !errors.Is(err, ErrIncomplete) {
+			return err
+		}
+		// the header has not been written yet
+		if err := s.wait(); err != nil {
+			return err
+		}
+	}
+}
+
+// File returns the underlying file.
+func (s *SampleReader) File() *File {
+	return s.f
+}
+
+// SetRecovery enables recovery mode on the underlying file, see File.SetRecovery.
+// Damaged packets are skipped instead of ending the iteration.
+func (s *SampleReader) SetRecovery(enabled bool) {
+	s.recovery = enabled
+	if s.f != nil {
+		s.f.SetRecovery(enabled)
+	}
+}
+
+// Follow makes the reader wait for packets appended to a file that is still being written, until ctx is done.
+// Next delivers packets as soon as they were written completely, a packet the file ends in the middle of is not treated as damaged.
+// Once ctx is done, Next returns false and Err returns nil.
+// Follow has to be called before opening the file.
+func (s *SampleReader) Follow(ctx context.Context) {
+	s.follow = ctx
+}
+
+// wait blocks until the file grew, or the context passed to Follow is done.
+func (s *SampleReader) wait() error {
+	size := s.f.Size()
+	ticker := time.NewTicker(FollowPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.follow.Done():
+			return s.follow.Err()
+		case <-ticker.C:
+		}
+		newSize, err := s.f.Refresh()
+		if err != nil {
+			return err
+		}
+		if newSize > size {
+			// the index only covers the packets that were there when it was built
+			s.index = nil
+			return nil
+		}
 	}
-	return err
+}
+
+// Skipped returns the byte ranges skipped in recovery mode.
+func (s *SampleReader) Skipped() []SkippedRange {
+	return s.f.Skipped()
 }
 
 func (s *SampleReader) Close() {
@@ -30,22 +109,109 @@ func (s *SampleReader) Close() {
 }
 
 func (s *SampleReader) Next() bool {
-	if s.f.HasData() {
-		s.curr = NewSamplePacket()
-		err := s.f.ReadPacket(s.curr)
-		if err != nil {
-			s.log.Errorf("Failed to read packet: %v", err)
+	for {
+		if s.f.HasData() {
+			s.curr = NewSamplePacket()
+			s.err = s.f.ReadPacket(s.curr)
+			if s.err == nil {
+				return true
+			}
+			if s.follow == nil || !errors.Is(s.err, ErrIncomplete) {
+				s.log.Errorf("Failed to read packet: %v", s.err)
+				return false
+			}
+		} else if s.follow == nil {
+			return false
+		}
+
+		if err := s.wait(); err != nil {
+			s.err = nil
+			if s.follow.Err() == nil {
+				s.err = err
+				s.log.Errorf("Failed to follow file: %v", err)
+			}
+			return false
 		}
-		return err == nil
 	}
+}
 
-	return false
+// Err returns the error that stopped Next, or nil if the end of the file was reached.
+func (s *SampleReader) Err() error {
+	return s.err
 }
 
 func (s *SampleReader) Current() *SamplePacket {
 	return s.curr
 }
 
+// Index returns the packet index of the file, building it on first use.
+// The read position is left unchanged, even if building the index fails.
+// When following, a packet the file ends in the middle of is still being written, so the index ends before it.
+func (s *SampleReader) Index() (*Index, error) {
+	if s.index != nil {
+		return s.index, nil
+	}
+
+	offset := s.f.Offset()
+	idx, err := BuildIndex(s.f)
+	if err != nil && s.follow != nil && (errors.Is(err, ErrIncomplete) || errors.Is(err, io.EOF)) {
+		err = nil
+	}
+	if seekErr := s.f.SeekTo(offset); err == nil {
+		err = seekErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.index = idx
+	return idx, nil
+}
+
+// SeekOffset moves the reader to the packet starting at or containing the given file offset.
+func (s *SampleReader) SeekOffset(offset int64) error {
+	idx, err := s.Index()
+	if err != nil {
+		return err
+	}
+	s.err = nil
+	pos := idx.SearchOffset(offset)
+	if pos < 0 {
+		return s.f.SeekTo(s.f.DataOffset())
+	}
+	return s.f.SeekTo(idx.Entries[pos].Offset)
+}
+
+// SeekTime moves the reader to the first packet with a TimingA of at least t.
+func (s *SampleReader) SeekTime(t int64) error {
+	idx, err := s.Index()
+	if err != nil {
+		return err
+	}
+	return s.seekEntry(idx, idx.SearchTime(t))
+}
+
+// SeekKeyframe moves the reader to the last keyframe of the given media type at or before t,
+// so decoding can start from there.
+func (s *SampleReader) SeekKeyframe(t int64, typ MediaType) error {
+	idx, err := s.Index()
+	if err != nil {
+		return err
+	}
+	pos := idx.PrecedingKeyframe(t, typ)
+	if pos < 0 {
+		return fmt.Errorf("No keyframe of media type %d at or before %d", typ, t)
+	}
+	return s.seekEntry(idx, pos)
+}
+
+func (s *SampleReader) seekEntry(idx *Index, pos int) error {
+	s.err = nil
+	if pos >= idx.Len() {
+		return s.f.SeekTo(s.f.Size())
+	}
+	return s.f.SeekTo(idx.Entries[pos].Offset)
+}
+
 type SampleDataFilter func(*SamplePacket) bool
 
 type SampleDataTransformer func([]byte) []byte
@@ -99,6 +265,8 @@ func (r *SampleDataReader) copyTo(b []byte) (n int, err error) {
 	return
 }
 
+// Read returns the data of at most one packet at a time,
+// so data is delivered as it arrives when following a file.
 func (r *SampleDataReader) Read(p []byte) (n int, err error) {
 	left := len(p)
 	var diff int
@@ -109,6 +277,9 @@ func (r *SampleDataReader) Read(p []byte) (n int, err error) {
 		}
 		left -= diff
 		n += diff
+		if len(r.buf) == 0 {
+			return
+		}
 	}
 
 	return
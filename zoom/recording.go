@@ -0,0 +1,408 @@
+package zoom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// FileKind is the kind of data stored in a .zoom file.
+type FileKind int
+
+const (
+	UnknownFile FileKind = iota
+	// SampleFile starts with a Header and contains SamplePackets.
+	SampleFile
+	// CmdFile has no header and contains CmdPackets.
+	CmdFile
+)
+
+// DetectFileKind looks at the first bytes of f to determine what kind of packets it contains.
+// The read position of f is reset to the start of the file.
+func DetectFileKind(f *File) (FileKind, error) {
+	if err := f.SeekTo(0); err != nil {
+		return UnknownFile, err
+	}
+	defer f.SeekTo(0)
+
+	if f.Size() < 8 {
+		return UnknownFile, nil
+	}
+	start, err := f.ReadExactBytes(8)
+	if err != nil {
+		return UnknownFile, err
+	}
+	if binary.LittleEndian.Uint32(start) != header {
+		return UnknownFile, nil
+	}
+	// Sample files start with a Header, which has the trailer right after the header magic.
+	// Command files start with a CmdPacket, where the header is followed by its SizeToRead.
+	if binary.LittleEndian.Uint32(start[4:]) == trailer {
+		return SampleFile, nil
+	}
+	return CmdFile, nil
+}
+
+// TrackKind identifies a track of a recording.
+type TrackKind int
+
+const (
+	TrackAudio TrackKind = iota
+	TrackWebCam
+	TrackScreenShare
+	TrackCursor
+	TrackAvatar
+	TrackCommands
+)
+
+//go:generate enumer -type=TrackKind -trimprefix=Track
+
+// TrackKindFor returns the track kind that packets of the given media type belong to.
+func TrackKindFor(typ MediaType) (TrackKind, bool) {
+	switch typ {
+	case Audio:
+		return TrackAudio, true
+	case VideoWebCam:
+		return TrackWebCam, true
+	case VideoScreenShare:
+		return TrackScreenShare, true
+	case Cursor:
+		return TrackCursor, true
+	case Avatar:
+		return TrackAvatar, true
+	}
+	return 0, false
+}
+
+type trackEntry struct {
+	f *File
+	IndexEntry
+}
+
+// Track is the list of packets of one kind across all sample files of a recording.
+type Track struct {
+	Kind    TrackKind
+	entries []trackEntry
+}
+
+// Len returns the number of packets in the track.
+func (t *Track) Len() int {
+	return len(t.entries)
+}
+
+// Entries returns the index entries of the track, in timing order.
+func (t *Track) Entries() []IndexEntry {
+	ret := make([]IndexEntry, len(t.entries))
+	for i, e := range t.entries {
+		ret[i] = e.IndexEntry
+	}
+	return ret
+}
+
+// Reader returns a reader over the packets of the track.
+// Readers of different tracks can be used concurrently.
+func (t *Track) Reader() *TrackReader {
+	return &TrackReader{t: t, pos: -1, files: map[*File]*File{}}
+}
+
+// TrackReader reads the packets of a track in timing order.
+type TrackReader struct {
+	t     *Track
+	pos   int
+	curr  *SamplePacket
+	err   error
+	files map[*File]*File
+}
+
+// file returns the reader's own fork of f, so it does not share the read position with other readers.
+func (r *TrackReader) file(f *File) (*File, error) {
+	if fork, ok := r.files[f]; ok {
+		return fork, nil
+	}
+	fork, err := f.Fork()
+	if err != nil {
+		return nil, err
+	}
+	r.files[f] = fork
+	return fork, nil
+}
+
+func (r *TrackReader) Next() bool {
+	if r.err != nil || r.pos+1 >= len(r.t.entries) {
+		return false
+	}
+	r.pos++
+	e := r.t.entries[r.pos]
+	var f *File
+	if f, r.err = r.file(e.f); r.err != nil {
+		return false
+	}
+	if r.err = f.SeekTo(e.Offset); r.err != nil {
+		return false
+	}
+	r.curr = NewSamplePacket()
+	r.err = f.ReadPacket(r.curr)
+	return r.err == nil
+}
+
+func (r *TrackReader) Current() *SamplePacket {
+	return r.curr
+}
+
+// Entry returns the index entry of the current packet.
+func (r *TrackReader) Entry() IndexEntry {
+	return r.t.entries[r.pos].IndexEntry
+}
+
+func (r *TrackReader) Error() error {
+	return r.err
+}
+
+// Recording is a whole zoom recording folder, made up of one or more sample files and a command file.
+type Recording struct {
+	Path        string
+	SampleFiles []*File
+	CmdFile     *File
+	// Unknown lists the files that are neither sample nor command files.
+	Unknown []string
+	// CmdTypes decodes the command packets, with a nil table every command is a RawEvent.
+	CmdTypes CmdTypeTable
+
+	tracks map[TrackKind]*Track
+	// Start and End of the recording, as TimingA values.
+	Start int64
+	End   int64
+
+	names map[*File]string
+	log   *zap.SugaredLogger
+}
+
+// OpenRecording opens every .zoom file in the directory and indexes the sample files.
+func OpenRecording(dir string, log *zap.SugaredLogger) (*Recording, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.zoom"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list files in %s: %w", dir, err)
+	}
+	sort.Strings(filenames)
+	return openRecording(dir, filenames, log)
+}
+
+// OpenRecordingFiles opens the given .zoom files as a single recording, e.g. a lone sample file.
+func OpenRecordingFiles(filenames []string, log *zap.SugaredLogger) (*Recording, error) {
+	dir := ""
+	if len(filenames) > 0 {
+		dir = filepath.Dir(filenames[0])
+	}
+	return openRecording(dir, filenames, log)
+}
+
+func openRecording(dir string, filenames []string, log *zap.SugaredLogger) (*Recording, error) {
+	r := &Recording{
+		Path:   dir,
+		tracks: map[TrackKind]*Track{},
+		names:  map[*File]string{},
+		log:    log.Named("recording"),
+	}
+
+	for _, filename := range filenames {
+		f, err := OpenFile(filename, log)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.names[f] = filename
+		kind, err := DetectFileKind(f)
+		if err != nil {
+			f.Close()
+			r.Close()
+			return nil, fmt.Errorf("Failed to detect kind of %s: %w", filename, err)
+		}
+		switch kind {
+		case SampleFile:
+			if err := f.ReadBeginning(); err != nil {
+				f.Close()
+				r.Close()
+				return nil, fmt.Errorf("Failed to read beginning of %s: %w", filename, err)
+			}
+			r.SampleFiles = append(r.SampleFiles, f)
+		case CmdFile:
+			if r.CmdFile != nil {
+				r.log.Warnw("Found more than one command file, ignoring", "filename", filename)
+				f.Close()
+				continue
+			}
+			r.CmdFile = f
+		default:
+			r.log.Warnw("Ignoring file of unknown kind", "filename", filename)
+			r.Unknown = append(r.Unknown, filename)
+			f.Close()
+		}
+	}
+
+	if len(r.SampleFiles) == 0 {
+		r.Close()
+		return nil, fmt.Errorf("No sample files found in %s", dir)
+	}
+
+	if err := r.buildTracks(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Recording) buildTracks() error {
+	first := true
+	for _, f := range r.SampleFiles {
+		idx, err := BuildIndex(f)
+		if err != nil {
+			return err
+		}
+		for _, e := range idx.Entries {
+			kind, ok := TrackKindFor(e.Type)
+			if !ok {
+				r.log.Debugw("Packet with unknown media type", "type", e.Type, "offset", e.Offset)
+				continue
+			}
+			t := r.tracks[kind]
+			if t == nil {
+				t = &Track{Kind: kind}
+				r.tracks[kind] = t
+			}
+			t.entries = append(t.entries, trackEntry{f, e})
+			r.extend(e.TimingA, first)
+			first = false
+		}
+	}
+
+	for _, t := range r.tracks {
+		sort.SliceStable(t.entries, func(i, j int) bool {
+			return t.entries[i].TimingA < t.entries[j].TimingA
+		})
+	}
+
+	if r.CmdFile != nil {
+		cmds, err := r.Commands()
+		if err != nil {
+			return err
+		}
+		for _, c := range cmds {
+			r.extend(int64(c.TimingA), first)
+			first = false
+		}
+	}
+
+	return nil
+}
+
+func (r *Recording) extend(t int64, first bool) {
+	if first || t < r.Start {
+		r.Start = t
+	}
+	if first || t > r.End {
+		r.End = t
+	}
+}
+
+// Filename returns the name the sample or command file f was opened from.
+func (r *Recording) Filename(f *File) string {
+	return r.names[f]
+}
+
+// Track returns the track of the given kind, or nil if the recording has no such packets.
+// Commands are not a sample track, use Commands instead.
+func (r *Recording) Track(kind TrackKind) *Track {
+	return r.tracks[kind]
+}
+
+// Tracks returns all sample tracks present in the recording.
+func (r *Recording) Tracks() []*Track {
+	ret := []*Track{}
+	for kind := TrackAudio; kind < TrackCommands; kind++ {
+		if t, ok := r.tracks[kind]; ok {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// Commands reads all packets of the command file.
+func (r *Recording) Commands() ([]*CmdPacket, error) {
+	if r.CmdFile == nil {
+		return nil, nil
+	}
+	if err := r.CmdFile.SeekTo(0); err != nil {
+		return nil, err
+	}
+	ret := []*CmdPacket{}
+	for r.CmdFile.HasData() {
+		p := NewCmdPacket()
+		if err := r.CmdFile.ReadPacket(p); err != nil {
+			return ret, fmt.Errorf("Failed to read command packet: %w", err)
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// Events reads and decodes all packets of the command file.
+func (r *Recording) Events() ([]Event, error) {
+	cmds, err := r.Commands()
+	events := r.CmdTypes.DecodeEvents(cmds)
+	if AllRaw(events) {
+		r.log.Warnw("The command file only holds raw events, pass the command types of the recording to decode them", "commands", len(events))
+	}
+	return events, err
+}
+
+// Roster returns the participants seen in the command file, together with their avatars.
+func (r *Recording) Roster() (*Roster, error) {
+	events, err := r.Events()
+	roster := NewRoster(events)
+	avatars, avatarErr := r.Avatars()
+	roster.AddAvatars(avatars)
+	if err == nil {
+		err = avatarErr
+	}
+	return roster, err
+}
+
+// Avatars reads the distinct avatars of all participants.
+func (r *Recording) Avatars() ([]*AvatarImage, error) {
+	t := r.Track(TrackAvatar)
+	if t == nil {
+		return nil, nil
+	}
+	c := &AvatarCollector{}
+	reader := t.Reader()
+	for reader.Next() {
+		c.Add(reader.Current())
+	}
+	if err := reader.Error(); err != nil {
+		return c.Avatars, fmt.Errorf("Failed to read avatars: %w", err)
+	}
+	return c.Avatars, nil
+}
+
+// Rel converts a TimingA value to the shared timeline of the recording, which starts at zero.
+func (r *Recording) Rel(t int64) int64 {
+	return t - r.Start
+}
+
+// Duration returns the length of the recording, in TimingA units.
+func (r *Recording) Duration() int64 {
+	return r.End - r.Start
+}
+
+func (r *Recording) Close() {
+	for _, f := range r.SampleFiles {
+		f.Close()
+	}
+	if r.CmdFile != nil {
+		r.CmdFile.Close()
+	}
+}
@@ -0,0 +1,100 @@
+package zoom
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestRecording writes a recording folder with the sample file of testSamples, a command file and a file of
+// unknown kind.
+func writeTestRecording(t *testing.T) string {
+	dir := t.TempDir()
+
+	w, err := CreateFile(filepath.Join(dir, "double_click_to_convert_01.zoom"), testLog)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBeginning(NewHeader(0x4E21)))
+	for _, p := range testSamples() {
+		require.NoError(t, w.WritePacket(p))
+	}
+	require.NoError(t, w.Close())
+
+	w, err = CreateFile(filepath.Join(dir, "double_click_to_convert_02.zoom"), testLog)
+	require.NoError(t, err)
+	for _, timing := range []uint32{5, 60} {
+		p := NewCmdPacket()
+		p.TimingA = timing
+		p.AdditionalData = []byte("hi")
+		p.AdditionalSize = int32(len(p.AdditionalData))
+		require.NoError(t, w.WritePacket(p))
+	}
+	require.NoError(t, w.Close())
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "double_click_to_convert_03.zoom"), []byte("not a zoom file"), 0644))
+	return dir
+}
+
+func TestDetectFileKind(t *testing.T) {
+	dir := writeTestRecording(t)
+	for name, kind := range map[string]FileKind{
+		"double_click_to_convert_01.zoom": SampleFile,
+		"double_click_to_convert_02.zoom": CmdFile,
+		"double_click_to_convert_03.zoom": UnknownFile,
+	} {
+		f, err := OpenFile(filepath.Join(dir, name), testLog)
+		require.NoError(t, err)
+		detected, err := DetectFileKind(f)
+		assert.NoError(t, err)
+		assert.Equal(t, kind, detected, name)
+		assert.Equal(t, int64(0), f.Offset())
+		f.Close()
+	}
+}
+
+func TestOpenRecording(t *testing.T) {
+	rec, err := OpenRecording(writeTestRecording(t), testLog)
+	require.NoError(t, err)
+	defer rec.Close()
+
+	require.Len(t, rec.SampleFiles, 1)
+	require.NotNil(t, rec.CmdFile)
+	assert.Equal(t, "double_click_to_convert_01.zoom", filepath.Base(rec.Filename(rec.SampleFiles[0])))
+	assert.Equal(t, "double_click_to_convert_02.zoom", filepath.Base(rec.Filename(rec.CmdFile)))
+	require.Len(t, rec.Unknown, 1)
+	assert.Equal(t, "double_click_to_convert_03.zoom", filepath.Base(rec.Unknown[0]))
+	cmds, err := rec.Commands()
+	require.NoError(t, err)
+	assert.Len(t, cmds, 2)
+
+	// the commands extend the timeline of the samples on both sides
+	assert.Equal(t, int64(5), rec.Start)
+	assert.Equal(t, int64(60), rec.End)
+	assert.Equal(t, int64(55), rec.Duration())
+	assert.Equal(t, int64(15), rec.Rel(20))
+
+	kinds := []TrackKind{}
+	for _, track := range rec.Tracks() {
+		kinds = append(kinds, track.Kind)
+	}
+	assert.Equal(t, []TrackKind{TrackAudio, TrackScreenShare}, kinds)
+	assert.Nil(t, rec.Track(TrackWebCam))
+
+	video := rec.Track(TrackScreenShare)
+	require.Equal(t, 4, video.Len())
+	assert.True(t, video.Entries()[0].Keyframe)
+
+	// readers of different tracks do not share the read position
+	audio := rec.Track(TrackAudio).Reader()
+	screen := video.Reader()
+	timings := []int64{}
+	for audio.Next() {
+		require.True(t, screen.Next())
+		timings = append(timings, audio.Current().TimingA, screen.Current().TimingA)
+	}
+	require.NoError(t, audio.Error())
+	assert.Equal(t, []int64{10, 20, 35, 30}, timings)
+	assert.Equal(t, int64(30), screen.Entry().TimingA)
+}
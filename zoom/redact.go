@@ -0,0 +1,227 @@
+package zoom
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+
+	"github.com/galli-leo/gozoom/ogg"
+)
+
+// RedactAction is what happens to the packets of a redacted media type.
+type RedactAction int
+
+const (
+	RedactKeep RedactAction = iota
+	RedactDrop
+	// RedactReplace keeps the packets, but replaces their data with silence or a placeholder image.
+	RedactReplace
+)
+
+//go:generate enumer -type=RedactAction -trimprefix=Redact
+
+// RedactedChat replaces the text of chat messages, as well as of commands not known to be free of personal data.
+const RedactedChat = "[redacted]"
+
+// Redaction describes the personal data to remove from a recording.
+type Redaction struct {
+	Webcam RedactAction
+	Avatar RedactAction
+	Audio  RedactAction
+	// Participants limits the media redaction to these NameIdents, it applies to everyone if empty.
+	// Audio is attributed to participants by AudioProp.NameIdent, which is not verified, so audio of other participants
+	// may be kept. RedactSamples warns about this in the report.
+	Participants []uint32
+	// Names replaces display names with pseudonyms.
+	Names bool
+	// Chat replaces the text of chat messages with RedactedChat.
+	Chat bool
+	// CmdTypes identifies the join and chat commands.
+	CmdTypes CmdTypeTable
+	// KeepCmdTypes are the command types known to carry no personal data. The text of all other commands that are
+	// neither joins nor chat messages is replaced with RedactedChat.
+	KeepCmdTypes []int32
+}
+
+// RedactionReport lists what was removed. It contains no personal data itself, so it can be shared with the recording.
+type RedactionReport struct {
+	Packets  int            `json:"packets"`
+	Dropped  map[string]int `json:"dropped"`
+	Replaced map[string]int `json:"replaced"`
+	// Names maps participant idents to the pseudonym they got.
+	Names        map[string]string `json:"names,omitempty"`
+	ChatMessages int               `json:"chat_messages"`
+	// Commands counts the other commands whose text was replaced, because they may carry personal data.
+	Commands int `json:"commands"`
+	// UnknownCmdTypes counts the commands of types missing from Redaction.CmdTypes, by type.
+	UnknownCmdTypes map[string]int `json:"unknown_cmd_types,omitempty"`
+	Warnings        []string       `json:"warnings,omitempty"`
+
+	audioWarned bool
+}
+
+func NewRedactionReport() *RedactionReport {
+	return &RedactionReport{
+		Dropped:         map[string]int{},
+		Replaced:        map[string]int{},
+		Names:           map[string]string{},
+		UnknownCmdTypes: map[string]int{},
+	}
+}
+
+func (r *RedactionReport) warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (r *Redaction) Validate() error {
+	if r.Webcam == RedactReplace {
+		return fmt.Errorf("Webcam video can only be kept or dropped")
+	}
+	return nil
+}
+
+func (r *Redaction) applies(ident uint32) bool {
+	if len(r.Participants) == 0 {
+		return true
+	}
+	for _, p := range r.Participants {
+		if p == ident {
+			return true
+		}
+	}
+	return false
+}
+
+// action returns what to do with pkt.
+func (r *Redaction) action(pkt *SamplePacket) RedactAction {
+	switch pkt.MediaType() {
+	case VideoWebCam:
+		if vid := pkt.VideoProp(); vid != nil && r.applies(uint32(vid.NameIdent)) {
+			return r.Webcam
+		}
+	case Avatar:
+		if prop := pkt.AvatarProp(); prop != nil && r.applies(uint32(prop.NameIdent)) {
+			return r.Avatar
+		}
+	case Audio:
+		// audio without a property cannot be attributed, so it is redacted for everyone
+		if prop := pkt.AudioProp(); prop == nil || r.applies(uint32(prop.NameIdent)) {
+			return r.Audio
+		}
+	}
+	return RedactKeep
+}
+
+// RedactSamples copies the packets of the sample file f to w, dropping or replacing the redacted ones.
+func (r *Redaction) RedactSamples(f *File, w *Writer, report *RedactionReport) error {
+	if f.Header == nil {
+		if err := f.ReadBeginning(); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteBeginning(f.Header); err != nil {
+		return err
+	}
+	if err := f.SeekTo(f.DataOffset()); err != nil {
+		return err
+	}
+	if r.Audio != RedactKeep && len(r.Participants) > 0 && !report.audioWarned {
+		report.audioWarned = true
+		report.warnf("Audio was attributed to participants by an unverified field of its property, audio of the other participants may have been redacted or kept by mistake")
+	}
+
+	for f.HasData() {
+		pkt := NewSamplePacket()
+		if err := f.ReadPacket(pkt); err != nil {
+			return fmt.Errorf("Failed to read packet at 0x%x: %w", f.Offset(), err)
+		}
+		typ := pkt.MediaType().String()
+		switch r.action(pkt) {
+		case RedactDrop:
+			report.Dropped[typ]++
+			continue
+		case RedactReplace:
+			data, err := replacementData(pkt)
+			if err != nil {
+				report.warnf("Dropped %s packet at timing %d: %v", typ, pkt.TimingA, err)
+				report.Dropped[typ]++
+				continue
+			}
+			pkt.Data = data
+			pkt.DataSize = int32(len(data))
+			report.Replaced[typ]++
+		}
+		if err := w.WritePacket(pkt); err != nil {
+			return err
+		}
+		report.Packets++
+	}
+	return nil
+}
+
+// placeholderAvatar is a plain gray PNG.
+var placeholderAvatar = func() []byte {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	buf := &bytes.Buffer{}
+	png.Encode(buf, img)
+	return buf.Bytes()
+}()
+
+// replacementData returns data without personal information, that takes the place of the data of pkt.
+func replacementData(pkt *SamplePacket) ([]byte, error) {
+	switch pkt.MediaType() {
+	case Avatar:
+		return placeholderAvatar, nil
+	case Audio:
+		samples, err := OpusPacketSamples(pkt.Data)
+		if err != nil {
+			return nil, fmt.Errorf("Only Opus audio can be replaced with silence: %w", err)
+		}
+		return ogg.OpusSilence(samples)
+	}
+	return nil, fmt.Errorf("%s packets cannot be replaced", pkt.MediaType())
+}
+
+// RedactCommands rewrites the display names and chat messages of the command packets. The text of all other
+// commands is replaced as well, unless their type is in KeepCmdTypes.
+// Packets are modified in place, their sizes are adjusted to the new text.
+func (r *Redaction) RedactCommands(cmds []*CmdPacket, report *RedactionReport) {
+	keep := map[int32]bool{}
+	for _, typ := range r.KeepCmdTypes {
+		keep[typ] = true
+	}
+	pseudonyms := map[uint32]string{}
+	for _, c := range cmds {
+		kind := r.CmdTypes.Kind(c)
+		if kind == CmdRaw {
+			report.UnknownCmdTypes[fmt.Sprintf("0x%x", c.Type)]++
+		}
+		switch {
+		case kind == CmdParticipantJoined:
+			if !r.Names {
+				continue
+			}
+			name, ok := pseudonyms[c.NameIdent]
+			if !ok {
+				name = fmt.Sprintf("Participant %d", len(pseudonyms)+1)
+				pseudonyms[c.NameIdent] = name
+				report.Names[fmt.Sprintf("0x%x", c.NameIdent)] = name
+			}
+			if c.Text() != "" {
+				c.SetText(name)
+			}
+		case kind == CmdChatMessage:
+			if r.Chat {
+				c.SetText(RedactedChat)
+				report.ChatMessages++
+			}
+		case !keep[c.Type] && c.AdditionalSize > 0:
+			c.SetText(RedactedChat)
+			report.Commands++
+		}
+	}
+}
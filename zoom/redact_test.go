@@ -0,0 +1,108 @@
+package zoom
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/galli-leo/gozoom/ogg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactSamples(t *testing.T) {
+	aliceCam := []byte{0, 0x10, 0, 0, 0, 0, 0, 0, 0x80, 0x02, 0, 0, 0xe0, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	bobCam := []byte{0, 0x20, 0, 0, 0, 0, 0, 0, 0x80, 0x02, 0, 0, 0xe0, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	aliceAudio := []byte{0, 0x10, 0, 0, 0x80, 0xbb, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0}
+	filename := writeTestFile(t, []*SamplePacket{
+		newTestSample(Avatar, 10, []byte{0, 0x10, 0, 0}, []byte("\x89PNG\r\n\x1a\nalice")),
+		newTestSample(VideoWebCam, 20, aliceCam, idrFrame),
+		newTestSample(VideoWebCam, 20, bobCam, idrFrame),
+		// 40ms of SILK
+		newTestSample(Audio, 30, aliceAudio, []byte{0x10, 1, 2, 3}),
+		// not Opus, so it cannot be replaced
+		newTestSample(Audio, 50, aliceAudio, []byte{0xff, 0xff, 0xff}),
+		newTestSample(VideoScreenShare, 60, nil, idrFrame),
+	})
+	f, err := OpenFile(filename, testLog)
+	require.NoError(t, err)
+	defer f.Close()
+
+	r := &Redaction{Webcam: RedactDrop, Avatar: RedactReplace, Audio: RedactReplace, Participants: []uint32{0x1000}}
+	require.NoError(t, r.Validate())
+	out := filepath.Join(t.TempDir(), "redacted.zoom")
+	w, err := CreateFile(out, testLog)
+	require.NoError(t, err)
+	report := NewRedactionReport()
+	require.NoError(t, r.RedactSamples(f, w, report))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, 4, report.Packets)
+	assert.Equal(t, map[string]int{"VideoWebCam": 1, "Audio": 1}, report.Dropped)
+	assert.Equal(t, map[string]int{"Avatar": 1, "Audio": 1}, report.Replaced)
+	// the replaced audio packet that is no Opus, and the unverified attribution of audio
+	assert.Len(t, report.Warnings, 2)
+
+	pkts := readTestFile(t, out)
+	require.Len(t, pkts, 4)
+	assert.Equal(t, placeholderAvatar, pkts[0].Data)
+	assert.Equal(t, int32(0x2000), pkts[1].VideoProp().NameIdent)
+	samples, err := OpusPacketSamples(pkts[2].Data)
+	require.NoError(t, err)
+	assert.Equal(t, 1920, samples)
+	assert.Equal(t, VideoScreenShare, pkts[3].MediaType())
+
+	assert.Error(t, (&Redaction{Webcam: RedactReplace}).Validate())
+}
+
+func TestOpusSilence(t *testing.T) {
+	for _, samples := range []int{120, 480, 960, 1920, 2880, 5760} {
+		pkt, err := ogg.OpusSilence(samples)
+		require.NoError(t, err)
+		n, err := OpusPacketSamples(pkt)
+		require.NoError(t, err)
+		assert.Equal(t, samples, n)
+	}
+	_, err := ogg.OpusSilence(100)
+	assert.Error(t, err)
+}
+
+func TestRedactCommands(t *testing.T) {
+	cmd := func(typ CmdType, ident uint32, text string) *CmdPacket {
+		p := NewCmdPacket()
+		p.Type = testCmdType(typ)
+		p.NameIdent = ident
+		p.AdditionalData = []byte(text)
+		p.AdditionalSize = int32(len(text))
+		p.SizeToRead = uint32(CmdSize + len(text))
+		return p
+	}
+	cmds := []*CmdPacket{
+		cmd(CmdParticipantJoined, 0x1000, "Alice Example\x00"),
+		cmd(CmdChatMessage, 0x1000, "my phone number is 555"),
+		cmd(CmdParticipantJoined, 0x2000, "Bob"),
+		cmd(CmdParticipantJoined, 0x1000, "Alice Example\x00"),
+		cmd(CmdParticipantLeft, 0x2000, "Bob"),
+		cmd(CmdRaw, 0x1000, "Alice Example"),
+		cmd(CmdRaw, 0x1000, "1"),
+	}
+	cmds[5].Type = 0x40
+	cmds[6].Type = 0x41
+	report := NewRedactionReport()
+	(&Redaction{Names: true, Chat: true, CmdTypes: testCmdTypes, KeepCmdTypes: []int32{0x41}}).RedactCommands(cmds, report)
+
+	assert.Equal(t, []byte("Participant 1\x00"), cmds[0].AdditionalData)
+	assert.Equal(t, uint32(CmdSize+14), cmds[0].SizeToRead)
+	assert.Equal(t, RedactedChat, cmds[1].Text())
+	assert.Equal(t, int32(len(RedactedChat)), cmds[1].AdditionalSize)
+	assert.Equal(t, "Participant 2", cmds[2].Text())
+	assert.Equal(t, "Participant 1", cmds[3].Text())
+	assert.Equal(t, map[string]string{"0x1000": "Participant 1", "0x2000": "Participant 2"}, report.Names)
+	assert.Equal(t, 1, report.ChatMessages)
+
+	// commands of other or unknown types are redacted unless they are known to carry no personal data
+	assert.Equal(t, RedactedChat, cmds[4].Text())
+	assert.Equal(t, RedactedChat, cmds[5].Text())
+	assert.Equal(t, "1", cmds[6].Text())
+	assert.Equal(t, 2, report.Commands)
+	assert.Equal(t, map[string]int{"0x40": 1, "0x41": 1}, report.UnknownCmdTypes)
+}
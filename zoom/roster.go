@@ -0,0 +1,85 @@
+package zoom
+
+import "fmt"
+
+// Participant is someone seen in the command file of a recording.
+type Participant struct {
+	Ident uint32
+	// Name is the display name from the last join event, empty if the participant never joined.
+	Name string
+	// Joined and Left are the TimingA of the first join and last leave event, -1 if there was none.
+	Joined int64
+	Left   int64
+	// Avatars are the distinct profile pictures of the participant, in order of appearance.
+	Avatars []*AvatarImage
+}
+
+// DisplayName returns the name of the participant, or a placeholder based on the ident if it is unknown.
+func (p *Participant) DisplayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("Participant 0x%x", p.Ident)
+}
+
+// Roster resolves NameIdent values to participants.
+type Roster struct {
+	byIdent map[uint32]*Participant
+	order   []*Participant
+}
+
+// NewRoster collects every participant referenced by the events, in order of appearance.
+func NewRoster(events []Event) *Roster {
+	r := &Roster{byIdent: map[uint32]*Participant{}}
+	for _, e := range events {
+		p := r.add(e.Participant())
+		switch ev := e.(type) {
+		case *ParticipantJoinedEvent:
+			if ev.Name != "" {
+				p.Name = ev.Name
+			}
+			if p.Joined < 0 {
+				p.Joined = ev.Timing()
+			}
+		case *ParticipantLeftEvent:
+			p.Left = ev.Timing()
+		}
+	}
+	return r
+}
+
+func (r *Roster) add(ident uint32) *Participant {
+	if p, ok := r.byIdent[ident]; ok {
+		return p
+	}
+	p := &Participant{Ident: ident, Joined: -1, Left: -1}
+	r.byIdent[ident] = p
+	r.order = append(r.order, p)
+	return p
+}
+
+// AddAvatars links every avatar to its participant, adding participants missing from the command file.
+func (r *Roster) AddAvatars(avatars []*AvatarImage) {
+	for _, a := range avatars {
+		p := r.add(a.Ident)
+		p.Avatars = append(p.Avatars, a)
+	}
+}
+
+// Get returns the participant with the given ident, or nil if it was never seen.
+func (r *Roster) Get(ident uint32) *Participant {
+	return r.byIdent[ident]
+}
+
+// Name returns the display name for ident.
+func (r *Roster) Name(ident uint32) string {
+	if p, ok := r.byIdent[ident]; ok {
+		return p.DisplayName()
+	}
+	return (&Participant{Ident: ident}).DisplayName()
+}
+
+// Participants returns all participants in order of appearance.
+func (r *Roster) Participants() []*Participant {
+	return r.order
+}
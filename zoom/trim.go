@@ -0,0 +1,203 @@
+package zoom
+
+import (
+	"fmt"
+)
+
+// TimeRange is a range of TimingA values, including Start and excluding End.
+// An End of zero means up to the end of the recording.
+type TimeRange struct {
+	Start int64
+	End   int64
+}
+
+func (r TimeRange) Contains(t int64) bool {
+	return t >= r.Start && (r.End == 0 || t < r.End)
+}
+
+func (r TimeRange) String() string {
+	if r.End == 0 {
+		return fmt.Sprintf("[%d, end)", r.Start)
+	}
+	return fmt.Sprintf("[%d, %d)", r.Start, r.End)
+}
+
+// TrimStats counts the packets copied by TrimSamples.
+type TrimStats struct {
+	Packets int
+	// Leading is the number of screen share packets before the start of the range,
+	// needed to decode from the preceding keyframe.
+	Leading int
+	// Dropped is the number of webcam packets in the range that preceded the first keyframe of their participant.
+	Dropped int
+}
+
+// TrimSamples copies the header and the packets of f within r to w, keeping their order in the file.
+// The screen share starts at the keyframe preceding r.Start, and gets the last SPS and PPS prepended if that keyframe has none.
+// Webcams start at the first keyframe of every participant within the range.
+// Avatars are kept regardless of their timing.
+func TrimSamples(f *File, w *Writer, r TimeRange) (TrimStats, error) {
+	stats := TrimStats{}
+	if f.Header == nil {
+		if err := f.ReadBeginning(); err != nil {
+			return stats, err
+		}
+	}
+	idx, err := BuildIndex(f)
+	if err != nil {
+		return stats, err
+	}
+	if err := w.WriteBeginning(f.Header); err != nil {
+		return stats, err
+	}
+
+	screenStart := idx.PrecedingKeyframe(r.Start, VideoScreenShare)
+	webcams := map[int32]bool{}
+	for pos, e := range idx.Entries {
+		keep := false
+		switch e.Type {
+		case Avatar:
+			keep = true
+		case VideoScreenShare:
+			if screenStart >= 0 {
+				keep = pos >= screenStart && (r.End == 0 || e.TimingA < r.End)
+			} else if r.Contains(e.TimingA) && e.Keyframe {
+				// no keyframe before the range, start at the first one inside it
+				screenStart = pos
+				keep = true
+			}
+		default:
+			keep = r.Contains(e.TimingA)
+		}
+		if !keep {
+			continue
+		}
+
+		pkt := NewSamplePacket()
+		if err := f.SeekTo(e.Offset); err != nil {
+			return stats, err
+		}
+		if err := f.ReadPacket(pkt); err != nil {
+			return stats, fmt.Errorf("Failed to read packet at 0x%x: %w", e.Offset, err)
+		}
+
+		switch e.Type {
+		case VideoWebCam:
+			vid := pkt.VideoProp()
+			if vid == nil {
+				break
+			}
+			if !webcams[vid.NameIdent] && !e.Keyframe {
+				stats.Dropped++
+				continue
+			}
+			webcams[vid.NameIdent] = true
+		case VideoScreenShare:
+			if pos == screenStart {
+				if err := prependParameterSets(f, idx, pos, pkt); err != nil {
+					return stats, err
+				}
+			}
+			if !r.Contains(e.TimingA) {
+				stats.Leading++
+			}
+		}
+
+		if err := w.WritePacket(pkt); err != nil {
+			return stats, err
+		}
+		stats.Packets++
+	}
+	return stats, nil
+}
+
+// prependParameterSets makes sure the keyframe at pos starts with an SPS and PPS,
+// taking them from the closest packet of the same video stream before it that has them.
+func prependParameterSets(f *File, idx *Index, pos int, pkt *SamplePacket) error {
+	sps, pps, _ := pkt.ParameterSets()
+	if sps != nil && pps != nil {
+		return nil
+	}
+	vid := pkt.VideoProp()
+	for i := pos - 1; i >= 0 && (sps == nil || pps == nil); i-- {
+		e := idx.Entries[i]
+		if e.Type != idx.Entries[pos].Type {
+			continue
+		}
+		prev := NewSamplePacket()
+		if err := f.SeekTo(e.Offset); err != nil {
+			return err
+		}
+		if err := f.ReadPacket(prev); err != nil {
+			return fmt.Errorf("Failed to read packet at 0x%x: %w", e.Offset, err)
+		}
+		if prevVid := prev.VideoProp(); e.Type == VideoWebCam && (vid == nil || prevVid == nil || prevVid.NameIdent != vid.NameIdent) {
+			continue
+		}
+		prevSPS, prevPPS, _ := prev.ParameterSets()
+		if sps == nil {
+			sps = prevSPS
+		}
+		if pps == nil {
+			pps = prevPPS
+		}
+	}
+	if sps == nil || pps == nil {
+		return fmt.Errorf("No parameter sets found for the keyframe at timing %d", pkt.TimingA)
+	}
+	setParameterSets(pkt, sps, pps)
+	return nil
+}
+
+// setParameterSets replaces the SPS and PPS of a video packet, putting them in front of the other NAL units.
+func setParameterSets(pkt *SamplePacket, sps, pps []byte) {
+	data := []byte{}
+	for _, nalu := range [][]byte{sps, pps} {
+		data = append(data, 0, 0, 0, 1)
+		data = append(data, nalu...)
+	}
+	_, _, others := pkt.ParameterSets()
+	for _, nalu := range others {
+		data = append(data, 0, 0, 0, 1)
+		data = append(data, nalu...)
+	}
+	pkt.Data = data
+	pkt.DataSize = int32(len(data))
+}
+
+// TrimCommands returns the command packets within r.
+// Join events of participants that are still present at the start of the range are kept as well,
+// so their names can still be resolved. Without join and leave types in the table, only the range is kept.
+func TrimCommands(cmds []*CmdPacket, r TimeRange, types CmdTypeTable) []*CmdPacket {
+	joins := map[uint32]*CmdPacket{}
+	order := []uint32{}
+	ret := []*CmdPacket{}
+	for _, c := range cmds {
+		t := int64(c.TimingA)
+		if t < r.Start {
+			switch types.Kind(c) {
+			case CmdParticipantJoined:
+				if _, ok := joins[c.NameIdent]; !ok {
+					order = append(order, c.NameIdent)
+				}
+				joins[c.NameIdent] = c
+			case CmdParticipantLeft:
+				delete(joins, c.NameIdent)
+			}
+			continue
+		}
+		if r.Contains(t) {
+			ret = append(ret, c)
+		}
+	}
+
+	leading := []*CmdPacket{}
+	for _, ident := range order {
+		if c, ok := joins[ident]; ok {
+			leading = append(leading, c)
+			// a participant that left and joined again is only kept once
+			delete(joins, ident)
+		}
+	}
+	return append(leading, ret...)
+}
@@ -0,0 +1,85 @@
+package zoom
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestFile(t *testing.T, filename string) []*SamplePacket {
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.Open(filename))
+	defer sr.Close()
+	ret := []*SamplePacket{}
+	for sr.Next() {
+		ret = append(ret, sr.Current())
+	}
+	require.NoError(t, sr.Err())
+	return ret
+}
+
+func TestTrimSamples(t *testing.T) {
+	videoProp := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0x80, 0x07, 0, 0, 0x38, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	webcamProp := []byte{0, 0x20, 0, 0, 0, 0, 0, 0, 0x80, 0x02, 0, 0, 0xe0, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	idrOnly := []byte{0, 0, 0, 1, 0x65, 0x88, 0x84}
+	filename := writeTestFile(t, []*SamplePacket{
+		newTestSample(Avatar, 10, []byte{0, 0x20, 0, 0}, []byte("avatar")),
+		newTestSample(VideoScreenShare, 20, videoProp, idrFrame),
+		newTestSample(Audio, 25, []byte{1, 2, 3}, []byte{0x11}),
+		newTestSample(VideoScreenShare, 30, videoProp, idrOnly),
+		newTestSample(VideoScreenShare, 40, videoProp, nonIdrFrame),
+		newTestSample(Audio, 45, []byte{1, 2, 3}, []byte{0x22}),
+		newTestSample(VideoWebCam, 46, webcamProp, nonIdrFrame),
+		newTestSample(VideoWebCam, 48, webcamProp, idrFrame),
+		newTestSample(VideoScreenShare, 50, videoProp, nonIdrFrame),
+		newTestSample(Audio, 55, []byte{1, 2, 3}, []byte{0x33}),
+		newTestSample(VideoScreenShare, 60, videoProp, nonIdrFrame),
+	})
+	f, err := OpenFile(filename, testLog)
+	require.NoError(t, err)
+	defer f.Close()
+
+	out := filepath.Join(t.TempDir(), "trimmed.zoom")
+	w, err := CreateFile(out, testLog)
+	require.NoError(t, err)
+	stats, err := TrimSamples(f, w, TimeRange{Start: 42, End: 60})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, TrimStats{Packets: 7, Leading: 2, Dropped: 1}, stats)
+
+	pkts := readTestFile(t, out)
+	timings := []int64{}
+	for _, p := range pkts {
+		timings = append(timings, p.TimingA)
+	}
+	assert.Equal(t, []int64{10, 30, 40, 45, 48, 50, 55}, timings)
+
+	// the keyframe at 30 gets the parameter sets of the one at 20
+	first := pkts[1]
+	assert.True(t, first.IsKeyframe())
+	sps, pps, _ := first.ParameterSets()
+	assert.Equal(t, []byte{0x67, 0x64, 0x00, 0x1f}, sps)
+	assert.Equal(t, []byte{0x68, 0xee}, pps)
+	assert.Len(t, first.NALUs(), 3)
+}
+
+func TestTrimCommands(t *testing.T) {
+	cmd := func(typ CmdType, ident uint32, timing uint32) *CmdPacket {
+		p := NewCmdPacket()
+		p.Type = testCmdType(typ)
+		p.NameIdent = ident
+		p.TimingA = timing
+		return p
+	}
+	aliceJoined := cmd(CmdParticipantJoined, 1, 10)
+	bobJoined := cmd(CmdParticipantJoined, 2, 20)
+	bobLeft := cmd(CmdParticipantLeft, 2, 30)
+	carolJoined := cmd(CmdParticipantJoined, 3, 35)
+	chat := cmd(CmdChatMessage, 1, 50)
+	late := cmd(CmdChatMessage, 1, 100)
+
+	trimmed := TrimCommands([]*CmdPacket{aliceJoined, bobJoined, bobLeft, carolJoined, chat, late}, TimeRange{Start: 40, End: 100}, testCmdTypes)
+	assert.Equal(t, []*CmdPacket{aliceJoined, carolJoined, chat}, trimmed)
+}
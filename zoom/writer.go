@@ -0,0 +1,136 @@
+package zoom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// DefaultFileOffset is the offset of the first packet in every sample file seen so far.
+const DefaultFileOffset = 1024
+
+// NewHeader returns a sample file header with the magic values set and the data starting at DefaultFileOffset.
+func NewHeader(versionInfo uint32) *Header {
+	return &Header{
+		Header:      header,
+		Trailer:     trailer,
+		VersionInfo: versionInfo,
+		FileOffset:  DefaultFileOffset,
+	}
+}
+
+type WritablePacket interface {
+	// Called by the writer, after writing the header magic and before writing the trailer.
+	WritePacket(w *Writer) error
+}
+
+// Writer produces .zoom files, mirroring the layout expected by File.
+type Writer struct {
+	w      io.Writer
+	closer io.Closer
+	offset int64
+	log    *zap.SugaredLogger
+}
+
+func NewWriter(w io.Writer, log *zap.SugaredLogger) *Writer {
+	return &Writer{
+		w:   w,
+		log: log.Named("writer"),
+	}
+}
+
+// CreateFile creates (or truncates) filename and returns a writer for it.
+func CreateFile(filename string, log *zap.SugaredLogger) (*Writer, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create file %s: %w", filename, err)
+	}
+	w := NewWriter(file, log)
+	w.closer = file
+	return w, nil
+}
+
+func (w *Writer) Close() error {
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
+
+// Offset returns the number of bytes written so far.
+func (w *Writer) Offset() int64 {
+	return w.offset
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("Failed to write %d bytes: %w", len(b), err)
+	}
+	return nil
+}
+
+// WriteExactBytes writes data without any padding.
+func (w *Writer) WriteExactBytes(data []byte) error {
+	return w.write(data)
+}
+
+// WriteBytes writes the first size bytes of data, padded to a multiple of 4 like File.ReadBytes expects.
+// If data already contains the padding, it is written as is.
+func (w *Writer) WriteBytes(data []byte, size int) error {
+	if len(data) < size {
+		return fmt.Errorf("Failed to write %d bytes, only have %d", size, len(data))
+	}
+	padded := (size + 3) & 0xfffffffc
+	if len(data) >= padded {
+		return w.write(data[:padded])
+	}
+	buf := make([]byte, padded)
+	copy(buf, data[:size])
+	return w.write(buf)
+}
+
+func (w *Writer) WriteStruct(struc interface{}) error {
+	buffer := &bytes.Buffer{}
+	if err := binary.Write(buffer, binary.LittleEndian, struc); err != nil {
+		return fmt.Errorf("Failed to convert %T: %w", struc, err)
+	}
+	return w.WriteBytes(buffer.Bytes(), buffer.Len())
+}
+
+// WriteBeginning writes the file header and pads the file up to the header's FileOffset.
+func (w *Writer) WriteBeginning(head *Header) error {
+	if head.Header != header || head.Trailer != trailer {
+		return fmt.Errorf("Invalid file header magic %x / %x", head.Header, head.Trailer)
+	}
+	if int(head.FileOffset) < HeaderSize {
+		return fmt.Errorf("File offset %d is inside the header", head.FileOffset)
+	}
+	if err := w.WriteStruct(head); err != nil {
+		return fmt.Errorf("Failed to write file header: %w", err)
+	}
+	return w.write(make([]byte, int64(head.FileOffset)-w.offset))
+}
+
+func (w *Writer) WritePacket(pkt WritablePacket) error {
+	if err := binary.Write(w.w, binary.LittleEndian, header); err != nil {
+		return fmt.Errorf("Failed to write header: %w", err)
+	}
+	w.offset += 4
+
+	if err := pkt.WritePacket(w); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w.w, binary.LittleEndian, uint32(trailer)); err != nil {
+		return fmt.Errorf("Failed to write trailer: %w", err)
+	}
+	w.offset += 4
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package zoom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testLog = zap.NewNop().Sugar()
+
+var idrFrame = []byte{0, 0, 0, 1, 0x67, 0x64, 0x00, 0x1f, 0, 0, 0, 1, 0x68, 0xee, 0, 0, 0, 1, 0x65, 0x88, 0x84}
+var nonIdrFrame = []byte{0, 0, 0, 1, 0x41, 0x9a, 0x02}
+
+func newTestSample(typ MediaType, timing int64, prop []byte, data []byte) *SamplePacket {
+	p := NewSamplePacket()
+	p.Type = int32(typ)
+	p.TimingA = timing
+	p.TimingB = timing
+	p.PropertySize = int32(len(prop))
+	p.Property = prop
+	p.DataSize = int32(len(data))
+	p.Data = data
+	return p
+}
+
+func testSamples() []*SamplePacket {
+	videoProp := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0x80, 0x07, 0, 0, 0x38, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	return []*SamplePacket{
+		newTestSample(Audio, 10, []byte{1, 2, 3}, []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee}),
+		newTestSample(VideoScreenShare, 20, videoProp, idrFrame),
+		newTestSample(VideoScreenShare, 30, videoProp, nonIdrFrame),
+		newTestSample(Audio, 35, []byte{1, 2, 3}, []byte{0x11}),
+		newTestSample(VideoScreenShare, 40, videoProp, idrFrame),
+		newTestSample(VideoScreenShare, 50, videoProp, nonIdrFrame),
+	}
+}
+
+func writeTestFile(t *testing.T, pkts []*SamplePacket) string {
+	filename := filepath.Join(t.TempDir(), "test.zoom")
+	w, err := CreateFile(filename, testLog)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBeginning(NewHeader(0x4E21)))
+	for _, p := range pkts {
+		require.NoError(t, w.WritePacket(p))
+	}
+	require.NoError(t, w.Close())
+	return filename
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	pkts := testSamples()
+	filename := writeTestFile(t, pkts)
+
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.Open(filename))
+	defer sr.Close()
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, testLog)
+	require.NoError(t, w.WriteBeginning(sr.f.Header))
+
+	i := 0
+	for sr.Next() {
+		curr := sr.Current()
+		require.Less(t, i, len(pkts))
+		assert.Equal(t, pkts[i].SampleHeader, curr.SampleHeader)
+		assert.Equal(t, pkts[i].Data, curr.Data)
+		require.NoError(t, w.WritePacket(curr))
+		i++
+	}
+	assert.Equal(t, len(pkts), i)
+
+	orig, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, orig, buf.Bytes())
+}
+
+func TestCmdPacketRoundTrip(t *testing.T) {
+	p := NewCmdPacket()
+	p.Type = 3
+	p.NameIdent = 0x1234
+	p.TimingA = 100
+	p.AdditionalData = []byte("hello")
+	p.AdditionalSize = int32(len(p.AdditionalData))
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, testLog)
+	require.NoError(t, w.WritePacket(p))
+	require.NoError(t, w.WritePacket(p))
+
+	filename := filepath.Join(t.TempDir(), "cmd.zoom")
+	require.NoError(t, ioutil.WriteFile(filename, buf.Bytes(), 0644))
+	f, err := OpenFile(filename, testLog)
+	require.NoError(t, err)
+	defer f.Close()
+
+	for i := 0; i < 2; i++ {
+		read := NewCmdPacket()
+		require.NoError(t, f.ReadPacket(read))
+		assert.Equal(t, p.CmdHeader, read.CmdHeader)
+		assert.Equal(t, p.AdditionalData, read.AdditionalData)
+	}
+	assert.False(t, f.HasData())
+}
+
+func TestIndexSeek(t *testing.T) {
+	filename := writeTestFile(t, testSamples())
+
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.Open(filename))
+	defer sr.Close()
+
+	idx, err := sr.Index()
+	require.NoError(t, err)
+	require.Equal(t, 6, idx.Len())
+	assert.True(t, idx.Entries[1].Keyframe)
+	assert.False(t, idx.Entries[2].Keyframe)
+	assert.False(t, idx.Entries[0].Keyframe)
+
+	assert.Equal(t, 1, idx.PrecedingKeyframe(35, VideoScreenShare))
+	assert.Equal(t, 4, idx.PrecedingKeyframe(45, VideoScreenShare))
+	assert.Equal(t, -1, idx.PrecedingKeyframe(15, VideoScreenShare))
+
+	require.NoError(t, sr.SeekTime(31))
+	require.True(t, sr.Next())
+	assert.EqualValues(t, 35, sr.Current().TimingA)
+
+	require.NoError(t, sr.SeekKeyframe(55, VideoScreenShare))
+	require.True(t, sr.Next())
+	assert.EqualValues(t, 40, sr.Current().TimingA)
+
+	require.NoError(t, sr.SeekOffset(idx.Entries[2].Offset+10))
+	require.True(t, sr.Next())
+	assert.EqualValues(t, 30, sr.Current().TimingA)
+}
+
+func TestIndexTruncated(t *testing.T) {
+	data, err := ioutil.ReadFile(writeTestFile(t, testSamples()))
+	require.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "truncated.zoom")
+	require.NoError(t, ioutil.WriteFile(filename, data[:len(data)-10], 0644))
+
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.Open(filename))
+	defer sr.Close()
+	require.True(t, sr.Next())
+	offset := sr.File().Offset()
+
+	// the failed index keeps the read position
+	err = sr.SeekTime(40)
+	assert.True(t, errors.Is(err, ErrIncomplete), "%v", err)
+	assert.Equal(t, offset, sr.File().Offset())
+
+	// a file that is still being written ends before the incomplete packet
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	follow := NewSampleReader(testLog)
+	follow.Follow(ctx)
+	require.NoError(t, follow.Open(filename))
+	defer follow.Close()
+	require.True(t, follow.Next())
+	offset = follow.File().Offset()
+	idx, err := follow.Index()
+	require.NoError(t, err)
+	assert.Equal(t, 5, idx.Len())
+	assert.Equal(t, offset, follow.File().Offset())
+	require.NoError(t, follow.SeekTime(40))
+	require.True(t, follow.Next())
+	assert.EqualValues(t, 40, follow.Current().TimingA)
+}
+
+func TestWriterRoundTripPadding(t *testing.T) {
+	// the property and the data carry non-zero bytes in their padding
+	p := newTestSample(Audio, 10, []byte{1, 2, 3, 0xff}, []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0x12, 0x34, 0x56})
+	p.PropertySize = 3
+	p.DataSize = 5
+	filename := writeTestFile(t, []*SamplePacket{p})
+
+	sr := NewSampleReader(testLog)
+	require.NoError(t, sr.Open(filename))
+	defer sr.Close()
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, testLog)
+	require.NoError(t, w.WriteBeginning(sr.f.Header))
+	require.True(t, sr.Next())
+	curr := sr.Current()
+	assert.Equal(t, []byte{1, 2, 3}, curr.Property)
+	assert.Equal(t, []byte{0xaa, 0xbb, 0xcc, 0xdd, 0xee}, curr.Data)
+	require.NoError(t, w.WritePacket(curr))
+
+	orig, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, orig, buf.Bytes())
+}